@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"aliciapceramics/scheduler"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func CalibrateRatesHandler(w http.ResponseWriter, r *http.Request) {
+
+	options := scheduler.DefaultCalibration
+
+	if lookback := r.URL.Query().Get("lookback_days"); lookback != "" {
+		lookbackDays, err := strconv.Atoi(lookback)
+
+		if err != nil || lookbackDays < 1 || lookbackDays > 365 {
+			LogError("calibrate_rates", fmt.Errorf("invalid lookback_days parameter %q", lookback), map[string]any{
+				"lookback_days": lookback,
+			})
+			RespondWithError(w, http.StatusBadRequest, "lookback_days must be between 1 and 365", "INVALID_LOOKBACK")
+			return
+		}
+
+		options.LookbackDays = lookbackDays
+	}
+
+	if r.URL.Query().Get("apply") == "true" {
+		if r.Method != http.MethodPost {
+			RespondWithError(w, http.StatusMethodNotAllowed, "apply=true requires a POST request", "METHOD_NOT_ALLOWED")
+			return
+		}
+
+		options.Apply = true
+	}
+
+	report, err := scheduler.Calibrate(options)
+
+	if err != nil {
+		LogError("calibrate_rates", err, map[string]any{
+			"lookback_days": options.LookbackDays,
+			"apply":         options.Apply,
+		})
+		RespondWithError(w, http.StatusInternalServerError, "Failed to calibrate production rates", "CALIBRATION_ERROR")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
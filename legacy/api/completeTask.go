@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -47,6 +48,22 @@ func CompleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var actualHours *float64
+	if hours := r.URL.Query().Get("actual_hours"); hours != "" {
+		parsedHours, err := strconv.ParseFloat(hours, 64)
+
+		if err != nil || parsedHours <= 0 || parsedHours > 24 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(CompleteTaskResponse{
+				Success: false,
+				Message: "actual_hours must be greater than 0 and at most 24",
+			})
+			return
+		}
+
+		actualHours = &parsedHours
+	}
+
 	dbURL := os.Getenv("SUPABASE_DB_URL")
 	if dbURL == "" {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -69,7 +86,7 @@ func CompleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer pool.Close()
 
-	if err := completeTask(ctx, pool, taskID); err != nil {
+	if err := completeTask(ctx, pool, taskID, actualHours); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(CompleteTaskResponse{
 			Success: false,
@@ -86,7 +103,7 @@ func CompleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string) error {
+func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string, actualHours *float64) error {
 	tx, err := db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -94,18 +111,21 @@ func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string) error {
 	defer tx.Rollback(ctx)
 
 	var task struct {
-		ID            string
-		OrderDetailID string
-		TaskType      string
-		Quantity      int
-		Status        string
+		ID             string
+		OrderDetailID  string
+		TaskType       string
+		StepKey        string
+		Component      string
+		Quantity       int
+		Status         string
+		DefectReportID *string
 	}
 
 	err = tx.QueryRow(ctx, `
-		SELECT id, order_detail_id, task_type, quantity, status
+		SELECT id, order_detail_id, task_type, COALESCE(step_key, ''), COALESCE(component, ''), quantity, status, defect_report_id
 		FROM tasks
 		WHERE id = $1
-	`, taskID).Scan(&task.ID, &task.OrderDetailID, &task.TaskType, &task.Quantity, &task.Status)
+	`, taskID).Scan(&task.ID, &task.OrderDetailID, &task.TaskType, &task.StepKey, &task.Component, &task.Quantity, &task.Status, &task.DefectReportID)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -118,13 +138,18 @@ func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string) error {
 		return fmt.Errorf("task %s is already completed", taskID)
 	}
 
+	stepKey, err := orders.TaskStepKey(task.StepKey, task.TaskType)
+	if err != nil {
+		return fmt.Errorf("failed to determine task step: %w", err)
+	}
+
 	completedAt := time.Now()
 
 	_, err = tx.Exec(ctx, `
 		UPDATE tasks
-		SET status = 'completed', completed_at = $1, updated_at = $1
-		WHERE id = $2
-	`, completedAt, taskID)
+		SET status = 'completed', completed_at = $1, updated_at = $1, actual_hours = $2
+		WHERE id = $3
+	`, completedAt, actualHours, taskID)
 
 	if err != nil {
 		return fmt.Errorf("failed to update task status: %w", err)
@@ -136,10 +161,15 @@ func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string) error {
 		Quantity          int
 		Status            string
 		CompletedQuantity int
+		StatusChangedAt   *time.Time
+		StepProgress      []byte
+		Components        []byte
+		LostQuantity      int
 	}
 
 	err = tx.QueryRow(ctx, `
-		SELECT id, order_id, quantity, status, completed_quantity
+		SELECT id, order_id, quantity, status, completed_quantity, status_changed_at, step_progress, components,
+			(SELECT COALESCE(SUM(quantity), 0) FROM defect_reports WHERE order_detail_id = order_details.id)
 		FROM order_details
 		WHERE id = $1
 	`, task.OrderDetailID).Scan(
@@ -148,42 +178,108 @@ func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string) error {
 		&orderDetail.Quantity,
 		&orderDetail.Status,
 		&orderDetail.CompletedQuantity,
+		&orderDetail.StatusChangedAt,
+		&orderDetail.StepProgress,
+		&orderDetail.Components,
+		&orderDetail.LostQuantity,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to fetch order detail: %w", err)
 	}
 
-	newCompletedQuantity := orderDetail.CompletedQuantity + task.Quantity
+	components, err := orders.DecodeComponents(orderDetail.Components)
+	if err != nil {
+		return fmt.Errorf("failed to decode order detail components: %w", err)
+	}
+
+	// Remake tasks move their defect report's pieces on, not the order detail's
+	if task.DefectReportID != nil {
+		if err := completeRemakeTask(ctx, tx, *task.DefectReportID, components, task.Component, stepKey, task.Quantity, completedAt); err != nil {
+			return err
+		}
+
+		return commitOrderStatus(ctx, tx, orderDetail.OrderID, completedAt)
+	}
+
+	progress, err := orders.DecodeStepProgress(orderDetail.StepProgress)
+	if err != nil {
+		return fmt.Errorf("failed to decode order detail step progress: %w", err)
+	}
+
+	// Pieces reported lost no longer hold up the rest of the batch
+	batch := orders.NewStepBatch(orderDetail.Status, orderDetail.StatusChangedAt, orderDetail.CompletedQuantity, progress, components, orderDetail.Quantity-orderDetail.LostQuantity, stepKey)
+	if err := batch.Complete(task.Component, stepKey, task.Quantity, completedAt); err != nil {
+		return fmt.Errorf("failed to determine next status: %w", err)
+	}
+
+	encodedProgress, err := orders.EncodeStepProgress(batch.Progress)
+	if err != nil {
+		return fmt.Errorf("failed to encode order detail step progress: %w", err)
+	}
 
 	_, err = tx.Exec(ctx, `
 		UPDATE order_details
-		SET completed_quantity = $1
-		WHERE id = $2
-	`, newCompletedQuantity, orderDetail.ID)
+		SET status = $1, status_changed_at = $2, step_progress = $3, completed_quantity = 0
+		WHERE id = $4
+	`, batch.Status, batch.StatusChangedAt, encodedProgress, orderDetail.ID)
 
 	if err != nil {
-		return fmt.Errorf("failed to update order detail completed quantity: %w", err)
+		return fmt.Errorf("failed to update order detail progress: %w", err)
 	}
 
-	if newCompletedQuantity >= orderDetail.Quantity {
-		nextStatus, err := orders.GetNextStatus(task.TaskType)
-		if err != nil {
-			return fmt.Errorf("failed to determine next status: %w", err)
-		}
+	return commitOrderStatus(ctx, tx, orderDetail.OrderID, completedAt)
+}
 
-		_, err = tx.Exec(ctx, `
-			UPDATE order_details
-			SET status = $1, completed_quantity = 0, status_changed_at = $2
-			WHERE id = $3
-		`, nextStatus, completedAt, orderDetail.ID)
+func completeRemakeTask(ctx context.Context, tx pgx.Tx, defectReportID string, components []orders.OrderDetailComponentDTO, component string, stepKey string, quantity int, completedAt time.Time) error {
+	var remake struct {
+		Quantity          int
+		Status            string
+		CompletedQuantity int
+		StatusChangedAt   *time.Time
+		StepProgress      []byte
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to update order detail status: %w", err)
-		}
+	err := tx.QueryRow(ctx, `
+		SELECT quantity, remake_status, remake_completed_quantity, remake_status_changed_at, remake_step_progress
+		FROM defect_reports
+		WHERE id = $1
+	`, defectReportID).Scan(&remake.Quantity, &remake.Status, &remake.CompletedQuantity, &remake.StatusChangedAt, &remake.StepProgress)
+
+	if err != nil {
+		return fmt.Errorf("failed to fetch defect report: %w", err)
+	}
+
+	progress, err := orders.DecodeStepProgress(remake.StepProgress)
+	if err != nil {
+		return fmt.Errorf("failed to decode remake step progress: %w", err)
+	}
+
+	batch := orders.NewStepBatch(remake.Status, remake.StatusChangedAt, remake.CompletedQuantity, progress, components, remake.Quantity, stepKey)
+	if err := batch.Complete(component, stepKey, quantity, completedAt); err != nil {
+		return fmt.Errorf("failed to determine next status: %w", err)
+	}
+
+	encodedProgress, err := orders.EncodeStepProgress(batch.Progress)
+	if err != nil {
+		return fmt.Errorf("failed to encode remake step progress: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE defect_reports
+		SET remake_status = $1, remake_status_changed_at = $2, remake_step_progress = $3, remake_completed_quantity = 0
+		WHERE id = $4
+	`, batch.Status, batch.StatusChangedAt, encodedProgress, defectReportID)
+
+	if err != nil {
+		return fmt.Errorf("failed to update remake progress: %w", err)
 	}
 
-	orderStatus, err := orders.CalculateOrderStatus(ctx, tx, orderDetail.OrderID)
+	return nil
+}
+
+func commitOrderStatus(ctx context.Context, tx pgx.Tx, orderID string, updatedAt time.Time) error {
+	orderStatus, err := orders.CalculateOrderStatus(ctx, tx, orderID)
 	if err != nil {
 		return fmt.Errorf("failed to calculate order status: %w", err)
 	}
@@ -192,7 +288,7 @@ func completeTask(ctx context.Context, db *pgxpool.Pool, taskID string) error {
 		UPDATE orders
 		SET status = $1, status_updated_at = $2, updated_at = $2
 		WHERE id = $3
-	`, orderStatus, completedAt, orderDetail.OrderID)
+	`, orderStatus, updatedAt, orderID)
 
 	if err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
@@ -25,6 +25,7 @@ type PieceDetail struct {
 	Size        *string `json:"size,omitempty"`
 	Quantity    int     `json:"quantity"`
 	Description string  `json:"description"`
+	Glaze       *string `json:"glaze,omitempty"`
 }
 
 type Order struct {
@@ -67,6 +68,7 @@ type OrderDetailDB struct {
 	Size        *string `json:"size,omitempty"`
 	Quantity    int     `json:"quantity"`
 	Description string  `json:"description"`
+	Glaze       *string `json:"glaze,omitempty"`
 }
 
 type CustomerSMSConsentRecord struct {
@@ -197,6 +199,18 @@ func OrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var bulkCode *orders.BulkCodeDTO
+	if req.Order.BulkCommissionCodeID != nil && *req.Order.BulkCommissionCodeID != "" {
+		bulkCode, err = orders.NewBulkCodeService().GetRedeemableCode(*req.Order.BulkCommissionCodeID)
+		if err != nil {
+			LogError("get_bulk_code", err, map[string]any{
+				"bulk_code_id": *req.Order.BulkCommissionCodeID,
+			})
+			RespondWithError(w, http.StatusBadRequest, "Bulk commission code is invalid or already redeemed", "BULK_CODE_ERROR")
+			return
+		}
+	}
+
 	customerId, err := upsertCustomer(supabaseUrl, supabaseKey, req.Order.Client)
 	if err != nil {
 		LogError("upsert_customer", err, map[string]any{
@@ -207,7 +221,7 @@ func OrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orderId, err := createOrder(customerId, req.Order)
+	orderId, err := createOrder(customerId, req.Order, bulkCode)
 	if err != nil {
 		LogError("create_order", err, map[string]any{
 			"customer_id": customerId,
@@ -227,12 +241,12 @@ func OrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Order.BulkCommissionCodeID != nil && *req.Order.BulkCommissionCodeID != "" {
+	if bulkCode != nil {
 		bulkCodeService := orders.NewBulkCodeService()
-		err = bulkCodeService.MarkAsRedeemed(*req.Order.BulkCommissionCodeID)
+		err = bulkCodeService.MarkAsRedeemed(bulkCode.ID)
 		if err != nil {
 			LogError("mark_bulk_code_redeemed", err, map[string]any{
-				"bulk_code_id": *req.Order.BulkCommissionCodeID,
+				"bulk_code_id": bulkCode.ID,
 				"order_id":     orderId,
 			})
 		}
@@ -282,12 +296,29 @@ func validateOrder(order Order) error {
 		return fmt.Errorf("phone number must be a valid US phone number")
 	}
 
-	// Piece details validation
-	if len(order.PieceDetails) == 0 {
+	if err := validatePieceDetails(order.PieceDetails); err != nil {
+		return err
+	}
+
+	// Timeline validation
+	if strings.TrimSpace(order.Timeline) == "" {
+		return fmt.Errorf("timeline is required")
+	}
+
+	// Consent validation
+	if !order.Consent {
+		return fmt.Errorf("consent is required")
+	}
+
+	return nil
+}
+
+func validatePieceDetails(pieceDetails []PieceDetail) error {
+	if len(pieceDetails) == 0 {
 		return fmt.Errorf("at least one piece detail is required")
 	}
 
-	for i, piece := range order.PieceDetails {
+	for i, piece := range pieceDetails {
 		if strings.TrimSpace(piece.Type) == "" {
 			return fmt.Errorf("piece type is required for item %d", i+1)
 		}
@@ -299,16 +330,6 @@ func validateOrder(order Order) error {
 		}
 	}
 
-	// Timeline validation
-	if strings.TrimSpace(order.Timeline) == "" {
-		return fmt.Errorf("timeline is required")
-	}
-
-	// Consent validation
-	if !order.Consent {
-		return fmt.Errorf("consent is required")
-	}
-
 	return nil
 }
 
@@ -398,7 +419,7 @@ func upsertCustomer(supabaseUrl, supabaseKey string, customer Customer) (string,
 	return result[0].ID, nil
 }
 
-func createOrder(customerID string, order Order) (string, error) {
+func createOrder(customerID string, order Order, bulkCode *orders.BulkCodeDTO) (string, error) {
 
 	service := orders.OrderService{}
 
@@ -411,12 +432,24 @@ func createOrder(customerID string, order Order) (string, error) {
 		PieceDetails:          []orders.CreateOrderDetailDTO{},
 	}
 
+	// Orders placed with a bulk code are held to the date the code promised
+	if bulkCode != nil {
+		promisedDate, err := bulkCode.PromisedDate()
+		if err != nil {
+			return "", fmt.Errorf("[NewOrderHandler] err %w", err)
+		}
+
+		createOrderDTO.BulkCodeID = &bulkCode.ID
+		createOrderDTO.PromisedDate = promisedDate
+	}
+
 	for _, detail := range order.PieceDetails {
 		createOrderDTO.PieceDetails = append(createOrderDTO.PieceDetails, orders.CreateOrderDetailDTO{
 			Type:        detail.Type,
 			Size:        detail.Size,
 			Quantity:    detail.Quantity,
 			Description: detail.Description,
+			Glaze:       detail.Glaze,
 		})
 	}
 
@@ -439,6 +472,7 @@ func createOrderDetails(supabaseUrl, supabaseKey, orderID string, pieceDetails [
 			Size:        detail.Size,
 			Quantity:    detail.Quantity,
 			Description: detail.Description,
+			Glaze:       detail.Glaze,
 		})
 	}
 
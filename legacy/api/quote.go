@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"aliciapceramics/scheduler"
+	"aliciapceramics/server/orders"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxQuotePieceDetails bounds the work one public quote request can ask the
+// planner for
+const maxQuotePieceDetails = 20
+
+type QuoteRequest struct {
+	PieceDetails []PieceDetail `json:"pieceDetails"`
+}
+
+type PieceQuote struct {
+	Type                    string  `json:"type"`
+	Size                    *string `json:"size,omitempty"`
+	Quantity                int     `json:"quantity"`
+	EstimatedCompletionDate string  `json:"estimatedCompletionDate"`
+	FullyScheduled          bool    `json:"fullyScheduled"`
+}
+
+// QuoteHandler estimates when a prospective order would be ready without
+// creating any records. Quotes account for the studio's backlog, falling back
+// to the standard lead time when the schedule can't be loaded. A quote is only
+// fully scheduled when the planner fit all the work inside its horizon.
+func QuoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		LogError("invalid_method", fmt.Errorf("method %s not allowed", r.Method), map[string]any{
+			"method": r.Method,
+		})
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "INVALID_METHOD")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		LogError("read_body", err, map[string]any{
+			"content_length": r.ContentLength,
+		})
+		RespondWithError(w, http.StatusBadRequest, "Failed to read request body", "INVALID_REQUEST")
+		return
+	}
+	defer r.Body.Close()
+
+	var req QuoteRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		LogError("parse_json", err, map[string]any{
+			"body_length": len(body),
+		})
+		RespondWithError(w, http.StatusBadRequest, "Invalid request format", "INVALID_JSON")
+		return
+	}
+
+	orderDetails, err := quoteOrderDetails(req.PieceDetails)
+	if err != nil {
+		LogError("quote_validation_failed", err, map[string]any{
+			"piece_count": len(req.PieceDetails),
+		})
+		RespondWithError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+
+	quote, err := quoteDates(orderDetails)
+
+	var inputErr *scheduler.QuoteInputError
+	if errors.As(err, &inputErr) {
+		LogError("quote_validation_failed", err, map[string]any{
+			"piece_count": len(req.PieceDetails),
+		})
+		RespondWithError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+
+	if err != nil {
+		LogError("quote_order", err, map[string]any{
+			"piece_count": len(req.PieceDetails),
+		})
+		RespondWithError(w, http.StatusInternalServerError, "Failed to estimate completion date", "QUOTE_ERROR")
+		return
+	}
+
+	pieces := []PieceQuote{}
+	for i, piece := range req.PieceDetails {
+		pieces = append(pieces, PieceQuote{
+			Type:                    piece.Type,
+			Size:                    piece.Size,
+			Quantity:                piece.Quantity,
+			EstimatedCompletionDate: quote.Details[i].Date.Format("2006-01-02"),
+			FullyScheduled:          quote.Details[i].FullyScheduled,
+		})
+	}
+
+	RespondWithSuccess(w, "Quote estimated successfully", map[string]any{
+		"estimatedCompletionDate": quote.Date.Format("2006-01-02"),
+		"fullyScheduled":          quote.FullyScheduled,
+		"pieces":                  pieces,
+		"catalogVersion":          quote.CatalogVersion,
+	})
+}
+
+func quoteOrderDetails(pieceDetails []PieceDetail) ([]orders.OrderDetailDTO, error) {
+	if len(pieceDetails) > maxQuotePieceDetails {
+		return nil, fmt.Errorf("a quote can include at most %d piece details", maxQuotePieceDetails)
+	}
+
+	if err := validatePieceDetails(pieceDetails); err != nil {
+		return nil, err
+	}
+
+	orderDetails := []orders.OrderDetailDTO{}
+
+	for i, piece := range pieceDetails {
+		orderDetails = append(orderDetails, orders.OrderDetailDTO{
+			ID:       fmt.Sprintf("quote-%d", i+1),
+			Type:     piece.Type,
+			Size:     piece.Size,
+			Quantity: piece.Quantity,
+			Glaze:    piece.Glaze,
+			Status:   "pending",
+		})
+	}
+
+	return orderDetails, nil
+}
+
+// quoteDates falls back to the standard lead time when the backlog can't be
+// planned. Details the production process can't plan aren't retried.
+func quoteDates(orderDetails []orders.OrderDetailDTO) (scheduler.CapacityQuote, error) {
+	quote, err := scheduler.QuoteWithCapacity(orderDetails, scheduler.RunOptions{})
+
+	var inputErr *scheduler.QuoteInputError
+	if err == nil || errors.As(err, &inputErr) {
+		return quote, err
+	}
+
+	LogError("quote_with_capacity", err, map[string]any{
+		"piece_count": len(orderDetails),
+	})
+
+	return scheduler.QuoteLeadTime(orderDetails, time.Now())
+}
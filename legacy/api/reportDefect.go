@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"aliciapceramics/scheduler"
+	"aliciapceramics/server/orders"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReportDefectRequest struct {
+	OrderDetailID string `json:"orderDetailId"`
+	TaskID        string `json:"taskId,omitempty"`
+	StepKey       string `json:"stepKey"`
+	Quantity      int    `json:"quantity"`
+	Reason        string `json:"reason"`
+}
+
+type ReportDefectResponse struct {
+	Success        bool   `json:"success"`
+	Message        string `json:"message"`
+	DefectReportID string `json:"defectReportId,omitempty"`
+}
+
+func ReportDefectHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "Method not allowed",
+		})
+		return
+	}
+
+	var req ReportDefectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	if req.OrderDetailID == "" || req.StepKey == "" || req.Reason == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "Order detail ID, step key and reason are required",
+		})
+		return
+	}
+
+	if stepKey, valid := scheduler.IsValidStepKey(req.StepKey); !valid || stepKey == scheduler.StepKeyPending {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: fmt.Sprintf("unknown step key: %s", req.StepKey),
+		})
+		return
+	}
+
+	if req.Quantity <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "quantity must be greater than 0",
+		})
+		return
+	}
+
+	dbURL := os.Getenv("SUPABASE_DB_URL")
+	if dbURL == "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "Database configuration error",
+		})
+		return
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "Failed to connect to database",
+		})
+		return
+	}
+	defer pool.Close()
+
+	reportID, err := reportDefect(ctx, pool, req)
+	if err != nil {
+		var requestErr defectRequestError
+		if errors.As(err, &requestErr) {
+			w.WriteHeader(requestErr.status)
+			json.NewEncoder(w).Encode(ReportDefectResponse{
+				Success: false,
+				Message: requestErr.message,
+			})
+			return
+		}
+
+		LogError("report_defect", err, map[string]any{
+			"orderDetailId": req.OrderDetailID,
+			"taskId":        req.TaskID,
+		})
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ReportDefectResponse{
+			Success: false,
+			Message: "Failed to report defect",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReportDefectResponse{
+		Success:        true,
+		Message:        "Defect reported successfully",
+		DefectReportID: reportID,
+	})
+}
+
+// defectRequestError is a report that can't be made as asked, rather than a
+// failure to reach the database
+type defectRequestError struct {
+	status  int
+	message string
+}
+
+func (e defectRequestError) Error() string {
+	return e.message
+}
+
+// reportDefect records the lost pieces and takes them out of the order
+// detail's batch, or out of the remake the reported task belongs to. The next
+// scheduler run plans their remake from the build step as a separate chain.
+func reportDefect(ctx context.Context, db *pgxpool.Pool, req ReportDefectRequest) (string, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var orderDetail struct {
+		ID                string
+		OrderID           string
+		Quantity          int
+		Status            string
+		CompletedQuantity int
+		StatusChangedAt   *time.Time
+		StepProgress      []byte
+		Components        []byte
+		LostQuantity      int
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT id, order_id, quantity, status, completed_quantity, status_changed_at, step_progress, components,
+			(SELECT COALESCE(SUM(quantity), 0) FROM defect_reports WHERE order_detail_id = order_details.id)
+		FROM order_details
+		WHERE id = $1
+		FOR UPDATE
+	`, req.OrderDetailID).Scan(
+		&orderDetail.ID,
+		&orderDetail.OrderID,
+		&orderDetail.Quantity,
+		&orderDetail.Status,
+		&orderDetail.CompletedQuantity,
+		&orderDetail.StatusChangedAt,
+		&orderDetail.StepProgress,
+		&orderDetail.Components,
+		&orderDetail.LostQuantity,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", defectRequestError{http.StatusNotFound, fmt.Sprintf("order detail not found: %s", req.OrderDetailID)}
+		}
+		return "", fmt.Errorf("failed to fetch order detail: %w", err)
+	}
+
+	components, err := orders.DecodeComponents(orderDetail.Components)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode order detail components: %w", err)
+	}
+
+	// With a task, the defect is against the step that task works on, which
+	// may already count the lost pieces as done
+	var taskID *string
+	var task struct {
+		OrderDetailID  string
+		TaskType       string
+		StepKey        string
+		Component      string
+		Status         string
+		DefectReportID *string
+	}
+
+	if req.TaskID != "" {
+		err = tx.QueryRow(ctx, `
+			SELECT order_detail_id, task_type, COALESCE(step_key, ''), COALESCE(component, ''), status, defect_report_id
+			FROM tasks
+			WHERE id = $1
+		`, req.TaskID).Scan(&task.OrderDetailID, &task.TaskType, &task.StepKey, &task.Component, &task.Status, &task.DefectReportID)
+
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return "", defectRequestError{http.StatusNotFound, fmt.Sprintf("task not found: %s", req.TaskID)}
+			}
+			return "", fmt.Errorf("failed to fetch task: %w", err)
+		}
+
+		if task.OrderDetailID != orderDetail.ID {
+			return "", defectRequestError{http.StatusBadRequest, fmt.Sprintf("task %s doesn't belong to order detail %s", req.TaskID, orderDetail.ID)}
+		}
+
+		task.StepKey, err = orders.TaskStepKey(task.StepKey, task.TaskType)
+		if err != nil {
+			return "", fmt.Errorf("failed to determine task step: %w", err)
+		}
+
+		taskID = &req.TaskID
+	}
+
+	reportedAt := time.Now()
+
+	// Pieces lost from a remake come out of that remake's batch, and the main
+	// batch carries on as it was
+	if task.DefectReportID != nil {
+		if err := loseRemakePieces(ctx, tx, *task.DefectReportID, components, task.Component, task.StepKey, task.Status == "completed", req.Quantity, reportedAt); err != nil {
+			return "", err
+		}
+
+		return commitDefectReport(ctx, tx, orderDetail.ID, orderDetail.OrderID, taskID, req, reportedAt)
+	}
+
+	goodQuantity := orderDetail.Quantity - orderDetail.LostQuantity
+	if req.Quantity > goodQuantity {
+		return "", defectRequestError{http.StatusBadRequest, fmt.Sprintf("order detail %s only has %d pieces left to report", orderDetail.ID, goodQuantity)}
+	}
+
+	progress, err := orders.DecodeStepProgress(orderDetail.StepProgress)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode order detail step progress: %w", err)
+	}
+
+	batch := orders.NewStepBatch(orderDetail.Status, orderDetail.StatusChangedAt, orderDetail.CompletedQuantity, progress, components, goodQuantity, task.StepKey)
+	if task.Status == "completed" {
+		batch.Lose(task.Component, task.StepKey, req.Quantity)
+	}
+
+	// The rest of the batch may now be done with the steps in progress, or
+	// there may be nothing left of it
+	batch.GoodQuantity -= req.Quantity
+
+	if batch.GoodQuantity == 0 {
+		batch.CompleteAll(reportedAt)
+	} else if err := batch.FinishSteps(reportedAt); err != nil {
+		return "", fmt.Errorf("failed to determine next status: %w", err)
+	}
+
+	encodedProgress, err := orders.EncodeStepProgress(batch.Progress)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode order detail step progress: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE order_details
+		SET status = $1, status_changed_at = $2, step_progress = $3, completed_quantity = 0
+		WHERE id = $4
+	`, batch.Status, batch.StatusChangedAt, encodedProgress, orderDetail.ID)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to update order detail: %w", err)
+	}
+
+	return commitDefectReport(ctx, tx, orderDetail.ID, orderDetail.OrderID, taskID, req, reportedAt)
+}
+
+// loseRemakePieces moves lost pieces out of a remake into the new defect
+// report, so they're only counted against the order detail once
+func loseRemakePieces(ctx context.Context, tx pgx.Tx, defectReportID string, components []orders.OrderDetailComponentDTO, component string, stepKey string, counted bool, quantity int, reportedAt time.Time) error {
+	var remake struct {
+		Quantity          int
+		Status            string
+		CompletedQuantity int
+		StatusChangedAt   *time.Time
+		StepProgress      []byte
+	}
+
+	err := tx.QueryRow(ctx, `
+		SELECT quantity, remake_status, remake_completed_quantity, remake_status_changed_at, remake_step_progress
+		FROM defect_reports
+		WHERE id = $1
+		FOR UPDATE
+	`, defectReportID).Scan(&remake.Quantity, &remake.Status, &remake.CompletedQuantity, &remake.StatusChangedAt, &remake.StepProgress)
+
+	if err != nil {
+		return fmt.Errorf("failed to fetch defect report: %w", err)
+	}
+
+	if quantity > remake.Quantity {
+		return defectRequestError{http.StatusBadRequest, fmt.Sprintf("remake %s only has %d pieces left to report", defectReportID, remake.Quantity)}
+	}
+
+	progress, err := orders.DecodeStepProgress(remake.StepProgress)
+	if err != nil {
+		return fmt.Errorf("failed to decode remake step progress: %w", err)
+	}
+
+	batch := orders.NewStepBatch(remake.Status, remake.StatusChangedAt, remake.CompletedQuantity, progress, components, remake.Quantity, stepKey)
+	if counted {
+		batch.Lose(component, stepKey, quantity)
+	}
+
+	batch.GoodQuantity -= quantity
+
+	if batch.GoodQuantity == 0 {
+		batch.CompleteAll(reportedAt)
+	} else if err := batch.FinishSteps(reportedAt); err != nil {
+		return fmt.Errorf("failed to determine next status: %w", err)
+	}
+
+	encodedProgress, err := orders.EncodeStepProgress(batch.Progress)
+	if err != nil {
+		return fmt.Errorf("failed to encode remake step progress: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE defect_reports
+		SET quantity = $1, remake_status = $2, remake_status_changed_at = $3, remake_step_progress = $4, remake_completed_quantity = 0
+		WHERE id = $5
+	`, batch.GoodQuantity, batch.Status, batch.StatusChangedAt, encodedProgress, defectReportID)
+
+	if err != nil {
+		return fmt.Errorf("failed to update remake: %w", err)
+	}
+
+	return nil
+}
+
+// commitDefectReport records the report and the order status it leaves the
+// order in
+func commitDefectReport(ctx context.Context, tx pgx.Tx, orderDetailID string, orderID string, taskID *string, req ReportDefectRequest, reportedAt time.Time) (string, error) {
+	var reportID string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO defect_reports (order_detail_id, task_id, step_key, quantity, reason, reported_at, remake_status, remake_completed_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', 0)
+		RETURNING id
+	`, orderDetailID, taskID, req.StepKey, req.Quantity, req.Reason, reportedAt).Scan(&reportID)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to insert defect report: %w", err)
+	}
+
+	orderStatus, err := orders.CalculateOrderStatus(ctx, tx, orderID)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate order status: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE orders
+		SET status = $1, status_updated_at = $2, updated_at = $2
+		WHERE id = $3
+	`, orderStatus, reportedAt, orderID)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return reportID, nil
+}
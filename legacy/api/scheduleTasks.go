@@ -3,18 +3,210 @@ package handler
 import (
 	"aliciapceramics/scheduler"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func ScheduleTasksHandler(w http.ResponseWriter, r *http.Request) {
 
-	if err := scheduler.Run(); err != nil {
-		LogError("schedule_tasks", err, map[string]any{})
+	options := scheduler.RunOptions{
+		HorizonWeeks: scheduler.DefaultHorizonWeeks,
+	}
+
+	if weeks := r.URL.Query().Get("weeks"); weeks != "" {
+		horizonWeeks, err := strconv.Atoi(weeks)
+
+		if err != nil || horizonWeeks < 1 || horizonWeeks > scheduler.MaxHorizonWeeks {
+			LogError("schedule_tasks", fmt.Errorf("invalid weeks parameter %q", weeks), map[string]any{
+				"weeks": weeks,
+			})
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("weeks must be between 1 and %d", scheduler.MaxHorizonWeeks), "INVALID_HORIZON")
+			return
+		}
+
+		options.HorizonWeeks = horizonWeeks
+	}
+
+	if r.URL.Query().Get("overtime") == "true" {
+		overtime := scheduler.DefaultOvertime
+
+		if maxHours := r.URL.Query().Get("max_hours"); maxHours != "" {
+			maxHoursPerDay, err := strconv.ParseFloat(maxHours, 64)
+
+			if err != nil || maxHoursPerDay <= 0 || maxHoursPerDay > 24 {
+				LogError("schedule_tasks", fmt.Errorf("invalid max_hours parameter %q", maxHours), map[string]any{
+					"max_hours": maxHours,
+				})
+				RespondWithError(w, http.StatusBadRequest, "max_hours must be greater than 0 and at most 24", "INVALID_MAX_HOURS")
+				return
+			}
+
+			overtime.MaxHoursPerDay = maxHoursPerDay
+		}
+
+		options.Overtime = &overtime
+	}
+
+	// Past dates are replayed from the snapshot stored by that day's run
+	var asOfDate *time.Time
+
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		parsedAsOf, err := time.Parse("2006-01-02", asOf)
+
+		readOnly := r.URL.Query().Get("preview") == "true" || r.URL.Query().Get("simulate") == "true"
+
+		if err != nil || !readOnly {
+			LogError("schedule_tasks", fmt.Errorf("invalid as_of parameter %q", asOf), map[string]any{
+				"as_of": asOf,
+			})
+			RespondWithError(w, http.StatusBadRequest, "as_of must be a YYYY-MM-DD date and requires preview=true or simulate=true", "INVALID_AS_OF")
+			return
+		}
+
+		asOfDate = &parsedAsOf
+	}
+
+	if catalogVersion := r.URL.Query().Get("catalog_version"); catalogVersion != "" {
+		readOnly := r.URL.Query().Get("preview") == "true" || r.URL.Query().Get("simulate") == "true"
+
+		if !readOnly {
+			LogError("schedule_tasks", fmt.Errorf("catalog_version %q requires a preview or simulation", catalogVersion), map[string]any{
+				"catalog_version": catalogVersion,
+			})
+			RespondWithError(w, http.StatusBadRequest, "catalog_version requires preview=true or simulate=true", "INVALID_CATALOG_VERSION")
+			return
+		}
+
+		known, err := scheduler.HasCatalogVersion(catalogVersion)
+		if err != nil {
+			LogError("schedule_tasks", err, map[string]any{
+				"catalog_version": catalogVersion,
+			})
+			RespondWithError(w, http.StatusInternalServerError, "Failed to load production process", "PROCESS_ERROR")
+			return
+		}
+
+		if !known {
+			LogError("schedule_tasks", fmt.Errorf("unknown catalog_version %q", catalogVersion), map[string]any{
+				"catalog_version": catalogVersion,
+			})
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("catalog_version %q is not a known catalog version", catalogVersion), "INVALID_CATALOG_VERSION")
+			return
+		}
+
+		options.CatalogVersion = catalogVersion
+	}
+
+	if r.URL.Query().Get("simulate") == "true" {
+		simulation := scheduler.DefaultSimulation
+
+		if runs := r.URL.Query().Get("runs"); runs != "" {
+			simulationRuns, err := strconv.Atoi(runs)
+
+			if err != nil || simulationRuns < 1 || simulationRuns > scheduler.MaxSimulationRuns {
+				LogError("schedule_tasks_simulate", fmt.Errorf("invalid runs parameter %q", runs), map[string]any{
+					"runs": runs,
+				})
+				RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("runs must be between 1 and %d", scheduler.MaxSimulationRuns), "INVALID_RUNS")
+				return
+			}
+
+			simulation.Runs = simulationRuns
+		}
+
+		if asOfDate != nil {
+			result, found, err := scheduler.SimulateStored(*asOfDate, options, simulation)
+
+			if err != nil {
+				LogError("schedule_tasks_simulate", err, map[string]any{
+					"as_of": asOfDate.Format("2006-01-02"),
+					"runs":  simulation.Runs,
+				})
+				RespondWithError(w, http.StatusInternalServerError, "Failed to simulate completion dates", "SIMULATION_ERROR")
+				return
+			}
+
+			if !found {
+				RespondWithError(w, http.StatusNotFound, fmt.Sprintf("No schedule snapshot was stored on %s", asOfDate.Format("2006-01-02")), "SNAPSHOT_NOT_FOUND")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		result, err := scheduler.Simulate(options, simulation)
+
+		if err != nil {
+			LogError("schedule_tasks_simulate", err, map[string]any{
+				"horizon_weeks": options.HorizonWeeks,
+				"runs":          simulation.Runs,
+			})
+			RespondWithError(w, http.StatusInternalServerError, "Failed to simulate completion dates", "SIMULATION_ERROR")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		if asOfDate != nil {
+			result, found, err := scheduler.ReplayStored(*asOfDate, options)
+
+			if err != nil {
+				LogError("schedule_tasks_replay", err, map[string]any{
+					"as_of":         asOfDate.Format("2006-01-02"),
+					"horizon_weeks": options.HorizonWeeks,
+				})
+				RespondWithError(w, http.StatusInternalServerError, "Failed to replay schedule", "REPLAY_ERROR")
+				return
+			}
+
+			if !found {
+				RespondWithError(w, http.StatusNotFound, fmt.Sprintf("No schedule snapshot was stored on %s", asOfDate.Format("2006-01-02")), "SNAPSHOT_NOT_FOUND")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		preview, err := scheduler.Preview(options)
+
+		if err != nil {
+			LogError("schedule_tasks_preview", err, map[string]any{
+				"horizon_weeks": options.HorizonWeeks,
+			})
+			RespondWithError(w, http.StatusInternalServerError, "Failed to build schedule preview", "PREVIEW_ERROR")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(preview)
+		return
+	}
+
+	result, err := scheduler.Run(options)
+
+	if err != nil {
+		LogError("schedule_tasks", err, map[string]any{
+			"horizon_weeks": options.HorizonWeeks,
+		})
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	json.NewEncoder(w).Encode(result)
 }
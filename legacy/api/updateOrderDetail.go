@@ -1,21 +1,27 @@
 package handler
 
 import (
+	"aliciapceramics/scheduler"
 	"aliciapceramics/server/orders"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UpdateOrderDetailRequest struct {
-	OrderDetailID     string `json:"orderDetailId"`
-	Status            string `json:"status,omitempty"`
-	CompletedQuantity *int   `json:"completedQuantity,omitempty"`
+	OrderDetailID     string                           `json:"orderDetailId"`
+	Status            string                           `json:"status,omitempty"`
+	CompletedQuantity *int                             `json:"completedQuantity,omitempty"`
+	Glaze             *string                          `json:"glaze,omitempty"`
+	Components        []orders.OrderDetailComponentDTO `json:"components,omitempty"`
+	CustomSteps       []orders.ProcessStepDTO          `json:"customSteps,omitempty"`
+	OptionalSteps     []string                         `json:"optionalSteps,omitempty"`
 }
 
 type UpdateOrderDetailResponse struct {
@@ -108,15 +114,18 @@ func updateOrderDetail(ctx context.Context, db *pgxpool.Pool, req UpdateOrderDet
 	defer tx.Rollback(ctx)
 
 	var orderDetail struct {
-		ID      string
-		OrderID string
+		ID            string
+		OrderID       string
+		Type          string
+		Size          *string
+		OptionalSteps []string
 	}
 
 	err = tx.QueryRow(ctx, `
-		SELECT id, order_id
+		SELECT id, order_id, type, size, optional_steps
 		FROM order_details
 		WHERE id = $1
-	`, req.OrderDetailID).Scan(&orderDetail.ID, &orderDetail.OrderID)
+	`, req.OrderDetailID).Scan(&orderDetail.ID, &orderDetail.OrderID, &orderDetail.Type, &orderDetail.Size, &orderDetail.OptionalSteps)
 
 	if err != nil {
 		return fmt.Errorf("failed to fetch order detail: %w", err)
@@ -142,6 +151,79 @@ func updateOrderDetail(ctx context.Context, db *pgxpool.Pool, req UpdateOrderDet
 		argCount++
 	}
 
+	// Progress set by hand replaces what was tracked step by step
+	if req.Status != "" || req.CompletedQuantity != nil {
+		updates["step_progress"] = nil
+		updateQuery += "step_progress = NULL, "
+	}
+
+	if req.Glaze != nil {
+		// An empty glaze clears it
+		var glaze *string
+		if trimmed := strings.TrimSpace(*req.Glaze); trimmed != "" {
+			glaze = &trimmed
+		}
+
+		updates["glaze"] = glaze
+		updateQuery += fmt.Sprintf("glaze = $%d, ", argCount)
+		args = append(args, glaze)
+		argCount++
+	}
+
+	if req.Components != nil || req.CustomSteps != nil || req.OptionalSteps != nil {
+		optionalSteps := orderDetail.OptionalSteps
+		if req.OptionalSteps != nil {
+			optionalSteps = req.OptionalSteps
+		}
+
+		if err := scheduler.InstallConfiguredProcess(); err != nil {
+			return fmt.Errorf("failed to load production process: %w", err)
+		}
+
+		err := scheduler.ValidateOrderDetailProcess(orders.OrderDetailDTO{
+			ID:            orderDetail.ID,
+			Type:          orderDetail.Type,
+			Size:          orderDetail.Size,
+			Components:    req.Components,
+			CustomSteps:   req.CustomSteps,
+			OptionalSteps: optionalSteps,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid production process: %w", err)
+		}
+	}
+
+	if req.Components != nil {
+		components, err := orders.EncodeComponents(req.Components)
+		if err != nil {
+			return fmt.Errorf("failed to encode components: %w", err)
+		}
+
+		updates["components"] = components
+		updateQuery += fmt.Sprintf("components = $%d, ", argCount)
+		args = append(args, components)
+		argCount++
+	}
+
+	if req.CustomSteps != nil {
+		customSteps, err := orders.EncodeCustomSteps(req.CustomSteps)
+		if err != nil {
+			return fmt.Errorf("failed to encode custom steps: %w", err)
+		}
+
+		updates["custom_steps"] = customSteps
+		updateQuery += fmt.Sprintf("custom_steps = $%d, ", argCount)
+		args = append(args, customSteps)
+		argCount++
+	}
+
+	if req.OptionalSteps != nil {
+		updates["optional_steps"] = req.OptionalSteps
+		updateQuery += fmt.Sprintf("optional_steps = $%d, ", argCount)
+		args = append(args, req.OptionalSteps)
+		argCount++
+	}
+
 	if len(updates) == 0 {
 		return fmt.Errorf("no fields to update")
 	}
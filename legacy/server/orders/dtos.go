@@ -9,10 +9,53 @@ type OrderDetailDTO struct {
 	Size              *string
 	Quantity          int
 	Description       string
+	Glaze             *string
 	Status            string
 	CompletedQuantity int
 	StatusChangedAt   *time.Time
 	CreatedAt         *time.Time
+	Components        []OrderDetailComponentDTO
+	CustomSteps       []ProcessStepDTO
+	OptionalSteps     []string
+	Remakes           []RemakeDTO
+	// StepProgress is keyed by StepProgressKey, so branches that run side by
+	// side are completed independently of the status
+	StepProgress map[string]StepProgressDTO
+}
+
+// RemakeDTO tracks the replacement pieces for a defect report through the
+// production process, separately from the rest of the order detail
+type RemakeDTO struct {
+	ID                string
+	StepKey           string
+	Reason            string
+	Quantity          int
+	Status            string
+	CompletedQuantity int
+	StatusChangedAt   *time.Time
+	StepProgress      map[string]StepProgressDTO
+}
+
+// StepProgressDTO is how many pieces have been through one step, and when
+// the step was finished for the whole batch
+type StepProgressDTO struct {
+	CompletedQuantity int
+	CompletedAt       *time.Time
+}
+
+type OrderDetailComponentDTO struct {
+	Name            string
+	QuantityPerUnit int
+	Steps           []ProcessStepDTO
+}
+
+type ProcessStepDTO struct {
+	StepKey    string
+	TaskType   string
+	Rate       float64
+	DryingDays int
+	After      []string
+	LossRate   float64
 }
 
 type OrderDTO struct {
@@ -22,7 +65,14 @@ type OrderDTO struct {
 	Timeline     string
 	Status       string
 	DueDate      *time.Time
-	OrderDetails []OrderDetailDTO
+	CreatedAt    *time.Time
+	BulkCodeID   *string
+	PromisedDate *time.Time
+	// ProposedDueDate is read from the timeline and waits for an admin to
+	// confirm it as DueDate
+	ProposedDueDate     *time.Time
+	TimelineFlexibility TimelineFlexibility
+	OrderDetails        []OrderDetailDTO
 }
 
 type OrdersDTO struct {
@@ -34,6 +84,7 @@ type CreateOrderDetailDTO struct {
 	Size        *string
 	Quantity    int
 	Description string
+	Glaze       *string
 }
 
 type CreateOrderDTO struct {
@@ -43,6 +94,8 @@ type CreateOrderDTO struct {
 	Inspiration           string
 	SpecialConsiderations string
 	Consent               bool
+	BulkCodeID            *string
+	PromisedDate          *time.Time
 }
 
 type UpdateOrderDTO struct {
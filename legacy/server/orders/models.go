@@ -13,6 +13,10 @@ type orderRow struct {
 	AccessToken           string           `json:"access_token"`
 	Status                string           `json:"status"`
 	DueDate               *time.Time       `json:"due_date"`
+	BulkCommissionCodeID  *string          `json:"bulk_commission_code_id,omitempty"`
+	PromisedDate          *time.Time       `json:"promised_date,omitempty"`
+	ProposedDueDate       *time.Time       `json:"proposed_due_date,omitempty"`
+	TimelineFlexibility   string           `json:"timeline_flexibility,omitempty"`
 	OrderDetails          []orderDetailRow `json:"order_details"`
 	StatusChangedAt       *time.Time       `json:"status_changed_at,omitempty"`
 	CreatedAt             *time.Time       `json:"created_at,omitempty"`
@@ -20,16 +24,56 @@ type orderRow struct {
 }
 
 type orderDetailRow struct {
-	ID                string     `json:"id,omitempty"`
-	OrderID           string     `json:"order_id"`
-	Type              string     `json:"type"`
-	Size              *string    `json:"size,omitempty"`
-	Quantity          int        `json:"quantity"`
-	Description       string     `json:"description"`
-	Status            string     `json:"status"`
+	ID                string                     `json:"id,omitempty"`
+	OrderID           string                     `json:"order_id"`
+	Type              string                     `json:"type"`
+	Size              *string                    `json:"size,omitempty"`
+	Quantity          int                        `json:"quantity"`
+	Description       string                     `json:"description"`
+	Glaze             *string                    `json:"glaze,omitempty"`
+	Status            string                     `json:"status"`
+	CompletedQuantity int                        `json:"completed_quantity"`
+	StatusChangedAt   *time.Time                 `json:"status_changed_at,omitempty"`
+	CreatedAt         *time.Time                 `json:"created_at,omitempty"`
+	Components        []orderDetailComponentRow  `json:"components,omitempty"`
+	CustomSteps       []processStepRow           `json:"custom_steps,omitempty"`
+	OptionalSteps     []string                   `json:"optional_steps,omitempty"`
+	DefectReports     []defectReportRow          `json:"defect_reports,omitempty"`
+	StepProgress      map[string]stepProgressRow `json:"step_progress,omitempty"`
+}
+
+type defectReportRow struct {
+	ID                      string                     `json:"id"`
+	OrderDetailID           string                     `json:"order_detail_id"`
+	TaskID                  *string                    `json:"task_id,omitempty"`
+	StepKey                 string                     `json:"step_key"`
+	Quantity                int                        `json:"quantity"`
+	Reason                  string                     `json:"reason"`
+	ReportedAt              *time.Time                 `json:"reported_at,omitempty"`
+	RemakeStatus            string                     `json:"remake_status"`
+	RemakeCompletedQuantity int                        `json:"remake_completed_quantity"`
+	RemakeStatusChangedAt   *time.Time                 `json:"remake_status_changed_at,omitempty"`
+	RemakeStepProgress      map[string]stepProgressRow `json:"remake_step_progress,omitempty"`
+}
+
+type stepProgressRow struct {
 	CompletedQuantity int        `json:"completed_quantity"`
-	StatusChangedAt   *time.Time `json:"status_changed_at,omitempty"`
-	CreatedAt         *time.Time `json:"created_at,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+type orderDetailComponentRow struct {
+	Name            string           `json:"name"`
+	QuantityPerUnit int              `json:"quantity_per_unit"`
+	Steps           []processStepRow `json:"steps,omitempty"`
+}
+
+type processStepRow struct {
+	StepKey    string   `json:"step_key"`
+	TaskType   string   `json:"task_type"`
+	Rate       float64  `json:"rate"`
+	DryingDays int      `json:"drying_days"`
+	After      []string `json:"after,omitempty"`
+	LossRate   float64  `json:"loss_rate,omitempty"`
 }
 
 type bulkCodeRow struct {
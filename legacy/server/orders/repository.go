@@ -10,6 +10,7 @@ import (
 
 type bulkCodeRepository interface {
 	GetByCode(code string) ([]bulkCodeRow, error)
+	GetByID(bulkCodeID string) ([]bulkCodeRow, error)
 	MarkAsRedeemed(bulkCodeID string) error
 }
 
@@ -35,6 +36,26 @@ func (r *supabaseBulkCodeRepository) GetByCode(code string) ([]bulkCodeRow, erro
 	return bulkCodes, nil
 }
 
+func (r *supabaseBulkCodeRepository) GetByID(bulkCodeID string) ([]bulkCodeRow, error) {
+	body, statusCode, err := database.MakeDBCall("GET", fmt.Sprintf("bulk_commission_codes?select=*&id=eq.%s", bulkCodeID), nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("[BulkCodeRepository:GetByID] request failed: %w", err)
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("[BulkCodeRepository:GetByID] failed with status code %d: %s", statusCode, string(body))
+	}
+
+	var bulkCodes []bulkCodeRow
+
+	if err := json.Unmarshal(body, &bulkCodes); err != nil {
+		return nil, fmt.Errorf("[BulkCodeRepository:GetByID] failed to parse body: %w", err)
+	}
+
+	return bulkCodes, nil
+}
+
 func (r *supabaseBulkCodeRepository) MarkAsRedeemed(bulkCodeID string) error {
 	updateData := map[string]string{
 		"redeemed_at": "now()",
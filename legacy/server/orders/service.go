@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -23,7 +24,7 @@ type OrderService struct {
 
 func (s *OrderService) GetOrdersWithDeadlines() (OrdersDTO, error) {
 
-	body, statusCode, err := database.MakeDBCall("GET", "orders?select=*,order_details(*)&due_date=not.is.null&status=neq.delivered&status=neq.cancelled&status=neq.completed&order=due_date.asc", nil)
+	body, statusCode, err := database.MakeDBCall("GET", "orders?select=*,order_details(*,defect_reports(*))&due_date=not.is.null&status=neq.delivered&status=neq.cancelled&status=neq.completed&order=due_date.asc", nil)
 
 	if err != nil {
 		return OrdersDTO{}, fmt.Errorf("error in GetOrders: %w", err)
@@ -48,13 +49,18 @@ func (s *OrderService) GetOrdersWithDeadlines() (OrdersDTO, error) {
 	for _, orderRow := range orders {
 
 		orderDTO := OrderDTO{
-			ID:           orderRow.ID,
-			CustomerID:   orderRow.CustomerID,
-			Type:         orderRow.Type,
-			DueDate:      orderRow.DueDate,
-			Timeline:     orderRow.Timeline,
-			Status:       orderRow.Status,
-			OrderDetails: []OrderDetailDTO{},
+			ID:                  orderRow.ID,
+			CustomerID:          orderRow.CustomerID,
+			Type:                orderRow.Type,
+			DueDate:             orderRow.DueDate,
+			Timeline:            orderRow.Timeline,
+			Status:              orderRow.Status,
+			CreatedAt:           orderRow.CreatedAt,
+			BulkCodeID:          orderRow.BulkCommissionCodeID,
+			PromisedDate:        orderRow.PromisedDate,
+			ProposedDueDate:     orderRow.ProposedDueDate,
+			TimelineFlexibility: TimelineFlexibility(orderRow.TimelineFlexibility),
+			OrderDetails:        []OrderDetailDTO{},
 		}
 
 		for _, orderDetailRow := range orderRow.OrderDetails {
@@ -64,8 +70,14 @@ func (s *OrderService) GetOrdersWithDeadlines() (OrdersDTO, error) {
 				Size:              orderDetailRow.Size,
 				Quantity:          orderDetailRow.Quantity,
 				Description:       orderDetailRow.Description,
+				Glaze:             orderDetailRow.Glaze,
 				Status:            orderDetailRow.Status,
 				CompletedQuantity: orderDetailRow.CompletedQuantity,
+				Components:        componentsFromRows(orderDetailRow.Components),
+				CustomSteps:       stepsFromRows(orderDetailRow.CustomSteps),
+				OptionalSteps:     orderDetailRow.OptionalSteps,
+				Remakes:           remakesFromRows(orderDetailRow.DefectReports),
+				StepProgress:      stepProgressFromRows(orderDetailRow.StepProgress),
 			})
 		}
 
@@ -77,7 +89,7 @@ func (s *OrderService) GetOrdersWithDeadlines() (OrdersDTO, error) {
 
 func (s *OrderService) GetNonDeadlineOrders() (OrdersDTO, error) {
 
-	body, statusCode, err := database.MakeDBCall("GET", "orders?select=*,order_details(*)&due_date=not.is.null&status=neq.delivered&status=neq.cancelled&status=neq.completed&order=due_date.asc", nil)
+	body, statusCode, err := database.MakeDBCall("GET", "orders?select=*,order_details(*,defect_reports(*))&due_date=is.null&status=neq.delivered&status=neq.cancelled&status=neq.completed&order=created_at.asc", nil)
 
 	if err != nil {
 		return OrdersDTO{}, fmt.Errorf("error in GetOrders: %w", err)
@@ -102,13 +114,18 @@ func (s *OrderService) GetNonDeadlineOrders() (OrdersDTO, error) {
 	for _, orderRow := range orders {
 
 		orderDTO := OrderDTO{
-			ID:           orderRow.ID,
-			CustomerID:   orderRow.CustomerID,
-			Type:         orderRow.Type,
-			DueDate:      orderRow.DueDate,
-			Timeline:     orderRow.Timeline,
-			Status:       orderRow.Status,
-			OrderDetails: []OrderDetailDTO{},
+			ID:                  orderRow.ID,
+			CustomerID:          orderRow.CustomerID,
+			Type:                orderRow.Type,
+			DueDate:             orderRow.DueDate,
+			Timeline:            orderRow.Timeline,
+			Status:              orderRow.Status,
+			CreatedAt:           orderRow.CreatedAt,
+			BulkCodeID:          orderRow.BulkCommissionCodeID,
+			PromisedDate:        orderRow.PromisedDate,
+			ProposedDueDate:     orderRow.ProposedDueDate,
+			TimelineFlexibility: TimelineFlexibility(orderRow.TimelineFlexibility),
+			OrderDetails:        []OrderDetailDTO{},
 		}
 
 		for _, orderDetailRow := range orderRow.OrderDetails {
@@ -118,8 +135,14 @@ func (s *OrderService) GetNonDeadlineOrders() (OrdersDTO, error) {
 				Size:              orderDetailRow.Size,
 				Quantity:          orderDetailRow.Quantity,
 				Description:       orderDetailRow.Description,
+				Glaze:             orderDetailRow.Glaze,
 				Status:            orderDetailRow.Status,
 				CompletedQuantity: orderDetailRow.CompletedQuantity,
+				Components:        componentsFromRows(orderDetailRow.Components),
+				CustomSteps:       stepsFromRows(orderDetailRow.CustomSteps),
+				OptionalSteps:     orderDetailRow.OptionalSteps,
+				Remakes:           remakesFromRows(orderDetailRow.DefectReports),
+				StepProgress:      stepProgressFromRows(orderDetailRow.StepProgress),
 			})
 		}
 
@@ -134,6 +157,9 @@ func (s *OrderService) CreateOrder(payload CreateOrderDTO) (OrderDTO, error) {
 
 	accessToken := uuid.New().String()
 
+	// The timeline is only proposed as a due date, for an admin to confirm
+	timeline := ParseTimeline(payload.Timeline, time.Now())
+
 	orderToCreate := orderRow{
 		CustomerID:            payload.CustomerID,
 		Timeline:              payload.Timeline,
@@ -142,6 +168,12 @@ func (s *OrderService) CreateOrder(payload CreateOrderDTO) (OrderDTO, error) {
 		Consent:               payload.Consent,
 		Status:                "pending",
 		AccessToken:           accessToken,
+		BulkCommissionCodeID:  payload.BulkCodeID,
+		PromisedDate:          payload.PromisedDate,
+		ProposedDueDate:       timeline.DueDate,
+		TimelineFlexibility:   string(timeline.Flexibility),
+		// The promised date is the order's deadline until an admin changes it
+		DueDate: payload.PromisedDate,
 	}
 
 	orderJSON, err := json.Marshal(orderToCreate)
@@ -173,13 +205,18 @@ func (s *OrderService) CreateOrder(payload CreateOrderDTO) (OrderDTO, error) {
 	orderCreated := result[0]
 
 	dto := OrderDTO{
-		ID:           orderCreated.ID,
-		CustomerID:   orderCreated.CustomerID,
-		Type:         orderCreated.Type,
-		DueDate:      orderCreated.DueDate,
-		Timeline:     orderCreated.Timeline,
-		Status:       orderCreated.Status,
-		OrderDetails: []OrderDetailDTO{},
+		ID:                  orderCreated.ID,
+		CustomerID:          orderCreated.CustomerID,
+		Type:                orderCreated.Type,
+		DueDate:             orderCreated.DueDate,
+		Timeline:            orderCreated.Timeline,
+		Status:              orderCreated.Status,
+		CreatedAt:           orderCreated.CreatedAt,
+		BulkCodeID:          orderCreated.BulkCommissionCodeID,
+		PromisedDate:        orderCreated.PromisedDate,
+		ProposedDueDate:     orderCreated.ProposedDueDate,
+		TimelineFlexibility: TimelineFlexibility(orderCreated.TimelineFlexibility),
+		OrderDetails:        []OrderDetailDTO{},
 	}
 
 	for _, orderDetailRow := range orderCreated.OrderDetails {
@@ -189,32 +226,169 @@ func (s *OrderService) CreateOrder(payload CreateOrderDTO) (OrderDTO, error) {
 			Size:              orderDetailRow.Size,
 			Quantity:          orderDetailRow.Quantity,
 			Description:       orderDetailRow.Description,
+			Glaze:             orderDetailRow.Glaze,
 			Status:            orderDetailRow.Status,
 			CompletedQuantity: orderDetailRow.CompletedQuantity,
+			Components:        componentsFromRows(orderDetailRow.Components),
+			CustomSteps:       stepsFromRows(orderDetailRow.CustomSteps),
+			OptionalSteps:     orderDetailRow.OptionalSteps,
 		})
 	}
 
 	return dto, nil
 }
 
-func GetNextStatus(taskType string) (string, error) {
-	statusMap := map[string]string{
+func componentsFromRows(rows []orderDetailComponentRow) []OrderDetailComponentDTO {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	components := make([]OrderDetailComponentDTO, 0, len(rows))
+	for _, row := range rows {
+		components = append(components, OrderDetailComponentDTO{
+			Name:            row.Name,
+			QuantityPerUnit: row.QuantityPerUnit,
+			Steps:           stepsFromRows(row.Steps),
+		})
+	}
+
+	return components
+}
+
+func componentsToRows(components []OrderDetailComponentDTO) []orderDetailComponentRow {
+	rows := make([]orderDetailComponentRow, 0, len(components))
+	for _, component := range components {
+		rows = append(rows, orderDetailComponentRow{
+			Name:            component.Name,
+			QuantityPerUnit: component.QuantityPerUnit,
+			Steps:           stepsToRows(component.Steps),
+		})
+	}
+
+	return rows
+}
+
+func stepsToRows(steps []ProcessStepDTO) []processStepRow {
+	rows := make([]processStepRow, 0, len(steps))
+	for _, step := range steps {
+		rows = append(rows, processStepRow{
+			StepKey:    step.StepKey,
+			TaskType:   step.TaskType,
+			Rate:       step.Rate,
+			DryingDays: step.DryingDays,
+			After:      step.After,
+			LossRate:   step.LossRate,
+		})
+	}
+
+	return rows
+}
+
+// EncodeComponents, EncodeCustomSteps and DecodeComponents convert the json
+// stored in the order_details components and custom_steps columns
+func EncodeComponents(components []OrderDetailComponentDTO) ([]byte, error) {
+	return json.Marshal(componentsToRows(components))
+}
+
+func EncodeCustomSteps(steps []ProcessStepDTO) ([]byte, error) {
+	return json.Marshal(stepsToRows(steps))
+}
+
+func DecodeComponents(data []byte) ([]OrderDetailComponentDTO, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var rows []orderDetailComponentRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	return componentsFromRows(rows), nil
+}
+
+func stepsFromRows(rows []processStepRow) []ProcessStepDTO {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	steps := make([]ProcessStepDTO, 0, len(rows))
+	for _, row := range rows {
+		steps = append(steps, ProcessStepDTO{
+			StepKey:    row.StepKey,
+			TaskType:   row.TaskType,
+			Rate:       row.Rate,
+			DryingDays: row.DryingDays,
+			After:      row.After,
+			LossRate:   row.LossRate,
+		})
+	}
+
+	return steps
+}
+
+func remakesFromRows(rows []defectReportRow) []RemakeDTO {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	remakes := make([]RemakeDTO, 0, len(rows))
+	for _, row := range rows {
+		remakes = append(remakes, RemakeDTO{
+			ID:                row.ID,
+			StepKey:           row.StepKey,
+			Reason:            row.Reason,
+			Quantity:          row.Quantity,
+			Status:            row.RemakeStatus,
+			CompletedQuantity: row.RemakeCompletedQuantity,
+			StatusChangedAt:   row.RemakeStatusChangedAt,
+			StepProgress:      stepProgressFromRows(row.RemakeStepProgress),
+		})
+	}
+
+	return remakes
+}
+
+// GetNextStatus is the status an order detail reaches once a step is done
+func GetNextStatus(stepKey string) (string, error) {
+	if _, known := statusPriority[stepKey]; !known || stepKey == "pending" || stepKey == "completed" {
+		return "", fmt.Errorf("unknown step key: %s", stepKey)
+	}
+
+	if stepKey == "fire" {
+		return "completed", nil
+	}
+
+	return stepKey, nil
+}
+
+// TaskStepKey is the step a task works on. Tasks planned before steps were
+// recorded on them fall back to their task type, which can't tell steps
+// sharing a task type apart.
+func TaskStepKey(stepKey string, taskType string) (string, error) {
+	if stepKey != "" {
+		return stepKey, nil
+	}
+
+	stepMap := map[string]string{
 		"task_build_base":    "build",
 		"task_build_bowl":    "build",
 		"task_trim":          "trim",
 		"task_attach_handle": "attach",
 		"task_attach_lid":    "attach",
+		"task_pull_handles":  "handles",
+		"task_wax":           "wax",
 		"task_bisque":        "bisque",
 		"task_glaze":         "glaze",
-		"task_fire":          "completed",
+		"task_fire":          "fire",
 	}
 
-	nextStatus, ok := statusMap[taskType]
+	taskStepKey, ok := stepMap[taskType]
 	if !ok {
 		return "", fmt.Errorf("unknown task type: %s", taskType)
 	}
 
-	return nextStatus, nil
+	return taskStepKey, nil
 }
 
 type BulkCodeService struct {
@@ -237,6 +411,25 @@ func (s *BulkCodeService) ValidateCode(code string) (*BulkCodeDTO, error) {
 		return nil, err
 	}
 
+	return redeemableCode(bulkCodes)
+}
+
+// GetRedeemableCode looks up a code by ID for an order being placed with it,
+// so the order can hold on to the date the code promised
+func (s *BulkCodeService) GetRedeemableCode(bulkCodeID string) (*BulkCodeDTO, error) {
+	if bulkCodeID == "" {
+		return nil, fmt.Errorf("bulk code ID is required")
+	}
+
+	bulkCodes, err := s.repository.GetByID(bulkCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return redeemableCode(bulkCodes)
+}
+
+func redeemableCode(bulkCodes []bulkCodeRow) (*BulkCodeDTO, error) {
 	if len(bulkCodes) == 0 {
 		return nil, fmt.Errorf("invalid code")
 	}
@@ -261,6 +454,25 @@ func (s *BulkCodeService) ValidateCode(code string) (*BulkCodeDTO, error) {
 	}, nil
 }
 
+// PromisedDate is the code's earliest completion date, or nil when the code
+// doesn't promise one
+func (d BulkCodeDTO) PromisedDate() (*time.Time, error) {
+	if d.EarliestCompletionDate == "" {
+		return nil, nil
+	}
+
+	promisedDate, err := time.Parse("2006-01-02", d.EarliestCompletionDate)
+	if err != nil {
+		promisedDate, err = time.Parse(time.RFC3339, d.EarliestCompletionDate)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid earliest completion date %q for bulk code %s", d.EarliestCompletionDate, d.Code)
+	}
+
+	return &promisedDate, nil
+}
+
 func (s *BulkCodeService) MarkAsRedeemed(bulkCodeID string) error {
 	if bulkCodeID == "" {
 		return fmt.Errorf("bulk code ID is required")
@@ -269,11 +481,42 @@ func (s *BulkCodeService) MarkAsRedeemed(bulkCodeID string) error {
 	return s.repository.MarkAsRedeemed(bulkCodeID)
 }
 
+var statusPriority = map[string]int{
+	"pending":    0,
+	"build":      1,
+	"handles":    2,
+	"trim":       3,
+	"attach":     4,
+	"trim_final": 5,
+	"bisque":     6,
+	"wax":        7,
+	"glaze":      8,
+	"glaze_coat": 9,
+	"fire":       10,
+	"completed":  11,
+}
+
+// AdvanceStatus moves a status on to next, unless a branch that finished
+// earlier already took it further
+func AdvanceStatus(current string, next string) string {
+	if statusPriority[next] < statusPriority[current] {
+		return current
+	}
+
+	return next
+}
+
 func CalculateOrderStatus(ctx context.Context, tx pgx.Tx, orderID string) (string, error) {
+	// Remakes still in production hold the order back like any other detail
 	rows, err := tx.Query(ctx, `
 		SELECT status
 		FROM order_details
 		WHERE order_id = $1
+		UNION ALL
+		SELECT defect_reports.remake_status
+		FROM defect_reports
+		JOIN order_details ON order_details.id = defect_reports.order_detail_id
+		WHERE order_details.order_id = $1 AND defect_reports.remake_status <> 'completed'
 	`, orderID)
 
 	if err != nil {
@@ -298,26 +541,17 @@ func CalculateOrderStatus(ctx context.Context, tx pgx.Tx, orderID string) (strin
 		return "pending", nil
 	}
 
-	statusPriority := map[string]int{
-		"pending":    0,
-		"build":      1,
-		"trim":       2,
-		"attach":     3,
-		"trim_final": 4,
-		"bisque":     5,
-		"glaze":      6,
-		"fire":       7,
-		"completed":  8,
-	}
-
 	statusToOrderStatus := map[string]string{
 		"pending":    "pending",
 		"build":      "building",
+		"handles":    "building",
 		"trim":       "trimming",
 		"attach":     "building",
 		"trim_final": "trimming",
 		"bisque":     "bisque_firing",
+		"wax":        "glazing",
 		"glaze":      "glazing",
+		"glaze_coat": "glazing",
 		"fire":       "glaze_firing",
 		"completed":  "completed",
 	}
@@ -3,10 +3,12 @@ package orders
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 type mockBulkCodeRepository struct {
 	getByCodeFunc       func(code string) ([]bulkCodeRow, error)
+	getByIDFunc         func(bulkCodeID string) ([]bulkCodeRow, error)
 	markAsRedeemedFunc  func(bulkCodeID string) error
 }
 
@@ -17,6 +19,13 @@ func (m *mockBulkCodeRepository) GetByCode(code string) ([]bulkCodeRow, error) {
 	return nil, nil
 }
 
+func (m *mockBulkCodeRepository) GetByID(bulkCodeID string) ([]bulkCodeRow, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(bulkCodeID)
+	}
+	return nil, nil
+}
+
 func (m *mockBulkCodeRepository) MarkAsRedeemed(bulkCodeID string) error {
 	if m.markAsRedeemedFunc != nil {
 		return m.markAsRedeemedFunc(bulkCodeID)
@@ -213,3 +222,139 @@ func TestMarkAsRedeemed(t *testing.T) {
 		}
 	})
 }
+
+func TestGetRedeemableCode(t *testing.T) {
+	t.Run("returns error when bulk code ID is empty", func(t *testing.T) {
+		service := &BulkCodeService{
+			repository: &mockBulkCodeRepository{},
+		}
+
+		_, err := service.GetRedeemableCode("")
+
+		if err == nil || err.Error() != "bulk code ID is required" {
+			t.Errorf("expected error 'bulk code ID is required', got '%v'", err)
+		}
+	})
+
+	t.Run("returns error when code has already been redeemed", func(t *testing.T) {
+		redeemedAt := "2025-01-15T10:00:00Z"
+		service := &BulkCodeService{
+			repository: &mockBulkCodeRepository{
+				getByIDFunc: func(bulkCodeID string) ([]bulkCodeRow, error) {
+					return []bulkCodeRow{{ID: bulkCodeID, Code: "ABC12345", RedeemedAt: &redeemedAt}}, nil
+				},
+			},
+		}
+
+		_, err := service.GetRedeemableCode("code-123")
+
+		if err == nil || err.Error() != "code has already been redeemed" {
+			t.Errorf("expected error 'code has already been redeemed', got '%v'", err)
+		}
+	})
+
+	t.Run("returns the code and its promised date", func(t *testing.T) {
+		service := &BulkCodeService{
+			repository: &mockBulkCodeRepository{
+				getByIDFunc: func(bulkCodeID string) ([]bulkCodeRow, error) {
+					if bulkCodeID != "code-123" {
+						t.Errorf("expected bulkCodeID 'code-123', got '%s'", bulkCodeID)
+					}
+					return []bulkCodeRow{{ID: "code-123", Code: "ABC12345", EarliestCompletionDate: "2026-03-01"}}, nil
+				},
+			},
+		}
+
+		result, err := service.GetRedeemableCode("code-123")
+
+		if err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		promisedDate, err := result.PromisedDate()
+
+		if err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if promisedDate == nil || !promisedDate.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected promised date 2026-03-01, got '%v'", promisedDate)
+		}
+	})
+}
+
+func TestPromisedDate(t *testing.T) {
+	t.Run("is nil when the code has no earliest completion date", func(t *testing.T) {
+		promisedDate, err := BulkCodeDTO{Code: "ABC12345"}.PromisedDate()
+
+		if err != nil || promisedDate != nil {
+			t.Errorf("expected no promised date, got '%v' with error '%v'", promisedDate, err)
+		}
+	})
+
+	t.Run("returns error for an unreadable date", func(t *testing.T) {
+		_, err := BulkCodeDTO{Code: "ABC12345", EarliestCompletionDate: "next spring"}.PromisedDate()
+
+		if err == nil {
+			t.Error("expected error for invalid date, got nil")
+		}
+	})
+}
+
+func TestGetNextStatus(t *testing.T) {
+	t.Run("resolves steps that share a task type by step key", func(t *testing.T) {
+		status, err := GetNextStatus("glaze_coat")
+
+		if err != nil || status != "glaze_coat" {
+			t.Errorf("expected status 'glaze_coat', got '%s' with error '%v'", status, err)
+		}
+	})
+
+	t.Run("completes the order detail once it's fired", func(t *testing.T) {
+		status, err := GetNextStatus("fire")
+
+		if err != nil || status != "completed" {
+			t.Errorf("expected status 'completed', got '%s' with error '%v'", status, err)
+		}
+	})
+
+	t.Run("returns error for an unknown step key", func(t *testing.T) {
+		_, err := GetNextStatus("polish")
+
+		if err == nil {
+			t.Error("expected error for unknown step key, got nil")
+		}
+	})
+}
+
+func TestTaskStepKey(t *testing.T) {
+	t.Run("uses the step recorded on the task", func(t *testing.T) {
+		stepKey, err := TaskStepKey("glaze_coat", "task_glaze")
+
+		if err != nil || stepKey != "glaze_coat" {
+			t.Errorf("expected step 'glaze_coat', got '%s' with error '%v'", stepKey, err)
+		}
+	})
+
+	t.Run("falls back to the task type without a step key", func(t *testing.T) {
+		stepKey, err := TaskStepKey("", "task_fire")
+
+		if err != nil || stepKey != "fire" {
+			t.Errorf("expected step 'fire', got '%s' with error '%v'", stepKey, err)
+		}
+	})
+}
+
+func TestAdvanceStatus(t *testing.T) {
+	t.Run("moves on to a later step", func(t *testing.T) {
+		if status := AdvanceStatus("build", "trim"); status != "trim" {
+			t.Errorf("expected status 'trim', got '%s'", status)
+		}
+	})
+
+	t.Run("stays put when a branch finishes after a later one", func(t *testing.T) {
+		if status := AdvanceStatus("trim", "handles"); status != "trim" {
+			t.Errorf("expected status 'trim', got '%s'", status)
+		}
+	})
+}
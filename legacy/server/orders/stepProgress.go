@@ -0,0 +1,226 @@
+package orders
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+func stepProgressFromRows(rows map[string]stepProgressRow) map[string]StepProgressDTO {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	progress := make(map[string]StepProgressDTO, len(rows))
+	for key, row := range rows {
+		progress[key] = StepProgressDTO{
+			CompletedQuantity: row.CompletedQuantity,
+			CompletedAt:       row.CompletedAt,
+		}
+	}
+
+	return progress
+}
+
+func stepProgressToRows(progress map[string]StepProgressDTO) map[string]stepProgressRow {
+	rows := make(map[string]stepProgressRow, len(progress))
+	for key, step := range progress {
+		rows[key] = stepProgressRow{
+			CompletedQuantity: step.CompletedQuantity,
+			CompletedAt:       step.CompletedAt,
+		}
+	}
+
+	return rows
+}
+
+// EncodeStepProgress and DecodeStepProgress convert the json stored in the
+// order_details step_progress and defect_reports remake_step_progress columns
+func EncodeStepProgress(progress map[string]StepProgressDTO) ([]byte, error) {
+	return json.Marshal(stepProgressToRows(progress))
+}
+
+func DecodeStepProgress(data []byte) (map[string]StepProgressDTO, error) {
+	progress := map[string]StepProgressDTO{}
+	if len(data) == 0 {
+		return progress, nil
+	}
+
+	var rows map[string]stepProgressRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	for key, row := range rows {
+		progress[key] = StepProgressDTO{
+			CompletedQuantity: row.CompletedQuantity,
+			CompletedAt:       row.CompletedAt,
+		}
+	}
+
+	return progress, nil
+}
+
+// StepProgressKey identifies a step in an order detail's step progress. Each
+// component goes through its own steps, so its name is part of the key.
+func StepProgressKey(component string, stepKey string) string {
+	if component == "" {
+		return stepKey
+	}
+
+	return component + "/" + stepKey
+}
+
+// ParseStepProgressKey splits a step progress key into its component and step
+func ParseStepProgressKey(key string) (string, string) {
+	component, stepKey, found := strings.Cut(key, "/")
+	if !found {
+		return "", key
+	}
+
+	return component, stepKey
+}
+
+// StepBatch is a batch of pieces going through their process together, either
+// an order detail or one of its remakes
+type StepBatch struct {
+	Status          string
+	StatusChangedAt *time.Time
+	Progress        map[string]StepProgressDTO
+	Components      []OrderDetailComponentDTO
+	GoodQuantity    int
+}
+
+// NewStepBatch starts step progress for batches from before steps were
+// tracked from their status. Its completed quantity counted towards the
+// status step while the status hadn't changed, and towards the step being
+// worked on after that.
+func NewStepBatch(status string, statusChangedAt *time.Time, completedQuantity int, progress map[string]StepProgressDTO, components []OrderDetailComponentDTO, goodQuantity int, currentStep string) *StepBatch {
+	batch := &StepBatch{
+		Status:          status,
+		StatusChangedAt: statusChangedAt,
+		Progress:        progress,
+		Components:      components,
+		GoodQuantity:    goodQuantity,
+	}
+
+	if batch.Progress == nil {
+		batch.Progress = map[string]StepProgressDTO{}
+	}
+
+	if len(batch.Progress) > 0 || status == "completed" {
+		return batch
+	}
+
+	if status != "pending" {
+		if statusChangedAt != nil {
+			batch.Progress[status] = StepProgressDTO{CompletedAt: statusChangedAt}
+		} else {
+			currentStep = status
+		}
+	}
+
+	if currentStep != "" && completedQuantity > 0 {
+		batch.Progress[currentStep] = StepProgressDTO{CompletedQuantity: completedQuantity}
+	}
+
+	return batch
+}
+
+// Complete counts pieces through a step and finishes the steps that every
+// piece left in the batch has been through
+func (b *StepBatch) Complete(component string, stepKey string, quantity int, completedAt time.Time) error {
+	key := StepProgressKey(component, stepKey)
+
+	step := b.Progress[key]
+	step.CompletedQuantity += quantity
+	b.Progress[key] = step
+
+	return b.FinishSteps(completedAt)
+}
+
+// Lose takes pieces reported lost out of a step they were counted through,
+// unless the step was already finished
+func (b *StepBatch) Lose(component string, stepKey string, quantity int) {
+	key := StepProgressKey(component, stepKey)
+
+	step, found := b.Progress[key]
+	if !found || step.CompletedAt != nil {
+		return
+	}
+
+	step.CompletedQuantity = max(step.CompletedQuantity-quantity, 0)
+	b.Progress[key] = step
+}
+
+// FinishSteps marks the steps that every piece left in the batch has been
+// through as done and moves the status on past them. The batch is only
+// completed once every one of its components has been fired.
+func (b *StepBatch) FinishSteps(finishedAt time.Time) error {
+	keys := make([]string, 0, len(b.Progress))
+	for key := range b.Progress {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		step := b.Progress[key]
+		component, stepKey := ParseStepProgressKey(key)
+
+		if step.CompletedAt != nil || step.CompletedQuantity < b.GoodQuantity*b.perUnit(component) {
+			continue
+		}
+
+		step.CompletedAt = &finishedAt
+		b.Progress[key] = step
+
+		nextStatus, err := GetNextStatus(stepKey)
+		if err != nil {
+			return err
+		}
+
+		if nextStatus == "completed" && !b.fired() {
+			nextStatus = "fire"
+		}
+
+		b.advance(nextStatus, finishedAt)
+	}
+
+	return nil
+}
+
+// CompleteAll finishes a batch that has no pieces left in it
+func (b *StepBatch) CompleteAll(completedAt time.Time) {
+	b.advance("completed", completedAt)
+}
+
+func (b *StepBatch) advance(nextStatus string, changedAt time.Time) {
+	// A status step in progress hasn't changed the status until it's done
+	status := AdvanceStatus(b.Status, nextStatus)
+	if status != b.Status || b.StatusChangedAt == nil {
+		b.Status = status
+		b.StatusChangedAt = &changedAt
+	}
+}
+
+func (b *StepBatch) perUnit(component string) int {
+	for _, candidate := range b.Components {
+		if candidate.Name == component && candidate.QuantityPerUnit > 0 {
+			return candidate.QuantityPerUnit
+		}
+	}
+
+	return 1
+}
+
+func (b *StepBatch) fired() bool {
+	for _, component := range b.Components {
+		fire, found := b.Progress[StepProgressKey(component.Name, "fire")]
+		if !found || fire.CompletedAt == nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,97 @@
+package orders
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepBatch(t *testing.T) {
+	completedAt := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	t.Run("completes branches out of order", func(t *testing.T) {
+		batch := NewStepBatch("build", &completedAt, 0, nil, nil, 10, "trim")
+
+		if err := batch.Complete("", "handles", 4, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if err := batch.Complete("", "trim", 10, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Status != "trim" {
+			t.Errorf("expected status 'trim', got '%s'", batch.Status)
+		}
+
+		handles := batch.Progress["handles"]
+		if handles.CompletedAt != nil || handles.CompletedQuantity != 4 {
+			t.Errorf("expected handles to have 4 pieces done and not be finished, got %+v", handles)
+		}
+
+		if err := batch.Complete("", "handles", 6, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Status != "trim" || batch.Progress["handles"].CompletedAt == nil {
+			t.Errorf("expected handles to finish with status 'trim', got '%s' and %+v", batch.Status, batch.Progress["handles"])
+		}
+	})
+
+	t.Run("carries progress over from the status", func(t *testing.T) {
+		batch := NewStepBatch("trim", nil, 3, map[string]StepProgressDTO{}, nil, 10, "trim")
+
+		if err := batch.Complete("", "trim", 7, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Progress["trim"].CompletedAt == nil || !batch.StatusChangedAt.Equal(completedAt) {
+			t.Errorf("expected trim to finish, got %+v", batch.Progress["trim"])
+		}
+	})
+
+	t.Run("waits for every component to be fired", func(t *testing.T) {
+		components := []OrderDetailComponentDTO{{Name: "cup", QuantityPerUnit: 4}, {Name: "saucer", QuantityPerUnit: 1}}
+		batch := NewStepBatch("glaze", &completedAt, 0, nil, components, 2, "fire")
+
+		if err := batch.Complete("cup", "fire", 4, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Progress["cup/fire"].CompletedAt != nil {
+			t.Errorf("expected half the cups to leave the cup firing unfinished, got %+v", batch.Progress["cup/fire"])
+		}
+
+		if err := batch.Complete("cup", "fire", 4, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Status != "fire" {
+			t.Errorf("expected status 'fire' while the saucers aren't fired, got '%s'", batch.Status)
+		}
+
+		if err := batch.Complete("saucer", "fire", 2, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Status != "completed" {
+			t.Errorf("expected status 'completed', got '%s'", batch.Status)
+		}
+	})
+
+	t.Run("finishes a step once the pieces it's waiting on are lost", func(t *testing.T) {
+		batch := NewStepBatch("build", &completedAt, 0, nil, nil, 10, "trim")
+
+		if err := batch.Complete("", "trim", 8, completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		batch.GoodQuantity -= 2
+		if err := batch.FinishSteps(completedAt); err != nil {
+			t.Fatalf("expected no error, got '%s'", err.Error())
+		}
+
+		if batch.Status != "trim" {
+			t.Errorf("expected status 'trim', got '%s'", batch.Status)
+		}
+	})
+}
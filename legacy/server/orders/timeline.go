@@ -0,0 +1,302 @@
+package orders
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TimelineFlexibility string
+
+const (
+	// TimelineFlexibilityFirm is a date the customer needs the pieces by
+	TimelineFlexibilityFirm TimelineFlexibility = "firm"
+	// TimelineFlexibilityApproximate is a rough date or a lead time
+	TimelineFlexibilityApproximate TimelineFlexibility = "approximate"
+	// TimelineFlexibilityFlexible is a customer happy to wait
+	TimelineFlexibilityFlexible TimelineFlexibility = "flexible"
+	// TimelineFlexibilityUnknown is a timeline the parser couldn't read
+	TimelineFlexibilityUnknown TimelineFlexibility = "unknown"
+)
+
+// TimelineProposal is what the customer's timeline suggests for the order.
+// It stays a proposal until an admin confirms it as the due date.
+type TimelineProposal struct {
+	DueDate     *time.Time
+	Flexibility TimelineFlexibility
+}
+
+var flexiblePhrases = []string{
+	"no rush", "no hurry", "not in a rush", "not in a hurry", "no deadline",
+	"no timeline", "no time frame", "no timeframe", "whenever", "any time",
+	"anytime", "flexible", "take your time",
+}
+
+var firmWords = regexp.MustCompile(`\b(by|before|no later than|within|deadline|need|needs|needed|must)\b`)
+var hedgeWords = regexp.MustCompile(`\b(around|about|roughly|approximately|sometime|ish|or so|ideally|hopefully)\b`)
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+const monthPattern = `(january|february|march|april|may|june|july|august|september|october|november|december|jan|feb|mar|apr|jun|jul|aug|sept|sep|oct|nov|dec)\b\.?`
+
+var isoDatePattern = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+var numericDatePattern = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?\b`)
+var monthDayPattern = regexp.MustCompile(`\b` + monthPattern + `\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s*(\d{4}))?`)
+var dayMonthPattern = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + monthPattern + `(?:,?\s*(\d{4}))?`)
+var partOfMonthPattern = regexp.MustCompile(`\b(early|mid|middle of|end of|late)[\s-]+` + monthPattern)
+var monthOnlyPattern = regexp.MustCompile(`\b(in|by|before|during|until|for)\s+` + monthPattern)
+var durationPattern = regexp.MustCompile(`\b(?:a\s+)?(\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|couple|few)(?:\s*(?:-|to|or)\s*(\d+|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve))?(?:\s+of)?\s+(day|week|month)s?\b`)
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"couple": 2, "few": 3,
+}
+
+type holiday struct {
+	names []string
+	date  func(year int, location *time.Location) time.Time
+}
+
+// Longer names come first so "christmas eve" isn't read as christmas
+var holidays = []holiday{
+	{[]string{"mother's day", "mothers day", "mother day"}, func(year int, loc *time.Location) time.Time { return nthWeekday(year, time.May, time.Sunday, 2, loc) }},
+	{[]string{"father's day", "fathers day", "father day"}, func(year int, loc *time.Location) time.Time { return nthWeekday(year, time.June, time.Sunday, 3, loc) }},
+	{[]string{"valentine's day", "valentines day", "valentine's", "valentines"}, fixedDate(time.February, 14)},
+	{[]string{"christmas eve", "xmas eve"}, fixedDate(time.December, 24)},
+	{[]string{"christmas", "xmas", "the holidays"}, fixedDate(time.December, 25)},
+	{[]string{"new year's eve", "new years eve"}, fixedDate(time.December, 31)},
+	{[]string{"new year's day", "new years day", "new year's", "new years", "new year"}, fixedDate(time.January, 1)},
+	{[]string{"thanksgiving"}, func(year int, loc *time.Location) time.Time {
+		return nthWeekday(year, time.November, time.Thursday, 4, loc)
+	}},
+	{[]string{"halloween"}, fixedDate(time.October, 31)},
+	{[]string{"easter"}, easter},
+}
+
+// ParseTimeline reads the timeline a customer wrote on their order, such as
+// "by Dec 15", "before Mother's Day", "in 6 weeks" or "no rush", and proposes
+// a due date. Dates without a year are taken to be the next one to come.
+func ParseTimeline(timeline string, now time.Time) TimelineProposal {
+	text := strings.ToLower(strings.TrimSpace(timeline))
+	text = strings.NewReplacer("’", "'", "‘", "'").Replace(text)
+
+	flexible := false
+	for _, phrase := range flexiblePhrases {
+		flexible = flexible || strings.Contains(text, phrase)
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if date, found := parseTimelineDate(text, today); found {
+		// Pieces wanted before a date have to be ready the day before it
+		if strings.Contains(text, "before") {
+			date = date.AddDate(0, 0, -1)
+		}
+
+		return TimelineProposal{DueDate: &date, Flexibility: dateFlexibility(text, flexible, TimelineFlexibilityFirm)}
+	}
+
+	if date, found := parseTimelineDuration(text, today); found {
+		return TimelineProposal{DueDate: &date, Flexibility: dateFlexibility(text, flexible, TimelineFlexibilityApproximate)}
+	}
+
+	if flexible {
+		return TimelineProposal{Flexibility: TimelineFlexibilityFlexible}
+	}
+
+	return TimelineProposal{Flexibility: TimelineFlexibilityUnknown}
+}
+
+func dateFlexibility(text string, flexible bool, fallback TimelineFlexibility) TimelineFlexibility {
+	if flexible || hedgeWords.MatchString(text) {
+		return TimelineFlexibilityApproximate
+	}
+
+	if firmWords.MatchString(text) {
+		return TimelineFlexibilityFirm
+	}
+
+	return fallback
+}
+
+func parseTimelineDate(text string, today time.Time) (time.Time, bool) {
+	location := today.Location()
+
+	if match := isoDatePattern.FindStringSubmatch(text); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		day, _ := strconv.Atoi(match[3])
+
+		return validDate(year, time.Month(month), day, location)
+	}
+
+	for _, holiday := range holidays {
+		for _, name := range holiday.names {
+			if strings.Contains(text, name) {
+				date := holiday.date(today.Year(), location)
+				if date.Before(today) {
+					date = holiday.date(today.Year()+1, location)
+				}
+
+				return date, true
+			}
+		}
+	}
+
+	if match := monthDayPattern.FindStringSubmatch(text); match != nil {
+		day, _ := strconv.Atoi(match[2])
+		return upcomingDate(monthNamed(match[1]), day, match[3], today)
+	}
+
+	if match := dayMonthPattern.FindStringSubmatch(text); match != nil {
+		day, _ := strconv.Atoi(match[1])
+		return upcomingDate(monthNamed(match[2]), day, match[3], today)
+	}
+
+	if match := numericDatePattern.FindStringSubmatch(text); match != nil {
+		month, _ := strconv.Atoi(match[1])
+		day, _ := strconv.Atoi(match[2])
+		if month < 1 || month > 12 {
+			return time.Time{}, false
+		}
+
+		return upcomingDate(time.Month(month), day, match[3], today)
+	}
+
+	if match := partOfMonthPattern.FindStringSubmatch(text); match != nil {
+		month := monthNamed(match[2])
+
+		switch match[1] {
+		case "early":
+			return upcomingDate(month, 7, "", today)
+		case "mid", "middle of":
+			return upcomingDate(month, 15, "", today)
+		default:
+			return upcomingDate(month, lastDayOf(month, today), "", today)
+		}
+	}
+
+	// A month on its own is its first day when the pieces are needed by then,
+	// and its last day when they're wanted during it
+	if match := monthOnlyPattern.FindStringSubmatch(text); match != nil {
+		month := monthNamed(match[2])
+
+		switch match[1] {
+		case "by", "before", "until":
+			return upcomingDate(month, 1, "", today)
+		default:
+			return upcomingDate(month, lastDayOf(month, today), "", today)
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func parseTimelineDuration(text string, today time.Time) (time.Time, bool) {
+	match := durationPattern.FindStringSubmatch(text)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	// A range like "4-6 weeks" is quoted against its far end
+	amount, ok := timelineNumber(match[1])
+	if match[2] != "" {
+		amount, ok = timelineNumber(match[2])
+	}
+
+	if !ok || amount <= 0 {
+		return time.Time{}, false
+	}
+
+	switch match[3] {
+	case "day":
+		return today.AddDate(0, 0, amount), true
+	case "week":
+		return today.AddDate(0, 0, amount*7), true
+	default:
+		return today.AddDate(0, amount, 0), true
+	}
+}
+
+func monthNamed(name string) time.Month {
+	return months[name[:3]]
+}
+
+func lastDayOf(month time.Month, today time.Time) int {
+	return time.Date(today.Year(), month+1, 0, 0, 0, 0, 0, today.Location()).Day()
+}
+
+func timelineNumber(value string) (int, bool) {
+	if number, found := numberWords[value]; found {
+		return number, true
+	}
+
+	number, err := strconv.Atoi(value)
+	return number, err == nil
+}
+
+func upcomingDate(month time.Month, day int, year string, today time.Time) (time.Time, bool) {
+	if year != "" {
+		parsedYear, _ := strconv.Atoi(year)
+		if parsedYear < 100 {
+			parsedYear += 2000
+		}
+
+		return validDate(parsedYear, month, day, today.Location())
+	}
+
+	date, valid := validDate(today.Year(), month, day, today.Location())
+	if valid && date.Before(today) {
+		return validDate(today.Year()+1, month, day, today.Location())
+	}
+
+	return date, valid
+}
+
+func validDate(year int, month time.Month, day int, location *time.Location) (time.Time, bool) {
+	date := time.Date(year, month, day, 0, 0, 0, 0, location)
+	if date.Month() != month || date.Day() != day {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+func fixedDate(month time.Month, day int) func(int, *time.Location) time.Time {
+	return func(year int, location *time.Location) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, location)
+	}
+}
+
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int, location *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, location)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+
+	return first.AddDate(0, 0, offset+(n-1)*7)
+}
+
+// easter uses the anonymous Gregorian algorithm
+func easter(year int, location *time.Location) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+}
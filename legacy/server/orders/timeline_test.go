@@ -0,0 +1,89 @@
+package orders
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeline(t *testing.T) {
+	// A Saturday in October
+	now := time.Date(2025, 10, 18, 15, 30, 0, 0, time.UTC)
+
+	date := func(year int, month time.Month, day int) *time.Time {
+		value := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &value
+	}
+
+	tests := []struct {
+		timeline    string
+		dueDate     *time.Time
+		flexibility TimelineFlexibility
+	}{
+		{"by Dec 15", date(2025, 12, 15), TimelineFlexibilityFirm},
+		{"By December 15th, 2025", date(2025, 12, 15), TimelineFlexibilityFirm},
+		{"need them by 12/15", date(2025, 12, 15), TimelineFlexibilityFirm},
+		{"2026-02-01", date(2026, 2, 1), TimelineFlexibilityFirm},
+		{"the 3rd of March", date(2026, 3, 3), TimelineFlexibilityFirm},
+		{"around Jan 10", date(2026, 1, 10), TimelineFlexibilityApproximate},
+		{"before Mother's Day", date(2026, 5, 9), TimelineFlexibilityFirm},
+		{"before Mother’s Day please", date(2026, 5, 9), TimelineFlexibilityFirm},
+		{"for Christmas", date(2025, 12, 25), TimelineFlexibilityFirm},
+		{"before Thanksgiving", date(2025, 11, 26), TimelineFlexibilityFirm},
+		{"Easter", date(2026, 4, 5), TimelineFlexibilityFirm},
+		{"mid-November", date(2025, 11, 15), TimelineFlexibilityFirm},
+		{"end of January", date(2026, 1, 31), TimelineFlexibilityFirm},
+		{"by February", date(2026, 2, 1), TimelineFlexibilityFirm},
+		{"sometime in March", date(2026, 3, 31), TimelineFlexibilityApproximate},
+		{"in 6 weeks", date(2025, 11, 29), TimelineFlexibilityApproximate},
+		{"within two weeks", date(2025, 11, 1), TimelineFlexibilityFirm},
+		{"4-6 weeks", date(2025, 11, 29), TimelineFlexibilityApproximate},
+		{"a couple of months", date(2025, 12, 18), TimelineFlexibilityApproximate},
+		{"no rush", nil, TimelineFlexibilityFlexible},
+		{"Whenever is fine!", nil, TimelineFlexibilityFlexible},
+		{"no rush, ideally by Dec 15", date(2025, 12, 15), TimelineFlexibilityApproximate},
+		{"ASAP", nil, TimelineFlexibilityUnknown},
+		{"", nil, TimelineFlexibilityUnknown},
+		{"by 2/30", nil, TimelineFlexibilityUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timeline, func(t *testing.T) {
+			proposal := ParseTimeline(tt.timeline, now)
+
+			if proposal.Flexibility != tt.flexibility {
+				t.Errorf("expected flexibility '%s', got '%s'", tt.flexibility, proposal.Flexibility)
+			}
+
+			if tt.dueDate == nil {
+				if proposal.DueDate != nil {
+					t.Errorf("expected no due date, got '%s'", proposal.DueDate.Format("2006-01-02"))
+				}
+				return
+			}
+
+			if proposal.DueDate == nil {
+				t.Fatalf("expected due date '%s', got none", tt.dueDate.Format("2006-01-02"))
+			}
+
+			if !proposal.DueDate.Equal(*tt.dueDate) {
+				t.Errorf("expected due date '%s', got '%s'", tt.dueDate.Format("2006-01-02"), proposal.DueDate.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestParseTimeline_PastDatesRollToNextYear(t *testing.T) {
+	now := time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC)
+
+	proposal := ParseTimeline("by Dec 15", now)
+
+	if proposal.DueDate == nil || !proposal.DueDate.Equal(time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected due date 2026-12-15, got '%v'", proposal.DueDate)
+	}
+
+	proposal = ParseTimeline("for Valentine's Day", now)
+
+	if proposal.DueDate == nil || !proposal.DueDate.Equal(time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected due date 2026-02-14, got '%v'", proposal.DueDate)
+	}
+}
@@ -0,0 +1,257 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Calendar is the studio's working pattern. Work days need the maker, so they
+// skip closed days, while drying carries on whether the studio is open or not.
+// Availability sets the hours the planner has on each day the studio is open.
+type Calendar struct {
+	workingDays [7]bool
+	holidays    map[string]string
+	closures    []Closure
+}
+
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+// Closure covers Start through End inclusive
+type Closure struct {
+	Start  time.Time
+	End    time.Time
+	Reason string
+}
+
+type calendarFile struct {
+	WorkingDays []string       `json:"working_days" yaml:"working_days"`
+	Holidays    []holidayEntry `json:"holidays,omitempty" yaml:"holidays,omitempty"`
+	Closures    []closureEntry `json:"closures,omitempty" yaml:"closures,omitempty"`
+}
+
+type holidayEntry struct {
+	Date string `json:"date" yaml:"date"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+type closureEntry struct {
+	Start  string `json:"start" yaml:"start"`
+	End    string `json:"end" yaml:"end"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+var DefaultWorkingDays = []time.Weekday{
+	time.Monday,
+	time.Tuesday,
+	time.Wednesday,
+	time.Thursday,
+	time.Friday,
+	time.Saturday,
+}
+
+var DefaultCalendar = mustCalendar(NewCalendar(DefaultWorkingDays, nil, nil))
+
+var activeCalendar atomic.Pointer[Calendar]
+
+func init() {
+	activeCalendar.Store(DefaultCalendar)
+}
+
+func CurrentCalendar() *Calendar {
+	return activeCalendar.Load()
+}
+
+func SetCalendar(calendar *Calendar) {
+	if calendar == nil {
+		calendar = DefaultCalendar
+	}
+
+	activeCalendar.Store(calendar)
+}
+
+func NewCalendar(workingDays []time.Weekday, holidays []Holiday, closures []Closure) (*Calendar, error) {
+	calendar := &Calendar{holidays: make(map[string]string)}
+
+	for _, weekday := range workingDays {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			return nil, fmt.Errorf("[Calendar:Validate] invalid working day %d", weekday)
+		}
+		calendar.workingDays[weekday] = true
+	}
+
+	if len(workingDays) == 0 {
+		return nil, fmt.Errorf("[Calendar:Validate] at least one working day is required")
+	}
+
+	for _, holiday := range holidays {
+		calendar.holidays[holiday.Date.Format("2006-01-02")] = holiday.Name
+	}
+
+	for _, closure := range closures {
+		if closure.End.Before(closure.Start) {
+			return nil, fmt.Errorf("[Calendar:Validate] closure %q ends on %s, before it starts on %s", closure.Reason, closure.End.Format("2006-01-02"), closure.Start.Format("2006-01-02"))
+		}
+		calendar.closures = append(calendar.closures, closure)
+	}
+
+	return calendar, nil
+}
+
+func mustCalendar(calendar *Calendar, err error) *Calendar {
+	if err != nil {
+		panic(err)
+	}
+
+	return calendar
+}
+
+func ParseCalendar(data []byte, format string) (*Calendar, error) {
+	var file calendarFile
+
+	if err := decodeProcessData(data, format, &file); err != nil {
+		return nil, err
+	}
+
+	workingDays := []time.Weekday{}
+	for _, name := range file.WorkingDays {
+		weekday, ok := parseWeekday(name)
+		if !ok {
+			return nil, fmt.Errorf("[Calendar:Parse] unknown working day %q", name)
+		}
+		workingDays = append(workingDays, weekday)
+	}
+
+	holidays := []Holiday{}
+	for _, entry := range file.Holidays {
+		date, err := time.Parse("2006-01-02", entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("[Calendar:Parse] invalid holiday date %q: %w", entry.Date, err)
+		}
+		holidays = append(holidays, Holiday{Date: date, Name: entry.Name})
+	}
+
+	closures := []Closure{}
+	for _, entry := range file.Closures {
+		start, err := time.Parse("2006-01-02", entry.Start)
+		if err != nil {
+			return nil, fmt.Errorf("[Calendar:Parse] invalid closure start %q: %w", entry.Start, err)
+		}
+
+		end, err := time.Parse("2006-01-02", entry.End)
+		if err != nil {
+			return nil, fmt.Errorf("[Calendar:Parse] invalid closure end %q: %w", entry.End, err)
+		}
+
+		closures = append(closures, Closure{Start: start, End: end, Reason: entry.Reason})
+	}
+
+	return NewCalendar(workingDays, holidays, closures)
+}
+
+func LoadCalendar(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[Calendar:Load] failed to read %s: %w", path, err)
+	}
+
+	calendar, err := ParseCalendar(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("[Calendar:Load] %s: %w", path, err)
+	}
+
+	return calendar, nil
+}
+
+// LoadConfiguredCalendar reads STUDIO_CALENDAR_FILE and falls back to the
+// default working week
+func LoadConfiguredCalendar() (*Calendar, error) {
+	if path := os.Getenv("STUDIO_CALENDAR_FILE"); path != "" {
+		return LoadCalendar(path)
+	}
+
+	return DefaultCalendar, nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(name, weekday.String()) || strings.EqualFold(name, weekday.String()[:3]) {
+			return weekday, true
+		}
+	}
+
+	return 0, false
+}
+
+func (c *Calendar) IsWorkDay(day time.Time) bool {
+	if !c.workingDays[day.Weekday()] {
+		return false
+	}
+
+	date := day.Format("2006-01-02")
+	if _, holiday := c.holidays[date]; holiday {
+		return false
+	}
+
+	for _, closure := range c.closures {
+		if date >= closure.Start.Format("2006-01-02") && date <= closure.End.Format("2006-01-02") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AddWorkDays returns the day after the workDays-th work day on or after from
+func (c *Calendar) AddWorkDays(from time.Time, workDays int) time.Time {
+	day := from
+	for workDays > 0 {
+		if c.IsWorkDay(day) {
+			workDays--
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return day
+}
+
+// SubtractWorkDays returns the latest start that still fits workDays of work
+// before to
+func (c *Calendar) SubtractWorkDays(to time.Time, workDays int) time.Time {
+	day := to
+	for workDays > 0 {
+		day = day.AddDate(0, 0, -1)
+		if c.IsWorkDay(day) {
+			workDays--
+		}
+	}
+
+	return day
+}
+
+// stepFinish is when a step started on start is done, drying included
+func (c *Calendar) stepFinish(start time.Time, step ProductionStep, quantity int) time.Time {
+	return c.AddWorkDays(start, stepWorkDays(step, quantity)).AddDate(0, 0, step.DryingDays)
+}
+
+// stepStart is the latest start for a step that has to be done by finish
+func (c *Calendar) stepStart(finish time.Time, step ProductionStep, quantity int) time.Time {
+	return c.SubtractWorkDays(finish.AddDate(0, 0, -step.DryingDays), stepWorkDays(step, quantity))
+}
+
+// stepWorkDays is how many days of the maker's time a step takes. Firings run
+// on their own, so they only take their drying days.
+func stepWorkDays(step ProductionStep, quantity int) int {
+	if isExternalProcess(step.TaskType) || step.Rate <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(float64(quantity) / step.Rate))
+}
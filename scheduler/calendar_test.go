@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// everyDayCalendar keeps date arithmetic on plain calendar days, for tests
+// that aren't about the working week
+var everyDayCalendar = mustCalendar(NewCalendar([]time.Weekday{
+	time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday,
+}, nil, nil))
+
+const calendarYAML = `
+working_days: [monday, tuesday, wednesday, thursday, friday]
+holidays:
+  - {date: "2025-12-25", name: Christmas}
+closures:
+  - {start: "2025-12-29", end: "2026-01-02", reason: winter break}
+`
+
+func TestParseCalendar(t *testing.T) {
+	calendar, err := ParseCalendar([]byte(calendarYAML), "yaml")
+	require.NoError(t, err)
+
+	assert.True(t, calendar.IsWorkDay(time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, calendar.IsWorkDay(time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC)), "Holidays are closed")
+	assert.False(t, calendar.IsWorkDay(time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC)), "Saturdays aren't working days")
+	assert.False(t, calendar.IsWorkDay(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)), "Closures include their last day")
+	assert.True(t, calendar.IsWorkDay(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)))
+
+	_, err = ParseCalendar([]byte(`working_days: [someday]`), "yaml")
+	assert.ErrorContains(t, err, "unknown working day")
+
+	_, err = ParseCalendar([]byte(`working_days: []`), "yaml")
+	assert.ErrorContains(t, err, "at least one working day")
+
+	_, err = ParseCalendar([]byte("working_days: [mon]\nclosures:\n  - {start: \"2026-01-05\", end: \"2026-01-01\"}"), "yaml")
+	assert.ErrorContains(t, err, "before it starts")
+}
+
+func TestCalendar_WorkDays(t *testing.T) {
+	calendar, err := ParseCalendar([]byte(calendarYAML), "yaml")
+	require.NoError(t, err)
+
+	wednesday := time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, wednesday, calendar.AddWorkDays(wednesday, 0))
+	assert.Equal(t, time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC), calendar.AddWorkDays(wednesday, 1))
+	assert.Equal(t, time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC), calendar.AddWorkDays(wednesday, 3), "Work stops over the holiday, the weekend and the closure")
+	assert.Equal(t, time.Date(2025, 12, 26, 0, 0, 0, 0, time.UTC), calendar.SubtractWorkDays(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), 1))
+
+	build := ProductionStep{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2}
+	finish := calendar.stepFinish(time.Date(2025, 12, 26, 0, 0, 0, 0, time.UTC), build, 5)
+	assert.Equal(t, time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC), finish, "Pieces dry over the weekend")
+	assert.Equal(t, time.Date(2025, 12, 26, 0, 0, 0, 0, time.UTC), calendar.stepStart(finish, build, 5))
+
+	fire := ProductionStep{StepKey: StepKeyFire, TaskType: TaskTypeFire, DryingDays: 5}
+	assert.Equal(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), calendar.stepFinish(time.Date(2025, 12, 26, 0, 0, 0, 0, time.UTC), fire, 20), "Firings run through closures")
+}
+
+func TestCalculateCompletionDate_SkipsClosures(t *testing.T) {
+	closed, err := NewCalendar(DefaultWorkingDays, nil, []Closure{{
+		Start:  time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+		Reason: "residency",
+	}})
+	require.NoError(t, err)
+
+	detail := orders.OrderDetailDTO{ID: "detail", Type: string(PieceTypeMugWithHandle), Quantity: 40, Status: string(StepKeyPending)}
+	fromDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	open, err := calculateCompletionDate(DefaultProcessCatalog, DefaultCalendar, detail, fromDate)
+	require.NoError(t, err)
+
+	away, err := calculateCompletionDate(DefaultProcessCatalog, closed, detail, fromDate)
+	require.NoError(t, err)
+
+	assert.True(t, away.After(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)), "Building can't finish during the closure")
+	assert.True(t, away.After(open))
+}
+
+func TestScheduler_RunSkipsClosedDays(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	closed, err := NewCalendar(DefaultWorkingDays, nil, []Closure{{
+		Start:  time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+		Reason: "kiln repair",
+	}})
+	require.NoError(t, err)
+
+	// Availability is still set on the closed days, as it is when the studio
+	// forgets to clear its hours before a closure
+	availableHours := map[string]float64{}
+	for day := monday; day.Before(monday.AddDate(0, 0, 28)); day = day.AddDate(0, 0, 1) {
+		availableHours[day.Format("2006-01-02")] = 6
+	}
+
+	store := NewInMemoryTaskStore()
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithoutHandle, 30, monday.AddDate(0, 0, 21))),
+		NewInMemoryAvailabilitySource(availableHours),
+		store,
+	).WithClock(NewFixedClock(monday)).WithCalendar(closed)
+
+	_, err = scheduler.Run(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+	require.NotEmpty(t, store.Tasks())
+
+	for _, task := range store.Tasks() {
+		assert.True(t, closed.IsWorkDay(task.Date), "%s task planned on closed day %s", task.TaskType, task.Date.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,281 @@
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+type CompletedTask struct {
+	OrderDetailId  string
+	TaskType       TaskType
+	PieceType      PieceType
+	Size           *string
+	Quantity       int
+	EstimatedHours float64
+	ActualHours    float64
+	CompletedAt    time.Time
+}
+
+type CalibrationOptions struct {
+	LookbackDays int
+	MinSamples   int
+
+	// OutlierThreshold is the modified z-score above which a task's observed
+	// rate is ignored
+	OutlierThreshold float64
+
+	// MaxChange caps how far one calibration can move a rate, as a fraction
+	// of the current rate
+	MaxChange float64
+
+	Apply bool
+}
+
+var DefaultCalibration = CalibrationOptions{
+	LookbackDays:     90,
+	MinSamples:       5,
+	OutlierThreshold: 3.5,
+	MaxChange:        0.25,
+	Apply:            false,
+}
+
+type calibrationKey struct {
+	pieceType PieceType
+	taskType  TaskType
+}
+
+type calibrationSample struct {
+	task         CompletedTask
+	observedRate float64
+}
+
+func Calibrate(options CalibrationOptions) (CalibrationReport, error) {
+	if options.Apply && (os.Getenv("PRODUCTION_PROCESS_FILE") != "" || os.Getenv("PRODUCTION_PROCESS_SOURCE") != "supabase") {
+		return CalibrationReport{}, fmt.Errorf("[Calibrate] calibrated rates can only be applied when the production process is loaded from supabase")
+	}
+
+	history, err := LoadConfiguredProcessHistory()
+	if err != nil {
+		return CalibrationReport{}, fmt.Errorf("[Calibrate] failed to load production process: %w", err)
+	}
+
+	report, err := CalibrateRates(history, &supabaseTaskStore{}, options, time.Now())
+	if err != nil {
+		return CalibrationReport{}, err
+	}
+
+	if !options.Apply || report.Catalog == nil {
+		return report, nil
+	}
+
+	if err := InsertProcessCatalog(report.Catalog); err != nil {
+		return CalibrationReport{}, fmt.Errorf("[Calibrate] failed to save catalog version %s: %w", report.ProposedVersion, err)
+	}
+
+	report.Applied = true
+
+	return report, nil
+}
+
+// CalibrateRates compares completed tasks against the catalog in force and
+// proposes a new catalog version, effective today, when rates have drifted
+func CalibrateRates(history *ProcessHistory, source CompletedTaskSource, options CalibrationOptions, now time.Time) (CalibrationReport, error) {
+	catalog, err := history.At(now)
+	if err != nil {
+		return CalibrationReport{}, err
+	}
+
+	since := now.AddDate(0, 0, -max(1, options.LookbackDays))
+
+	tasks, err := source.GetCompletedTasks(since)
+	if err != nil {
+		return CalibrationReport{}, fmt.Errorf("[Calibrate] failed to fetch completed tasks: %w", err)
+	}
+
+	report := CalibrationReport{
+		GeneratedAt:    now,
+		Since:          since,
+		CatalogVersion: catalog.Version(),
+		Rates:          []RateCalibration{},
+	}
+
+	samples := make(map[calibrationKey][]calibrationSample)
+
+	for _, task := range tasks {
+		if task.Quantity <= 0 || task.ActualHours <= 0 {
+			continue
+		}
+
+		step, exists := catalog.stepForTask(task.TaskType, task.PieceType)
+		if !exists || isExternalProcess(step.TaskType) {
+			continue
+		}
+
+		// Setup isn't part of the rate, so it comes out of the hours worked
+		workedHours := task.ActualHours - catalog.setupFor(task.TaskType).TaskHours
+		if workedHours <= 0 {
+			continue
+		}
+
+		// Observed rates are brought back to the catalog's base size
+		observedRate := float64(task.Quantity) / (workedHours / ShiftDurationHours)
+		if task.Size != nil {
+			if multiplier, sized := catalog.sizeMultiplier(*task.Size); sized {
+				observedRate = observedRate / multiplier.Rate
+			}
+		}
+
+		key := calibrationKey{pieceType: task.PieceType, taskType: task.TaskType}
+		samples[key] = append(samples[key], calibrationSample{task: task, observedRate: observedRate})
+	}
+
+	proposed := make(map[calibrationKey]float64)
+
+	for _, key := range sortedCalibrationKeys(samples) {
+		step, _ := catalog.stepForTask(key.taskType, key.pieceType)
+		calibration := calibrateRate(key, step.Rate, samples[key], options)
+
+		if calibration.Changed {
+			proposed[key] = calibration.ProposedRate
+		}
+
+		report.Rates = append(report.Rates, calibration)
+	}
+
+	if len(proposed) == 0 {
+		return report, nil
+	}
+
+	report.ProposedVersion = "calibrated-" + now.Format("2006-01-02")
+	if _, exists := history.Version(report.ProposedVersion); exists {
+		return CalibrationReport{}, fmt.Errorf("[Calibrate] catalog version %s already exists", report.ProposedVersion)
+	}
+
+	processes := make(map[PieceType][]ProductionStep)
+	for _, pieceType := range catalog.PieceTypes() {
+		steps := catalog.Steps(pieceType)
+
+		for i, step := range steps {
+			if rate, exists := proposed[calibrationKey{pieceType: pieceType, taskType: step.TaskType}]; exists {
+				steps[i].Rate = rate
+			}
+		}
+
+		processes[pieceType] = steps
+	}
+
+	// The observed rates left setup out, so the setup times carry over with them
+	calibrated, err := NewProcessCatalogWithSetup(processes, catalog.sizes, catalog.setup)
+	if err != nil {
+		return CalibrationReport{}, fmt.Errorf("[Calibrate] calibrated catalog is invalid: %w", err)
+	}
+
+	effectiveFrom := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	report.Catalog = calibrated.withVersion(report.ProposedVersion, effectiveFrom)
+
+	return report, nil
+}
+
+func calibrateRate(key calibrationKey, currentRate float64, samples []calibrationSample, options CalibrationOptions) RateCalibration {
+	calibration := RateCalibration{
+		PieceType:    key.pieceType,
+		TaskType:     key.taskType,
+		CurrentRate:  currentRate,
+		ProposedRate: currentRate,
+	}
+
+	rates := make([]float64, 0, len(samples))
+	for _, sample := range samples {
+		rates = append(rates, sample.observedRate)
+	}
+
+	center := median(rates)
+	spread := medianAbsoluteDeviation(rates, center)
+
+	kept := []float64{}
+	for _, sample := range samples {
+		// 0.6745 scales the MAD to a standard deviation for normal data
+		if spread > 0 && math.Abs(0.6745*(sample.observedRate-center)/spread) > options.OutlierThreshold {
+			calibration.Outliers++
+			continue
+		}
+
+		kept = append(kept, sample.observedRate)
+		calibration.EstimatedHours += sample.task.EstimatedHours
+		calibration.ActualHours += sample.task.ActualHours
+	}
+
+	calibration.Samples = len(kept)
+	calibration.ObservedRate = roundRate(median(kept))
+
+	if calibration.EstimatedHours > 0 {
+		calibration.DriftPercent = math.Round((calibration.ActualHours/calibration.EstimatedHours-1)*1000) / 10
+	}
+
+	if calibration.Samples < options.MinSamples {
+		calibration.Note = fmt.Sprintf("needs %d samples to calibrate", options.MinSamples)
+		return calibration
+	}
+
+	proposedRate := calibration.ObservedRate
+	if options.MaxChange > 0 {
+		proposedRate = math.Max(currentRate*(1-options.MaxChange), math.Min(currentRate*(1+options.MaxChange), proposedRate))
+
+		if proposedRate != calibration.ObservedRate {
+			calibration.Note = fmt.Sprintf("limited to a %.0f%% change", options.MaxChange*100)
+		}
+	}
+
+	calibration.ProposedRate = roundRate(proposedRate)
+	calibration.Changed = calibration.ProposedRate != currentRate
+
+	return calibration
+}
+
+func sortedCalibrationKeys(samples map[calibrationKey][]calibrationSample) []calibrationKey {
+	keys := make([]calibrationKey, 0, len(samples))
+	for key := range samples {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pieceType != keys[j].pieceType {
+			return keys[i].pieceType < keys[j].pieceType
+		}
+		return keys[i].taskType < keys[j].taskType
+	})
+
+	return keys
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+func medianAbsoluteDeviation(values []float64, center float64) float64 {
+	deviations := make([]float64, 0, len(values))
+	for _, value := range values {
+		deviations = append(deviations, math.Abs(value-center))
+	}
+
+	return median(deviations)
+}
+
+func roundRate(rate float64) float64 {
+	return math.Round(rate*10) / 10
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completedBuilds returns tasks that each built 10 pieces in the given hours
+func completedBuilds(completedAt time.Time, hours ...float64) []CompletedTask {
+	tasks := []CompletedTask{}
+	for _, actual := range hours {
+		tasks = append(tasks, CompletedTask{
+			TaskType:       TaskTypeBuildBase,
+			PieceType:      PieceTypeMugWithoutHandle,
+			Quantity:       10,
+			EstimatedHours: 4,
+			ActualHours:    actual,
+			CompletedAt:    completedAt,
+		})
+	}
+	return tasks
+}
+
+func TestCalibrateRates_ProposesObservedRate(t *testing.T) {
+	history, err := ParseProcessHistory([]byte(historyYAML), "yaml")
+	require.NoError(t, err)
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewInMemoryTaskStore()
+	store.AddCompletedTasks(completedBuilds(now.AddDate(0, 0, -3), 4.4, 4.6, 4.4, 4.6, 4.5, 40)...)
+	store.AddCompletedTasks(completedBuilds(now.AddDate(0, -6, 0), 1, 1, 1, 1, 1)...)
+
+	report, err := CalibrateRates(history, store, DefaultCalibration, now)
+	require.NoError(t, err)
+	assert.Equal(t, "rates-2026", report.CatalogVersion)
+	require.Len(t, report.Rates, 1)
+
+	calibration := report.Rates[0]
+	assert.Equal(t, 5, calibration.Samples, "Tasks before the lookback window are ignored")
+	assert.Equal(t, 1, calibration.Outliers, "The 40 hour task is rejected")
+	assert.InDelta(t, 12.5, calibration.DriftPercent, 0.01)
+	assert.InDelta(t, 8.9, calibration.ObservedRate, 0.001)
+	assert.InDelta(t, 8.9, calibration.ProposedRate, 0.001)
+	assert.True(t, calibration.Changed)
+
+	require.NotNil(t, report.Catalog)
+	assert.Equal(t, "calibrated-2026-06-01", report.Catalog.Version())
+	assert.Equal(t, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), report.Catalog.EffectiveFrom())
+	assert.InDelta(t, 8.9, report.Catalog.Steps(PieceTypeMugWithoutHandle)[0].Rate, 0.001)
+	assert.False(t, report.Applied)
+}
+
+func TestCalibrateRates_Limits(t *testing.T) {
+	history, err := ParseProcessHistory([]byte(historyYAML), "yaml")
+	require.NoError(t, err)
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	store := NewInMemoryTaskStore()
+	store.AddCompletedTasks(completedBuilds(now, 4, 4, 4)...)
+
+	report, err := CalibrateRates(history, store, DefaultCalibration, now)
+	require.NoError(t, err)
+	assert.False(t, report.Rates[0].Changed)
+	assert.Contains(t, report.Rates[0].Note, "needs 5 samples")
+	assert.Nil(t, report.Catalog)
+
+	store.AddCompletedTasks(completedBuilds(now, 2, 2, 2, 2, 2)...)
+
+	report, err = CalibrateRates(history, store, DefaultCalibration, now)
+	require.NoError(t, err)
+	assert.InDelta(t, 12.5, report.Rates[0].ProposedRate, 0.001, "A rate moves at most 25% per calibration")
+	assert.Contains(t, report.Rates[0].Note, "limited to a 25% change")
+}
+
+func TestCalibrateRates_NormalizesSizes(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	large := "12oz"
+
+	tasks := []CompletedTask{}
+	for range 5 {
+		tasks = append(tasks, CompletedTask{TaskType: TaskTypeBuildBase, PieceType: PieceTypeTumbler, Size: &large, Quantity: 4, EstimatedHours: 4, ActualHours: 4, CompletedAt: now})
+	}
+
+	store := NewInMemoryTaskStore()
+	store.AddCompletedTasks(tasks...)
+
+	report, err := CalibrateRates(DefaultProcessCatalog.history(), store, DefaultCalibration, now)
+	require.NoError(t, err)
+	require.Len(t, report.Rates, 1)
+	assert.InDelta(t, 5.0, report.Rates[0].ObservedRate, 0.001, "Four 12oz tumblers a shift is the base rate of five")
+	assert.False(t, report.Rates[0].Changed)
+}
+
+func TestCalibrateRates_KeepsSetupTimes(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	catalog, err := NewProcessCatalogWithSetup(map[PieceType][]ProductionStep{
+		PieceTypeMugWithoutHandle: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 10, DryingDays: 2},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, DryingDays: 5},
+		},
+	}, SizeMultipliers, map[TaskType]SetupTime{TaskTypeBuildBase: {TaskHours: 0.5}})
+	require.NoError(t, err)
+
+	store := NewInMemoryTaskStore()
+	store.AddCompletedTasks(completedBuilds(now, 5, 5, 5, 5, 5)...)
+
+	report, err := CalibrateRates(catalog.history(), store, DefaultCalibration, now)
+	require.NoError(t, err)
+	require.Len(t, report.Rates, 1)
+	assert.InDelta(t, 8.9, report.Rates[0].ObservedRate, 0.001, "Half an hour of each task was setup")
+
+	require.NotNil(t, report.Catalog)
+	assert.InDelta(t, 8.9, report.Catalog.Steps(PieceTypeMugWithoutHandle)[0].Rate, 0.001)
+	assert.Equal(t, SetupTime{TaskHours: 0.5}, report.Catalog.setupFor(TaskTypeBuildBase), "Calibrated rates leave setup out, so it has to stay in the catalog")
+}
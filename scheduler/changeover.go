@@ -0,0 +1,47 @@
+package scheduler
+
+type ChangeoverOptions struct {
+	CostHours      float64
+	MaxModesPerDay int
+}
+
+var DefaultChangeover = ChangeoverOptions{
+	CostHours:      0.5,
+	MaxModesPerDay: 2,
+}
+
+func (d *DaySchedule) hasMode(step StepKey) bool {
+	for _, mode := range d.Modes {
+		if mode == step {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *DaySchedule) addMode(step StepKey, changeoverHours float64) {
+	if d.hasMode(step) {
+		return
+	}
+
+	if d.Mode == "" {
+		d.Mode = step
+	}
+
+	d.Modes = append(d.Modes, step)
+	d.ChangeoverHours += changeoverHours
+	d.AvailableHours -= changeoverHours
+}
+
+func (p *planner) changeoverCost(daySchedule *DaySchedule, step StepKey) (float64, bool) {
+	if len(daySchedule.Modes) == 0 || daySchedule.hasMode(step) {
+		return 0, true
+	}
+
+	if len(daySchedule.Modes) >= max(1, p.changeover.MaxModesPerDay) {
+		return 0, false
+	}
+
+	return p.changeover.CostHours, true
+}
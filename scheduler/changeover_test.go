@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func planMixedDay(t *testing.T, changeover ChangeoverOptions, hours float64, tasks ...TaskChainItem) *DaySchedule {
+	t.Helper()
+
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, changeover)
+
+	for i := range tasks {
+		tasks[i].StartDate = monday
+		tasks[i].HasDeadline = true
+		p.trackChain(tasks[i : i+1])
+	}
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: hours}
+	_, scheduled := p.planDay(monday, daySchedule, tasks)
+	require.True(t, scheduled)
+
+	return daySchedule
+}
+
+func mixedDayTasks(glazeQuantity int) []TaskChainItem {
+	return []TaskChainItem{
+		{OrderDetailId: "build-detail", OrderDetailStatus: StepKeyBuild, TaskType: TaskTypeBuildBase, PieceType: PieceTypeMugWithoutHandle, Quantity: 5},
+		{OrderDetailId: "glaze-detail", OrderDetailStatus: StepKeyGlaze, TaskType: TaskTypeGlaze, PieceType: PieceTypeMugWithoutHandle, Quantity: glazeQuantity},
+	}
+}
+
+func TestPlanDay_MixesStepsWithChangeover(t *testing.T) {
+	daySchedule := planMixedDay(t, DefaultChangeover, 8, mixedDayTasks(10)...)
+
+	require.Len(t, daySchedule.Tasks, 2, "Building and glazing should share the day")
+	assert.Equal(t, StepKeyBuild, daySchedule.Mode, "The first step opened stays the primary mode")
+	assert.Equal(t, []StepKey{StepKeyBuild, StepKeyGlaze}, daySchedule.Modes)
+	assert.InDelta(t, 0.5, daySchedule.ChangeoverHours, 0.001)
+
+	glazeHours := CalculateHours(TaskTypeGlaze, PieceTypeMugWithoutHandle, 10)
+	assert.InDelta(t, 8-4-0.5-glazeHours, daySchedule.AvailableHours, 0.001, "The changeover is deducted from the day")
+}
+
+func TestPlanDay_MaxModesPerDay(t *testing.T) {
+	daySchedule := planMixedDay(t, ChangeoverOptions{CostHours: 0.5, MaxModesPerDay: 1}, 8, mixedDayTasks(10)...)
+
+	require.Len(t, daySchedule.Tasks, 1)
+	assert.Equal(t, TaskTypeBuildBase, daySchedule.Tasks[0].TaskType)
+	assert.Equal(t, []StepKey{StepKeyBuild}, daySchedule.Modes)
+	assert.InDelta(t, 0.0, daySchedule.ChangeoverHours, 0.001)
+}
+
+func TestPlanDay_SkipsChangeoverThatIsNotWorthIt(t *testing.T) {
+	daySchedule := planMixedDay(t, DefaultChangeover, 8, mixedDayTasks(1)...)
+
+	require.Len(t, daySchedule.Tasks, 1, "A single glaze doesn't justify half an hour of setup")
+	assert.Equal(t, []StepKey{StepKeyBuild}, daySchedule.Modes)
+	assert.InDelta(t, 4.0, daySchedule.AvailableHours, 0.001)
+}
+
+func TestPlanDay_ChangeoverMustFitInTheDay(t *testing.T) {
+	daySchedule := planMixedDay(t, DefaultChangeover, 4.4, mixedDayTasks(10)...)
+
+	require.Len(t, daySchedule.Tasks, 1)
+	assert.InDelta(t, 0.4, daySchedule.AvailableHours, 0.001)
+}
@@ -0,0 +1,27 @@
+package scheduler
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+var SystemClock Clock = systemClock{}
+
+func NewFixedClock(now time.Time) Clock {
+	return fixedClock{now: now}
+}
@@ -3,13 +3,29 @@ package scheduler
 import (
 	"aliciapceramics/legacy/server/orders"
 	"fmt"
-	"math"
 	"time"
 )
 
 const StandardProductionWeeks = 3
 
 func CalculateCompletionDate(orderDetail orders.OrderDetailDTO, fromDate time.Time) (time.Time, error) {
+	return calculateCompletionDate(CurrentProcessCatalog(), CurrentCalendar(), orderDetail, fromDate)
+}
+
+// QuoteCompletionDate records which catalog version the date was computed
+// against, so the quote can be explained later
+func QuoteCompletionDate(orderDetail orders.OrderDetailDTO, fromDate time.Time) (CompletionQuote, error) {
+	catalog := CurrentProcessCatalog()
+
+	date, err := calculateCompletionDate(catalog, CurrentCalendar(), orderDetail, fromDate)
+	if err != nil {
+		return CompletionQuote{}, err
+	}
+
+	return CompletionQuote{Date: date, CatalogVersion: catalog.Version()}, nil
+}
+
+func calculateCompletionDate(catalog *ProcessCatalog, calendar *Calendar, orderDetail orders.OrderDetailDTO, fromDate time.Time) (time.Time, error) {
 	safePieceType, isValidPieceType := IsValidPieceType(orderDetail.Type)
 	safeStep, isValidStep := IsValidStepKey(orderDetail.Status)
 
@@ -21,44 +37,22 @@ func CalculateCompletionDate(orderDetail orders.OrderDetailDTO, fromDate time.Ti
 		return time.Time{}, fmt.Errorf("order detail %s has invalid status %s", orderDetail.ID, orderDetail.Status)
 	}
 
-	process := ProductionProcess[safePieceType]
-
-	var currentStepIndex int
-	for idx, step := range process {
-		if step.StepKey == safeStep {
-			currentStepIndex = idx
-			break
-		}
+	processes, err := resolveDetailProcesses(catalog, safePieceType, orderDetail)
+	if err != nil {
+		return time.Time{}, err
 	}
 
-	totalDaysNeeded := 0
-
-	for i := currentStepIndex; i < len(process); i++ {
-		step := process[i]
+	completionDate := fromDate.AddDate(0, 0, StandardProductionWeeks*7)
 
-		var workDays int
-		if step.Rate > 0 {
-			workDays = int(math.Ceil(float64(orderDetail.Quantity) / step.Rate))
-		} else {
-			workDays = 0
-		}
+	// Components are made side by side, so the slowest one sets the date
+	for _, process := range processes {
+		currentStepIndex, _ := statusStepIndex(process.planned, safeStep)
+		finish := processFinish(calendar, fromDate, process.planned[currentStepIndex:], orderDetail.Quantity*process.perUnit)
 
-		var daysNeeded int
-		if step.TaskType == TaskTypeBisque || step.TaskType == TaskTypeFire {
-			daysNeeded = step.DryingDays
-		} else {
-			daysNeeded = workDays + step.DryingDays
+		if finish.After(completionDate) {
+			completionDate = finish
 		}
-
-		totalDaysNeeded += daysNeeded
 	}
 
-	minimumDays := StandardProductionWeeks * 7
-	if totalDaysNeeded < minimumDays {
-		totalDaysNeeded = minimumDays
-	}
-
-	completionDate := fromDate.AddDate(0, 0, totalDaysNeeded)
-
 	return completionDate, nil
 }
@@ -1,48 +1,7 @@
 package scheduler
 
-import (
-	"time"
-)
-
 const ShiftDurationHours = 4.0
 
-var WeeklySchedule = map[time.Weekday]float64{
-	time.Monday:    4.0,
-	time.Tuesday:   2.0,
-	time.Wednesday: 2.0,
-	time.Thursday:  4.0,
-	time.Friday:    8.0,
-	time.Saturday:  8.0,
-	time.Sunday:    0.0,
-}
-
-var ProductionRatesPerShift = map[string]map[string]int{
-	"build": {
-		"base": 5,
-		"bowl": 3,
-	},
-	"attach": {
-		"lid":    10,
-		"handle": 9,
-	},
-	"trim": {
-		"piece": 18,
-	},
-	"glaze": {
-		"piece": 17,
-	},
-}
-
-var DryingPeriods = map[string]int{
-	"post_build":  2,
-	"post_attach": 4,
-}
-
-var ExternalPeriods = map[string]int{
-	"bisque": 3,
-	"fire":   3,
-}
-
 type PieceType string
 type TaskType string
 type StepKey string
@@ -55,6 +14,9 @@ const (
 	PieceTypeTrinketDish      PieceType = "trinket-dish"
 	PieceTypeDinnerware       PieceType = "dinnerware"
 	PieceTypeOther            PieceType = "other"
+
+	PieceTypeDinnerwarePlate PieceType = "dinnerware/plate"
+	PieceTypeDinnerwareBowl  PieceType = "dinnerware/bowl"
 )
 
 const (
@@ -63,6 +25,8 @@ const (
 	TaskTypeTrim         TaskType = "task_trim"
 	TaskTypeAttachHandle TaskType = "task_attach_handle"
 	TaskTypeAttachLid    TaskType = "task_attach_lid"
+	TaskTypePullHandles  TaskType = "task_pull_handles"
+	TaskTypeWax          TaskType = "task_wax"
 	TaskTypeBisque       TaskType = "task_bisque"
 	TaskTypeGlaze        TaskType = "task_glaze"
 	TaskTypeFire         TaskType = "task_fire"
@@ -71,61 +35,131 @@ const (
 const (
 	StepKeyPending   StepKey = "pending"
 	StepKeyBuild     StepKey = "build"
+	StepKeyHandles   StepKey = "handles"
 	StepKeyTrim      StepKey = "trim"
 	StepKeyAttach    StepKey = "attach"
 	StepKeyTrimFinal StepKey = "trim_final"
 	StepKeyBisque    StepKey = "bisque"
+	StepKeyWax       StepKey = "wax"
 	StepKeyGlaze     StepKey = "glaze"
+	StepKeyGlazeCoat StepKey = "glaze_coat"
 	StepKeyFire      StepKey = "fire"
 )
 
+// ProductionStep runs after the step listed before it, unless some step in
+// the process names its predecessors with After. Those processes are graphs:
+// steps with no After can start right away, and a step with several waits
+// for all of them. Optional steps are only made when an order detail asks.
+// LossRate is the share of pieces cracked or warped during the step.
 type ProductionStep struct {
-	StepKey    StepKey
-	TaskType   TaskType
-	Rate       float64
-	DryingDays int
+	StepKey    StepKey   `json:"step_key" yaml:"step_key"`
+	TaskType   TaskType  `json:"task_type" yaml:"task_type"`
+	Rate       float64   `json:"rate" yaml:"rate"`
+	DryingDays int       `json:"drying_days" yaml:"drying_days"`
+	After      []StepKey `json:"after,omitempty" yaml:"after,omitempty"`
+	Optional   bool      `json:"optional,omitempty" yaml:"optional,omitempty"`
+	LossRate   float64   `json:"loss_rate,omitempty" yaml:"loss_rate,omitempty"`
+}
+
+type SizeMultiplier struct {
+	Rate       float64 `json:"rate" yaml:"rate"`
+	DryingDays float64 `json:"drying_days" yaml:"drying_days"`
+}
+
+// SizeMultipliers scale the hand steps of DefaultProductionProcess, whose rates and
+// drying days are for 10oz pieces
+var SizeMultipliers = map[string]SizeMultiplier{
+	"8":  {Rate: 1.2, DryingDays: 0.8},
+	"10": {Rate: 1.0, DryingDays: 1.0},
+	"12": {Rate: 0.8, DryingDays: 1.25},
 }
 
-var ProductionProcess = map[PieceType][]ProductionStep{
-	PieceTypeMugWithHandle: {
-		{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
-		{StepKey: StepKeyTrim, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 1},
-		{StepKey: StepKeyAttach, TaskType: TaskTypeAttachHandle, Rate: 8, DryingDays: 2},
-		{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 3},
-		{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
-		{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
-		{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
-	},
-	PieceTypeMugWithoutHandle: {
-		{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
-		{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 3},
-		{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
-		{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
-		{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
-	},
-	PieceTypeTumbler: {
-		{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
-		{StepKey: StepKeyTrim, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 1},
-		{StepKey: StepKeyAttach, TaskType: TaskTypeAttachLid, Rate: 10, DryingDays: 2},
-		{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 3},
-		{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
-		{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
-		{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
-	},
-	PieceTypeMatchaBowl: {
-		{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 3, DryingDays: 3},
-		{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 8, DryingDays: 3},
-		{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
-		{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
-		{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
-	},
-	PieceTypeTrinketDish: {
-		{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 30, DryingDays: 2},
-		{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 120, DryingDays: 3},
-		{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
-		{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 50, DryingDays: 0},
-		{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 0},
-	},
+// SetupTime is the fixed time a task type takes on top of its rate. Task
+// hours are spent on every task, wedging clay or prepping the batch, and day
+// hours once on each day the task type is worked, setting up the wheel and
+// cleaning it down. Glazing's day hours are spent on each glaze mixed.
+type SetupTime struct {
+	TaskHours float64 `json:"task_hours,omitempty" yaml:"task_hours,omitempty"`
+	DayHours  float64 `json:"day_hours,omitempty" yaml:"day_hours,omitempty"`
+}
+
+// SetupTimes is empty because the default rates were measured over whole
+// shifts and already carry setup. A catalog that sets setup times should have
+// rates for the making alone.
+var SetupTimes = map[TaskType]SetupTime{}
+
+// DefaultProductionProcess returns a fresh copy of the compiled-in process, so
+// callers building their own catalog from it can't change the defaults
+func DefaultProductionProcess() map[PieceType][]ProductionStep {
+	return map[PieceType][]ProductionStep{
+		PieceTypeMugWithHandle: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
+			{StepKey: StepKeyTrim, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 1},
+			{StepKey: StepKeyAttach, TaskType: TaskTypeAttachHandle, Rate: 8, DryingDays: 2},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeMugWithoutHandle: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeTumbler: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
+			{StepKey: StepKeyTrim, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 1},
+			{StepKey: StepKeyAttach, TaskType: TaskTypeAttachLid, Rate: 10, DryingDays: 2},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 15, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeMatchaBowl: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 3, DryingDays: 3},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 8, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeTrinketDish: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 30, DryingDays: 2},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 120, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 50, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 0},
+		},
+		PieceTypeDinnerware: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 4, DryingDays: 3},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 10, DryingDays: 4},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 12, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeDinnerwarePlate: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 4, DryingDays: 3},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 10, DryingDays: 4},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 12, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeDinnerwareBowl: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBowl, Rate: 3, DryingDays: 3},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 8, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 14, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+		PieceTypeOther: {
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 3, DryingDays: 3},
+			{StepKey: StepKeyTrimFinal, TaskType: TaskTypeTrim, Rate: 8, DryingDays: 3},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, Rate: 0, DryingDays: 5},
+			{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 12, DryingDays: 0},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, Rate: 0, DryingDays: 5},
+		},
+	}
 }
 
 func IsValidPieceType(pieceType string) (PieceType, bool) {
@@ -150,11 +184,14 @@ func IsValidStepKey(stepKey string) (StepKey, bool) {
 	case
 		StepKeyPending,
 		StepKeyBuild,
+		StepKeyHandles,
 		StepKeyTrim,
 		StepKeyAttach,
 		StepKeyTrimFinal,
 		StepKeyBisque,
+		StepKeyWax,
 		StepKeyGlaze,
+		StepKeyGlazeCoat,
 		StepKeyFire:
 		return StepKey(stepKey), true
 	default:
@@ -1,13 +1,14 @@
 package scheduler
 
 import (
+	"aliciapceramics/legacy/server/availability"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestProductionProcess_MugWithHandle(t *testing.T) {
-	process := ProductionProcess[PieceTypeMugWithHandle]
+	process := DefaultProductionProcess()[PieceTypeMugWithHandle]
 
 	assert.Len(t, process, 7, "Mug with handle should have 7 steps")
 
@@ -40,7 +41,7 @@ func TestProductionProcess_MugWithHandle(t *testing.T) {
 }
 
 func TestProductionProcess_MugWithoutHandle(t *testing.T) {
-	process := ProductionProcess[PieceTypeMugWithoutHandle]
+	process := DefaultProductionProcess()[PieceTypeMugWithoutHandle]
 
 	assert.Len(t, process, 5, "Mug without handle should have 5 steps")
 
@@ -60,7 +61,7 @@ func TestProductionProcess_MugWithoutHandle(t *testing.T) {
 }
 
 func TestProductionProcess_Tumbler(t *testing.T) {
-	process := ProductionProcess[PieceTypeTumbler]
+	process := DefaultProductionProcess()[PieceTypeTumbler]
 
 	assert.Len(t, process, 7, "Tumbler should have 7 steps")
 
@@ -81,7 +82,7 @@ func TestProductionProcess_Tumbler(t *testing.T) {
 }
 
 func TestProductionProcess_MatchaBowl(t *testing.T) {
-	process := ProductionProcess[PieceTypeMatchaBowl]
+	process := DefaultProductionProcess()[PieceTypeMatchaBowl]
 
 	assert.Len(t, process, 5, "Matcha bowl should have 5 steps")
 
@@ -97,7 +98,7 @@ func TestProductionProcess_MatchaBowl(t *testing.T) {
 }
 
 func TestProductionProcess_TrinketDish(t *testing.T) {
-	process := ProductionProcess[PieceTypeTrinketDish]
+	process := DefaultProductionProcess()[PieceTypeTrinketDish]
 
 	assert.Len(t, process, 5, "Trinket dish should have 5 steps")
 
@@ -114,7 +115,7 @@ func TestProductionProcess_TrinketDish(t *testing.T) {
 func TestProductionProcess_BUG1_DuplicateStepKeys(t *testing.T) {
 	t.Log("BUG #1: Process contains duplicate StepKeys, making it impossible to resume from certain steps")
 
-	process := ProductionProcess[PieceTypeMugWithHandle]
+	process := DefaultProductionProcess()[PieceTypeMugWithHandle]
 
 	stepKeyCount := make(map[StepKey]int)
 	for _, step := range process {
@@ -131,7 +132,7 @@ func TestProductionProcess_BUG1_DuplicateStepKeys(t *testing.T) {
 }
 
 func TestProductionProcess_AllPositiveRates(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		for _, step := range process {
 			if step.TaskType == TaskTypeBisque || step.TaskType == TaskTypeFire {
 				assert.Equal(t, 0.0, step.Rate, "External processes should have 0 rate for %s", pieceType)
@@ -143,7 +144,7 @@ func TestProductionProcess_AllPositiveRates(t *testing.T) {
 }
 
 func TestProductionProcess_AllPositiveDryingDays(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		for _, step := range process {
 			assert.GreaterOrEqual(t, step.DryingDays, 0, "DryingDays should be non-negative for %s step %s", pieceType, step.TaskType)
 		}
@@ -231,13 +232,13 @@ func TestIsValidStepKey_Invalid(t *testing.T) {
 }
 
 func TestWeeklySchedule_ValidDays(t *testing.T) {
-	assert.Equal(t, 4.0, WeeklySchedule[1], "Monday should have 4 hours")
-	assert.Equal(t, 2.0, WeeklySchedule[2], "Tuesday should have 2 hours")
-	assert.Equal(t, 2.0, WeeklySchedule[3], "Wednesday should have 2 hours")
-	assert.Equal(t, 4.0, WeeklySchedule[4], "Thursday should have 4 hours")
-	assert.Equal(t, 8.0, WeeklySchedule[5], "Friday should have 8 hours")
-	assert.Equal(t, 8.0, WeeklySchedule[6], "Saturday should have 8 hours")
-	assert.Equal(t, 0.0, WeeklySchedule[0], "Sunday should have 0 hours")
+	assert.Equal(t, 4.0, availability.DefaultWeeklySchedule[1], "Monday should have 4 hours")
+	assert.Equal(t, 2.0, availability.DefaultWeeklySchedule[2], "Tuesday should have 2 hours")
+	assert.Equal(t, 2.0, availability.DefaultWeeklySchedule[3], "Wednesday should have 2 hours")
+	assert.Equal(t, 4.0, availability.DefaultWeeklySchedule[4], "Thursday should have 4 hours")
+	assert.Equal(t, 8.0, availability.DefaultWeeklySchedule[5], "Friday should have 8 hours")
+	assert.Equal(t, 8.0, availability.DefaultWeeklySchedule[6], "Saturday should have 8 hours")
+	assert.Equal(t, 0.0, availability.DefaultWeeklySchedule[0], "Sunday should have 0 hours")
 }
 
 func TestShiftDurationHours(t *testing.T) {
@@ -245,13 +246,13 @@ func TestShiftDurationHours(t *testing.T) {
 }
 
 func TestProductionProcess_NoEmptyProcesses(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		assert.NotEmpty(t, process, "Process for %s should not be empty", pieceType)
 	}
 }
 
 func TestProductionProcess_AllEndWithFire(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		lastStep := process[len(process)-1]
 		assert.Equal(t, TaskTypeFire, lastStep.TaskType, "Last step for %s should be fire", pieceType)
 		assert.Equal(t, StepKeyFire, lastStep.StepKey, "Last step key for %s should be fire", pieceType)
@@ -259,7 +260,7 @@ func TestProductionProcess_AllEndWithFire(t *testing.T) {
 }
 
 func TestProductionProcess_AllStartWithBuild(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		firstStep := process[0]
 		assert.Equal(t, StepKeyBuild, firstStep.StepKey, "First step key for %s should be build", pieceType)
 		assert.Greater(t, firstStep.Rate, 0.0, "Build step for %s should have positive rate", pieceType)
@@ -267,7 +268,7 @@ func TestProductionProcess_AllStartWithBuild(t *testing.T) {
 }
 
 func TestProductionProcess_BisqueBeforeFire(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		bisqueIndex := -1
 		fireIndex := -1
 
@@ -287,7 +288,7 @@ func TestProductionProcess_BisqueBeforeFire(t *testing.T) {
 }
 
 func TestProductionProcess_GlazeBeforeFire(t *testing.T) {
-	for pieceType, process := range ProductionProcess {
+	for pieceType, process := range DefaultProductionProcess() {
 		glazeIndex := -1
 		fireIndex := -1
 
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"time"
 )
 
 func GetDeadlineOrders() ([]OrderDB, error) {
@@ -198,3 +199,460 @@ func InsertTasks(tasks []TaskToCreate) error {
 
 	return nil
 }
+
+func DeleteLatenessReports() error {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/lateness_reports?order_detail_id=not.is.null", supabaseUrl)
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete lateness reports request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete lateness reports: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete lateness reports with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func InsertLatenessReport(entries []LatenessEntry) error {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/lateness_reports", supabaseUrl)
+
+	body, err := json.Marshal(entries)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse lateness report into json: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create insert lateness report request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert lateness report: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to insert lateness report with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func InsertSnapshot(snapshot Snapshot) error {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/schedule_snapshots", supabaseUrl)
+
+	body, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse snapshot into json: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create insert snapshot request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert snapshot: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to insert snapshot with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// GetSnapshot returns the first snapshot stored on the as-of date, which is
+// the studio as it stood when that day's schedule was run
+func GetSnapshot(asOf time.Time) (Snapshot, bool, error) {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return Snapshot{}, false, fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/schedule_snapshots?select=*&as_of=gte.%s&as_of=lt.%s&order=as_of.asc&limit=1", supabaseUrl, asOf.Format("2006-01-02"), asOf.AddDate(0, 0, 1).Format("2006-01-02"))
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Snapshot{}, false, fmt.Errorf("failed to fetch snapshot with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	var snapshots []Snapshot
+
+	err = json.Unmarshal(body, &snapshots)
+
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("failed to parse snapshot response: %w", err)
+	}
+
+	if len(snapshots) == 0 {
+		return Snapshot{}, false, nil
+	}
+
+	return snapshots[0], true, nil
+}
+
+type productionStepRow struct {
+	Version       string    `json:"version"`
+	EffectiveFrom *string   `json:"effective_from"`
+	PieceType     PieceType `json:"piece_type"`
+	Position      int       `json:"position"`
+	StepKey       StepKey   `json:"step_key"`
+	TaskType      TaskType  `json:"task_type"`
+	Rate          float64   `json:"rate"`
+	DryingDays    int       `json:"drying_days"`
+	After         []StepKey `json:"after"`
+	Optional      bool      `json:"optional"`
+	LossRate      float64   `json:"loss_rate"`
+
+	// Sizes and Setup belong to the whole version, so every row of a version
+	// carries them. Rows saved before they were stored have neither, and those
+	// versions use the defaults.
+	Sizes map[string]SizeMultiplier `json:"sizes"`
+	Setup map[TaskType]SetupTime    `json:"setup"`
+}
+
+func LoadProcessHistoryFromSupabase() (*ProcessHistory, error) {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return nil, fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/production_steps?select=*&order=version.asc,piece_type.asc,position.asc", supabaseUrl)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query production steps: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch production steps with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	var rows []productionStepRow
+
+	err = json.Unmarshal(body, &rows)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse production steps response: %w, body: %s", err, string(body))
+	}
+
+	return processHistoryFromRows(rows)
+}
+
+func processHistoryFromRows(rows []productionStepRow) (*ProcessHistory, error) {
+	files := make(map[string]*processFile)
+	versions := []string{}
+
+	for _, row := range rows {
+		file, exists := files[row.Version]
+		if !exists {
+			file = &processFile{Version: row.Version, Processes: make(map[PieceType][]ProductionStep)}
+			if row.EffectiveFrom != nil {
+				file.EffectiveFrom = *row.EffectiveFrom
+			}
+			file.Sizes = row.Sizes
+			file.Setup = row.Setup
+
+			files[row.Version] = file
+			versions = append(versions, row.Version)
+		}
+
+		file.Processes[row.PieceType] = append(file.Processes[row.PieceType], ProductionStep{
+			StepKey:    row.StepKey,
+			TaskType:   row.TaskType,
+			Rate:       row.Rate,
+			DryingDays: row.DryingDays,
+			After:      row.After,
+			Optional:   row.Optional,
+			LossRate:   row.LossRate,
+		})
+	}
+
+	catalogs := []*ProcessCatalog{}
+
+	for _, version := range versions {
+		catalog, err := files[version].catalog()
+		if err != nil {
+			return nil, fmt.Errorf("invalid production steps for version %q: %w", version, err)
+		}
+
+		catalogs = append(catalogs, catalog)
+	}
+
+	return NewProcessHistory(catalogs...)
+}
+
+type completedTaskRow struct {
+	OrderDetailId  string     `json:"order_detail_id"`
+	TaskType       TaskType   `json:"task_type"`
+	Quantity       int        `json:"quantity"`
+	EstimatedHours float64    `json:"estimated_hours"`
+	ActualHours    *float64   `json:"actual_hours"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	OrderDetail    *struct {
+		Type string  `json:"type"`
+		Size *string `json:"size"`
+	} `json:"order_details"`
+}
+
+func GetCompletedTasks(since time.Time) ([]CompletedTask, error) {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return nil, fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/tasks?select=order_detail_id,task_type,quantity,estimated_hours,actual_hours,completed_at,order_details(type,size)&status=eq.completed&actual_hours=not.is.null&completed_at=gte.%s", supabaseUrl, since.Format("2006-01-02"))
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed tasks: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch completed tasks with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	var rows []completedTaskRow
+
+	err = json.Unmarshal(body, &rows)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse completed tasks response: %w, body: %s", err, string(body))
+	}
+
+	tasks := []CompletedTask{}
+
+	for _, row := range rows {
+		if row.ActualHours == nil || row.CompletedAt == nil || row.OrderDetail == nil {
+			continue
+		}
+
+		tasks = append(tasks, CompletedTask{
+			OrderDetailId:  row.OrderDetailId,
+			TaskType:       row.TaskType,
+			PieceType:      PieceType(row.OrderDetail.Type),
+			Size:           row.OrderDetail.Size,
+			Quantity:       row.Quantity,
+			EstimatedHours: row.EstimatedHours,
+			ActualHours:    *row.ActualHours,
+			CompletedAt:    *row.CompletedAt,
+		})
+	}
+
+	return tasks, nil
+}
+
+func InsertProcessCatalog(catalog *ProcessCatalog) error {
+	supabaseUrl := os.Getenv("SUPABASE_URL")
+	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+
+	if supabaseUrl == "" || supabaseKey == "" {
+		return fmt.Errorf("database configuration missing, has_url: %t ; has_key: %t", supabaseUrl != "", supabaseKey != "")
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/production_steps", supabaseUrl)
+
+	body, err := json.Marshal(processCatalogRows(catalog))
+
+	if err != nil {
+		return fmt.Errorf("failed to parse production steps into json: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create insert production steps request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert production steps: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to insert production steps with status %d and response %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func processCatalogRows(catalog *ProcessCatalog) []productionStepRow {
+	effectiveFrom := catalog.EffectiveFrom().Format("2006-01-02")
+	rows := []productionStepRow{}
+
+	for _, pieceType := range catalog.PieceTypes() {
+		for position, step := range catalog.Steps(pieceType) {
+			rows = append(rows, productionStepRow{
+				Version:       catalog.Version(),
+				EffectiveFrom: &effectiveFrom,
+				PieceType:     pieceType,
+				Position:      position,
+				StepKey:       step.StepKey,
+				TaskType:      step.TaskType,
+				Rate:          step.Rate,
+				DryingDays:    step.DryingDays,
+				After:         step.After,
+				Optional:      step.Optional,
+				LossRate:      step.LossRate,
+				Sizes:         catalog.sizes,
+				Setup:         catalog.setup,
+			})
+		}
+	}
+
+	return rows
+}
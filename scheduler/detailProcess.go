@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const DinnerwareSetSize = "set"
+
+// DinnerwareSet is planned for dinnerware ordered as a "set" without an
+// explicit component list
+var DinnerwareSet = []orders.OrderDetailComponentDTO{
+	{Name: "plate", QuantityPerUnit: 1},
+	{Name: "bowl", QuantityPerUnit: 1},
+}
+
+type detailProcess struct {
+	component string
+	process   PieceType
+	steps     []ProductionStep
+	perUnit   int
+	derived   bool
+
+	// planned leaves out the optional steps the order detail didn't ask for
+	planned []ProductionStep
+}
+
+func (t TaskChainItem) chainKey() string {
+	key := t.OrderDetailId
+	if t.DefectReportId != "" {
+		key += "#" + t.DefectReportId
+	}
+
+	if t.Component == "" {
+		return key
+	}
+
+	return key + "/" + t.Component
+}
+
+func (t TaskChainItem) processKey() PieceType {
+	if t.Process == "" {
+		return t.PieceType
+	}
+
+	return t.Process
+}
+
+func basePieceType(pieceType PieceType) PieceType {
+	base, _, _ := strings.Cut(string(pieceType), "/")
+	return PieceType(base)
+}
+
+func variantPieceType(pieceType PieceType, variant string) PieceType {
+	return PieceType(string(pieceType) + "/" + strings.ToLower(strings.TrimSpace(variant)))
+}
+
+// resolveDetailProcesses picks the production process for each component of
+// an order detail, scaled to the ordered size. Processes that aren't in the
+// catalog are marked derived so the planner can be given them.
+func resolveDetailProcesses(catalog *ProcessCatalog, pieceType PieceType, orderDetail orders.OrderDetailDTO) ([]detailProcess, error) {
+	processes, err := detailProcesses(catalog, pieceType, orderDetail)
+	if err != nil {
+		return nil, err
+	}
+
+	if orderDetail.Size != nil {
+		if multiplier, exists := catalog.sizeMultiplier(*orderDetail.Size); exists {
+			for i, process := range processes {
+				// Custom steps are written for the piece as ordered
+				if process.derived {
+					continue
+				}
+
+				processes[i].process = sizedPieceType(process.process, *orderDetail.Size)
+				processes[i].steps = multiplier.apply(process.steps)
+				processes[i].derived = true
+			}
+		}
+	}
+
+	return planOptionalSteps(processes, orderDetail)
+}
+
+func planOptionalSteps(processes []detailProcess, orderDetail orders.OrderDetailDTO) ([]detailProcess, error) {
+	selected := stepKeys(orderDetail.OptionalSteps)
+
+	for _, stepKey := range selected {
+		optional := false
+		for _, process := range processes {
+			optional = optional || slices.ContainsFunc(process.steps, func(step ProductionStep) bool {
+				return step.StepKey == stepKey && step.Optional
+			})
+		}
+
+		if !optional {
+			return nil, fmt.Errorf("order detail %s asks for step %s, which isn't an optional step of its process", orderDetail.ID, stepKey)
+		}
+	}
+
+	for i, process := range processes {
+		processes[i].planned = selectSteps(process.steps, selected)
+	}
+
+	return processes, nil
+}
+
+// detailProcesses resolves custom steps before components, components before
+// a size variant such as dinnerware/plate, and falls back to the piece type's
+// own process
+func detailProcesses(catalog *ProcessCatalog, pieceType PieceType, orderDetail orders.OrderDetailDTO) ([]detailProcess, error) {
+	if len(orderDetail.CustomSteps) > 0 {
+		if pieceType != PieceTypeOther {
+			return nil, fmt.Errorf("order detail %s has custom steps but only %s pieces support them", orderDetail.ID, PieceTypeOther)
+		}
+
+		process := variantPieceType(pieceType, orderDetail.ID)
+		steps := productionSteps(orderDetail.CustomSteps)
+
+		if err := validateProcess(process, steps); err != nil {
+			return nil, fmt.Errorf("order detail %s has invalid custom steps: %w", orderDetail.ID, err)
+		}
+
+		return []detailProcess{{process: process, steps: steps, perUnit: 1, derived: true}}, nil
+	}
+
+	components := orderDetail.Components
+
+	if len(components) == 0 && orderDetail.Size != nil {
+		size := strings.ToLower(strings.TrimSpace(*orderDetail.Size))
+
+		if pieceType == PieceTypeDinnerware && size == DinnerwareSetSize {
+			components = DinnerwareSet
+		} else if variant := variantPieceType(pieceType, size); size != "" && catalog.HasProcess(variant) {
+			return []detailProcess{{process: variant, steps: catalog.Steps(variant), perUnit: 1}}, nil
+		}
+	}
+
+	if len(components) == 0 {
+		if !catalog.HasProcess(pieceType) {
+			return nil, fmt.Errorf("order detail %s has no production process for %s", orderDetail.ID, pieceType)
+		}
+
+		return []detailProcess{{process: pieceType, steps: catalog.Steps(pieceType), perUnit: 1}}, nil
+	}
+
+	processes := []detailProcess{}
+	errs := []error{}
+
+	for _, component := range components {
+		name := strings.ToLower(strings.TrimSpace(component.Name))
+		if name == "" {
+			errs = append(errs, fmt.Errorf("order detail %s has a component without a name", orderDetail.ID))
+			continue
+		}
+
+		resolved := detailProcess{
+			component: name,
+			process:   variantPieceType(pieceType, name),
+			perUnit:   max(1, component.QuantityPerUnit),
+		}
+
+		if len(component.Steps) > 0 {
+			resolved.process = variantPieceType(pieceType, orderDetail.ID+"/"+name)
+			resolved.steps = productionSteps(component.Steps)
+			resolved.derived = true
+
+			if err := validateProcess(resolved.process, resolved.steps); err != nil {
+				errs = append(errs, fmt.Errorf("order detail %s component %s has invalid steps: %w", orderDetail.ID, name, err))
+				continue
+			}
+		} else if catalog.HasProcess(resolved.process) {
+			resolved.steps = catalog.Steps(resolved.process)
+		} else {
+			errs = append(errs, fmt.Errorf("order detail %s component %s has no production process", orderDetail.ID, name))
+			continue
+		}
+
+		processes = append(processes, resolved)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return processes, nil
+}
+
+func productionSteps(steps []orders.ProcessStepDTO) []ProductionStep {
+	converted := make([]ProductionStep, 0, len(steps))
+	for _, step := range steps {
+		converted = append(converted, ProductionStep{
+			StepKey:    StepKey(step.StepKey),
+			TaskType:   TaskType(step.TaskType),
+			Rate:       step.Rate,
+			DryingDays: step.DryingDays,
+			After:      stepKeys(step.After),
+			LossRate:   step.LossRate,
+		})
+	}
+
+	return converted
+}
+
+func stepKeys(keys []string) []StepKey {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	converted := make([]StepKey, 0, len(keys))
+	for _, key := range keys {
+		converted = append(converted, StepKey(key))
+	}
+
+	return converted
+}
+
+// statusStepIndex finds the order detail's status in steps. Components that
+// skip the status step, like a plate when the set's cups are being attached,
+// resume at the first step that comes after it.
+func statusStepIndex(steps []ProductionStep, status StepKey) (int, bool) {
+	for idx, step := range steps {
+		if step.StepKey == status {
+			return idx, true
+		}
+	}
+
+	rank := stepRank(status)
+	for idx, step := range steps {
+		if stepRank(step.StepKey) > rank {
+			return idx, false
+		}
+	}
+
+	return len(steps), false
+}
+
+// ValidateOrderDetailProcess checks that an order detail's custom steps and
+// components resolve to production processes the scheduler can plan
+func ValidateOrderDetailProcess(orderDetail orders.OrderDetailDTO) error {
+	pieceType, isValidPieceType := IsValidPieceType(orderDetail.Type)
+	if !isValidPieceType {
+		return fmt.Errorf("order detail %s is not valid with type %s", orderDetail.ID, orderDetail.Type)
+	}
+
+	_, err := resolveDetailProcesses(CurrentProcessCatalog(), pieceType, orderDetail)
+	return err
+}
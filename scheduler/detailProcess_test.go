@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func customSteps(buildRate float64) []orders.ProcessStepDTO {
+	return []orders.ProcessStepDTO{
+		{StepKey: string(StepKeyBuild), TaskType: string(TaskTypeBuildBase), Rate: buildRate, DryingDays: 1},
+		{StepKey: string(StepKeyBisque), TaskType: string(TaskTypeBisque), DryingDays: 5},
+		{StepKey: string(StepKeyFire), TaskType: string(TaskTypeFire), DryingDays: 5},
+	}
+}
+
+func TestCalculateDetailChains_DinnerwareSetPlansEachComponent(t *testing.T) {
+	size := "Set"
+	detail := orders.OrderDetailDTO{ID: "set-detail", Type: string(PieceTypeDinnerware), Size: &size, Quantity: 4, Status: string(StepKeyPending)}
+
+	chains, custom, err := calculateDetailChains(DefaultProcessCatalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 60), time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 2)
+	assert.Empty(t, custom)
+
+	plate, bowl := chains[0], chains[1]
+	assert.Equal(t, "plate", plate[0].Component)
+	assert.Equal(t, PieceTypeDinnerwarePlate, plate[0].Process)
+	assert.Equal(t, TaskTypeBuildBase, plate[0].TaskType)
+	assert.Equal(t, "bowl", bowl[0].Component)
+	assert.Equal(t, TaskTypeBuildBowl, bowl[0].TaskType)
+	assert.NotEqual(t, plate[0].chainKey(), bowl[0].chainKey(), "Components of one order detail are tracked separately")
+
+	for _, chain := range chains {
+		assert.Equal(t, 4, chain[0].Quantity)
+		assert.Equal(t, PieceTypeDinnerware, chain[0].PieceType)
+	}
+}
+
+func TestCalculateDetailChains_ComponentQuantities(t *testing.T) {
+	detail := orders.OrderDetailDTO{
+		ID:                "components-detail",
+		Type:              string(PieceTypeDinnerware),
+		Quantity:          3,
+		CompletedQuantity: 1,
+		Status:            string(StepKeyPending),
+		Components: []orders.OrderDetailComponentDTO{
+			{Name: "plate", QuantityPerUnit: 2},
+			{Name: "bowl"},
+		},
+	}
+
+	chains, _, err := calculateDetailChains(DefaultProcessCatalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 60), time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 2)
+
+	assert.Equal(t, 4, chains[0][0].Quantity, "Two plates for each of the two remaining place settings")
+	assert.Equal(t, 2, chains[1][0].Quantity)
+}
+
+func TestCalculateDetailChains_SizeSelectsVariant(t *testing.T) {
+	size := "bowl"
+	detail := orders.OrderDetailDTO{ID: "bowl-detail", Type: string(PieceTypeDinnerware), Size: &size, Quantity: 2, Status: string(StepKeyPending)}
+
+	chains, _, err := calculateDetailChains(DefaultProcessCatalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 60), time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+
+	assert.Empty(t, chains[0][0].Component)
+	assert.Equal(t, PieceTypeDinnerwareBowl, chains[0][0].processKey())
+}
+
+func TestCalculateDetailChains_EveryPieceTypeHasAProcess(t *testing.T) {
+	for _, pieceType := range []PieceType{PieceTypeDinnerware, PieceTypeOther} {
+		detail := orders.OrderDetailDTO{ID: "detail", Type: string(pieceType), Quantity: 1, Status: string(StepKeyPending)}
+
+		tasks, err := CalculateTaskChain(detail, time.Now().AddDate(0, 0, 60))
+		require.NoError(t, err, pieceType)
+		assert.NotEmpty(t, tasks, pieceType)
+	}
+}
+
+func TestCalculateDetailChains_CustomSteps(t *testing.T) {
+	detail := orders.OrderDetailDTO{ID: "sculpture", Type: string(PieceTypeOther), Quantity: 1, Status: string(StepKeyPending), CustomSteps: customSteps(0.5)}
+
+	chains, custom, err := calculateDetailChains(DefaultProcessCatalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 60), time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	require.Len(t, chains[0], 3)
+
+	assert.Equal(t, []StepKey{StepKeyBuild, StepKeyBisque, StepKeyFire}, []StepKey{chains[0][0].OrderDetailStatus, chains[0][1].OrderDetailStatus, chains[0][2].OrderDetailStatus})
+	assert.Contains(t, custom, chains[0][0].Process)
+}
+
+func TestCalculateDetailChains_RejectsInvalidCustomSteps(t *testing.T) {
+	invalid := customSteps(0)
+	detail := orders.OrderDetailDTO{ID: "sculpture", Type: string(PieceTypeOther), Quantity: 1, Status: string(StepKeyPending), CustomSteps: invalid}
+
+	_, _, err := calculateDetailChains(DefaultProcessCatalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 60), time.Now())
+	assert.ErrorContains(t, err, "needs a rate")
+
+	detail.Type = string(PieceTypeTumbler)
+	detail.CustomSteps = customSteps(1)
+	assert.ErrorContains(t, ValidateOrderDetailProcess(detail), "only other pieces")
+
+	detail.Type = string(PieceTypeDinnerware)
+	detail.CustomSteps = nil
+	detail.Components = []orders.OrderDetailComponentDTO{{Name: "teapot"}}
+	assert.ErrorContains(t, ValidateOrderDetailProcess(detail), "component teapot has no production process")
+}
+
+func TestCalculateCompletionDate_SlowestComponent(t *testing.T) {
+	fromDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	size := "set"
+	detail := orders.OrderDetailDTO{ID: "set-detail", Type: string(PieceTypeDinnerware), Size: &size, Quantity: 60, Status: string(StepKeyPending)}
+
+	setCompletion, err := CalculateCompletionDate(detail, fromDate)
+	require.NoError(t, err)
+
+	detail.Size = nil
+	detail.Components = []orders.OrderDetailComponentDTO{{Name: "bowl"}}
+	bowlCompletion, err := CalculateCompletionDate(detail, fromDate)
+	require.NoError(t, err)
+
+	assert.Equal(t, bowlCompletion, setCompletion, "Bowls build slower than plates, so they set the date")
+}
+
+func TestScheduler_PlansCustomStepsAndComponents(t *testing.T) {
+	dueDate := time.Now().AddDate(0, 0, 20)
+
+	sculpture := newDeadlineOrder("order-1", "sculpture", PieceTypeOther, 2, dueDate)
+	sculpture.OrderDetails[0].CustomSteps = customSteps(2)
+
+	set := newDeadlineOrder("order-2", "set-detail", PieceTypeDinnerware, 2, dueDate)
+	setSize := DinnerwareSetSize
+	set.OrderDetails[0].Size = &setSize
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(sculpture, set),
+		NewInMemoryAvailabilitySource(flatAvailability(4, 8)),
+		NewInMemoryTaskStore(),
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+
+	components := map[string]bool{}
+	sculptureBuildHours := 0.0
+	for _, day := range preview.Days {
+		for _, task := range day.Tasks {
+			if task.OrderDetailId == "sculpture" && task.TaskType == TaskTypeBuildBase {
+				sculptureBuildHours += task.EstimatedHours
+			}
+			if task.OrderDetailId == "set-detail" {
+				components[task.Component] = true
+			}
+		}
+	}
+
+	assert.InDelta(t, 4.0, sculptureBuildHours, 0.001, "Two pieces at two per shift take one shift")
+	assert.Equal(t, map[string]bool{"plate": true, "bowl": true}, components)
+}
@@ -3,45 +3,63 @@ package scheduler
 import "math"
 
 func CalculateHours(taskType TaskType, pieceType PieceType, quantity int) float64 {
-	productionStep, found := getProductionStepForTaskByPiece(taskType, pieceType)
+	return calculateHours(CurrentProcessCatalog(), taskType, pieceType, quantity)
+}
+
+func CalculateQuantity(hours float64, taskType TaskType, pieceType PieceType) int {
+	return calculateQuantity(CurrentProcessCatalog(), hours, taskType, pieceType)
+}
+
+func calculateHours(catalog *ProcessCatalog, taskType TaskType, pieceType PieceType, quantity int) float64 {
+	productionStep, found := catalog.stepForTask(taskType, pieceType)
 
 	if !found {
 		return 0
 	}
 
-	// Handle tasks with zero rate (external processes like bisque/fire)
-	if productionStep.Rate == 0 {
+	return stepHours(catalog, productionStep, quantity)
+}
+
+func calculateQuantity(catalog *ProcessCatalog, hours float64, taskType TaskType, pieceType PieceType) int {
+
+	step, found := catalog.stepForTask(taskType, pieceType)
+
+	if !found {
 		return 0
 	}
 
-	return float64(quantity) / (productionStep.Rate / ShiftDurationHours)
+	return stepQuantity(catalog, hours, step)
 }
 
-func CalculateQuantity(hours float64, taskType TaskType, pieceType PieceType) int {
-
-	if hours <= 0 {
+// stepHours is the hours to work quantity pieces through one step. Planned
+// tasks know their step, which matters when a process repeats a task type.
+func stepHours(catalog *ProcessCatalog, productionStep ProductionStep, quantity int) float64 {
+	// Handle tasks with zero rate (external processes like bisque/fire)
+	if productionStep.Rate == 0 || quantity <= 0 {
 		return 0
 	}
 
-	shifts := hours / ShiftDurationHours
-	step, found := getProductionStepForTaskByPiece(taskType, pieceType)
+	return catalog.setupFor(productionStep.TaskType).TaskHours + float64(quantity)/(productionStep.Rate/ShiftDurationHours)
+}
 
-	if !found {
+func stepQuantity(catalog *ProcessCatalog, hours float64, step ProductionStep) int {
+	// The task's setup comes out of the hours before any pieces are made
+	hours -= catalog.setupFor(step.TaskType).TaskHours
+	if hours <= 0 {
 		return 0
 	}
 
-	return int(math.Floor(step.Rate * shifts))
+	shifts := hours / ShiftDurationHours
+
+	// The tolerance keeps hours worked out from a quantity from rounding down
+	// to one piece fewer
+	return int(math.Floor(step.Rate*shifts + 1e-9))
 }
 
 func getProductionStepForTaskByPiece(taskType TaskType, pieceType PieceType) (ProductionStep, bool) {
+	return CurrentProcessCatalog().stepForTask(taskType, pieceType)
+}
 
-	productionSteps := ProductionProcess[pieceType]
-
-	for _, step := range productionSteps {
-		if step.TaskType == taskType {
-			return step, true
-		}
-	}
-
-	return ProductionStep{}, false
+func getProductionStepByKey(stepKey StepKey, pieceType PieceType) (ProductionStep, bool) {
+	return CurrentProcessCatalog().stepByKey(stepKey, pieceType)
 }
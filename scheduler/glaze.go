@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"slices"
+	"strings"
+)
+
+func normalizeGlaze(glaze *string) string {
+	if glaze == nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(*glaze))
+}
+
+func (d *DaySchedule) hasGlaze(glaze string) bool {
+	return slices.Contains(d.Glazes, glaze)
+}
+
+func (d *DaySchedule) addGlaze(glaze string) {
+	if glaze != "" && !d.hasGlaze(glaze) {
+		d.Glazes = append(d.Glazes, glaze)
+	}
+}
+
+// joinsGlazeBatch lets pieces that are ready ahead of schedule be glazed on a
+// day their glaze is already set up
+func (p *planner) joinsGlazeBatch(daySchedule *DaySchedule, task TaskChainItem) bool {
+	return task.TaskType == TaskTypeGlaze && task.Glaze != "" && daySchedule.hasGlaze(task.Glaze)
+}
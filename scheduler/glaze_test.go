@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGlazeItem(detailID, glaze string, startDate time.Time) TaskChainItem {
+	return TaskChainItem{
+		TaskType:          TaskTypeGlaze,
+		PieceType:         PieceTypeMugWithoutHandle,
+		StartDate:         startDate,
+		Quantity:          10,
+		OrderDetailId:     detailID,
+		OrderDetailStatus: StepKeyGlaze,
+		HasDeadline:       true,
+		Glaze:             glaze,
+	}
+}
+
+const glazeMixingHours = 0.25
+
+// newGlazePlanner plans against a catalog that sets time aside for mixing
+// each glaze
+func newGlazePlanner(t *testing.T, monday time.Time) *planner {
+	t.Helper()
+
+	catalog, err := NewProcessCatalogWithSetup(DefaultProductionProcess(), SizeMultipliers, map[TaskType]SetupTime{
+		TaskTypeGlaze: {DayHours: glazeMixingHours},
+	})
+	require.NoError(t, err)
+
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+	p.process = catalog
+
+	return p
+}
+
+func TestPlanDay_GroupsGlazesWithSetup(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newGlazePlanner(t, monday)
+
+	tasks := []TaskChainItem{
+		newGlazeItem("celadon-1", "celadon", monday),
+		newGlazeItem("shino", "shino", monday),
+		newGlazeItem("celadon-2", "celadon", monday.AddDate(0, 0, 5)),
+	}
+	for i := range tasks {
+		p.trackChain(tasks[i : i+1])
+	}
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: 8}
+	_, scheduled := p.planDay(monday, daySchedule, p.prioritise(monday, tasks))
+	require.True(t, scheduled)
+	require.Len(t, daySchedule.Tasks, 3)
+
+	glazeHours := CalculateHours(TaskTypeGlaze, PieceTypeMugWithoutHandle, 10)
+
+	assert.Equal(t, "celadon-1", daySchedule.Tasks[0].OrderDetailId)
+	assert.Equal(t, "shino", daySchedule.Tasks[1].OrderDetailId)
+	assert.Equal(t, "celadon-2", daySchedule.Tasks[2].OrderDetailId, "Celadon is already mixed, so pieces due later are glazed with it")
+
+	assert.InDelta(t, glazeHours+glazeMixingHours, daySchedule.Tasks[0].EstimatedHours, 0.001)
+	assert.InDelta(t, glazeHours+glazeMixingHours, daySchedule.Tasks[1].EstimatedHours, 0.001)
+	assert.InDelta(t, glazeHours, daySchedule.Tasks[2].EstimatedHours, 0.001)
+	assert.Equal(t, "celadon", daySchedule.Tasks[2].Glaze)
+	assert.Equal(t, []string{"celadon", "shino"}, daySchedule.Glazes)
+}
+
+func TestPlanDay_GlazeBatchDoesNotJumpUrgentWork(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newGlazePlanner(t, monday)
+
+	tasks := []TaskChainItem{
+		newGlazeItem("celadon-2", "celadon", monday.AddDate(0, 0, 10)),
+		newGlazeItem("celadon-1", "celadon", monday),
+		newGlazeItem("shino", "shino", monday),
+	}
+	for i := range tasks {
+		p.trackChain(tasks[i : i+1])
+	}
+
+	// The day only has room for two glazes
+	glazeHours := CalculateHours(TaskTypeGlaze, PieceTypeMugWithoutHandle, 10)
+	availableHours := 2*(glazeHours+glazeMixingHours) + 0.01
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: availableHours}
+	p.planDay(monday, daySchedule, p.prioritise(monday, tasks))
+
+	require.Len(t, daySchedule.Tasks, 2)
+	assert.Equal(t, "celadon-1", daySchedule.Tasks[0].OrderDetailId)
+	assert.Equal(t, "shino", daySchedule.Tasks[1].OrderDetailId, "Shino is due before the celadon that could share its batch")
+}
+
+func TestPlanDay_OnlyReadyPiecesJoinAGlazeBatch(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+
+	glaze := newGlazeItem("drying", "celadon", monday.AddDate(0, 0, 5))
+	bisque := newBisqueItem("drying", PieceTypeMugWithoutHandle, 10, monday)
+	p.trackChain([]TaskChainItem{bisque, glaze})
+
+	lead := newGlazeItem("celadon-1", "celadon", monday)
+	p.trackChain([]TaskChainItem{lead})
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: 8}
+	p.planDay(monday, daySchedule, []TaskChainItem{lead, glaze})
+
+	require.Len(t, daySchedule.Tasks, 1, "Pieces still waiting on their bisque can't be glazed")
+	assert.Equal(t, "celadon-1", daySchedule.Tasks[0].OrderDetailId)
+}
+
+func TestPlanDay_NoGlazeNoSetup(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+
+	task := newGlazeItem("plain", "", monday)
+	p.trackChain([]TaskChainItem{task})
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: 8}
+	p.planDay(monday, daySchedule, []TaskChainItem{task})
+
+	require.Len(t, daySchedule.Tasks, 1)
+	assert.InDelta(t, CalculateHours(TaskTypeGlaze, PieceTypeMugWithoutHandle, 10), daySchedule.Tasks[0].EstimatedHours, 0.001)
+	assert.Empty(t, daySchedule.Glazes)
+}
+
+func TestCalculateTaskChain_GlazeOnGlazeStep(t *testing.T) {
+	glaze := "  Celadon "
+	detail := orders.OrderDetailDTO{ID: "detail", Type: string(PieceTypeMugWithoutHandle), Quantity: 4, Status: string(StepKeyPending), Glaze: &glaze}
+
+	tasks, err := CalculateTaskChain(detail, time.Now().AddDate(0, 0, 60))
+	require.NoError(t, err)
+
+	for _, task := range tasks {
+		if task.TaskType == TaskTypeGlaze {
+			assert.Equal(t, "celadon", task.Glaze)
+		} else {
+			assert.Empty(t, task.Glaze, task.OrderDetailStatus)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type KilnConfig struct {
+	CapacityPieces float64
+	FiringDays     int
+	CoolDownDays   int
+	FiringsPerWeek int
+	MinLoadRatio   float64
+}
+
+var DefaultKiln = KilnConfig{
+	CapacityPieces: 40,
+	FiringDays:     1,
+	CoolDownDays:   1,
+	FiringsPerWeek: 2,
+	MinLoadRatio:   0.75,
+}
+
+var KilnPieceEquivalents = map[PieceType]float64{
+	PieceTypeMugWithHandle:    1.0,
+	PieceTypeMugWithoutHandle: 1.0,
+	PieceTypeTumbler:          1.5,
+	PieceTypeMatchaBowl:       1.25,
+	PieceTypeTrinketDish:      0.5,
+	PieceTypeDinnerware:       1.5,
+	PieceTypeOther:            1.0,
+	PieceTypeDinnerwarePlate:  1.5,
+	PieceTypeDinnerwareBowl:   1.0,
+}
+
+type kilnCandidate struct {
+	task         TaskChainItem
+	latestFiring time.Time
+}
+
+func (k KilnConfig) TurnaroundDays() int {
+	return max(1, k.FiringDays+k.CoolDownDays)
+}
+
+func pieceEquivalents(pieceType PieceType) float64 {
+	if equivalents, exists := KilnPieceEquivalents[pieceType]; exists {
+		return equivalents
+	}
+
+	if equivalents, exists := KilnPieceEquivalents[basePieceType(pieceType)]; exists {
+		return equivalents
+	}
+
+	return 1.0
+}
+
+func kilnLoadID(day time.Time) string {
+	return "kiln-" + day.Format("2006-01-02")
+}
+
+func kilnWeek(day time.Time) string {
+	return day.AddDate(0, 0, -int(day.Weekday())).Format("2006-01-02")
+}
+
+func (p *planner) fireKiln(day time.Time, daySchedule *DaySchedule, tasks []TaskChainItem) ([]TaskChainItem, bool) {
+	candidates := p.kilnCandidates(day, tasks)
+
+	if len(candidates) == 0 {
+		return tasks, false
+	}
+
+	load := p.kilnLoadOn(day)
+
+	if load == nil {
+		if !p.kilnAvailable(day) {
+			return tasks, false
+		}
+
+		firingType, forced, shouldFire := p.chooseFiring(day, candidates)
+		if !shouldFire {
+			return tasks, false
+		}
+
+		p.kilnLoads = append(p.kilnLoads, KilnLoad{
+			ID:             kilnLoadID(day),
+			Date:           day,
+			FiringType:     firingType,
+			CapacityPieces: p.kiln.CapacityPieces,
+			Forced:         forced,
+			Items:          []KilnLoadItem{},
+		})
+		load = &p.kilnLoads[len(p.kilnLoads)-1]
+	}
+
+	fired := false
+	completionDate := day.AddDate(0, 0, p.kiln.TurnaroundDays())
+
+	for _, candidate := range candidates[load.FiringType] {
+		task := candidate.task
+		equivalents := pieceEquivalents(task.processKey())
+
+		quantity := task.Quantity
+		if p.kiln.CapacityPieces > 0 {
+			fit := int(math.Floor((load.CapacityPieces-load.LoadedPieces)/equivalents + 1e-9))
+			quantity = min(quantity, fit)
+		}
+
+		if quantity <= 0 {
+			continue
+		}
+
+		load.Items = append(load.Items, KilnLoadItem{
+			OrderDetailId:    task.OrderDetailId,
+			Component:        task.Component,
+			PieceType:        task.PieceType,
+			Quantity:         quantity,
+			PieceEquivalents: equivalents * float64(quantity),
+		})
+		load.LoadedPieces += equivalents * float64(quantity)
+
+		daySchedule.Tasks = append(daySchedule.Tasks, TaskToCreate{
+			OrderDetailId:  task.OrderDetailId,
+			Component:      task.Component,
+			StepKey:        task.OrderDetailStatus,
+			Date:           day,
+			TaskType:       task.TaskType,
+			Quantity:       quantity,
+			EstimatedHours: 0,
+			IsLate:         task.HasDeadline && task.StartDate.Before(p.startDate),
+			KilnLoadId:     load.ID,
+			DefectReportId: task.DefectReportId,
+			CatalogVersion: p.process.Version(),
+		})
+
+		p.markStarted(task, day)
+
+		i := indexOfChainItem(tasks, task.chainKey(), task.OrderDetailStatus)
+		tasks, _ = p.recordProgress(tasks, i, quantity, completionDate)
+		fired = true
+	}
+
+	return tasks, fired
+}
+
+func (p *planner) kilnCandidates(day time.Time, tasks []TaskChainItem) map[TaskType][]kilnCandidate {
+	candidates := make(map[TaskType][]kilnCandidate)
+
+	for _, task := range tasks {
+		if !isExternalProcess(task.TaskType) {
+			continue
+		}
+
+		earliestPossibleStart, isCurrentStep := p.earliestStart(task)
+		if !isCurrentStep || earliestPossibleStart.After(day) {
+			continue
+		}
+
+		// The chain reserves the step's DryingDays for the firing, so any days
+		// beyond the kiln turnaround can be spent waiting for a fuller load
+		step, _ := p.process.stepByKey(task.OrderDetailStatus, task.processKey())
+		waitDays := max(0, step.DryingDays-p.kiln.TurnaroundDays())
+
+		// Eager work waits no longer than that from when its pieces are ready
+		latestStart := task.StartDate
+		if task.Eager && earliestPossibleStart.Before(latestStart) {
+			latestStart = earliestPossibleStart
+		}
+
+		candidates[task.TaskType] = append(candidates[task.TaskType], kilnCandidate{
+			task:         task,
+			latestFiring: latestStart.AddDate(0, 0, waitDays),
+		})
+	}
+
+	for _, list := range candidates {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].latestFiring.Before(list[j].latestFiring)
+		})
+	}
+
+	return candidates
+}
+
+func (p *planner) chooseFiring(day time.Time, candidates map[TaskType][]kilnCandidate) (TaskType, bool, bool) {
+	var chosen TaskType
+	var chosenForced bool
+	var chosenLoad float64
+	var chosenLatest time.Time
+
+	for _, firingType := range []TaskType{TaskTypeBisque, TaskTypeFire} {
+		list := candidates[firingType]
+		if len(list) == 0 {
+			continue
+		}
+
+		load := 0.0
+		for _, candidate := range list {
+			load += pieceEquivalents(candidate.task.processKey()) * float64(candidate.task.Quantity)
+		}
+
+		if p.kiln.CapacityPieces > 0 {
+			load = math.Min(load, p.kiln.CapacityPieces)
+		}
+
+		forced := !list[0].latestFiring.After(day)
+		fullEnough := p.kiln.CapacityPieces <= 0 || load >= p.kiln.CapacityPieces*p.kiln.MinLoadRatio
+
+		if !forced && !fullEnough {
+			continue
+		}
+
+		switch {
+		case chosen == "":
+		case forced && !chosenForced:
+		case forced && chosenForced && list[0].latestFiring.Before(chosenLatest):
+		case !forced && !chosenForced && load > chosenLoad:
+		default:
+			continue
+		}
+
+		chosen = firingType
+		chosenForced = forced
+		chosenLoad = load
+		chosenLatest = list[0].latestFiring
+	}
+
+	return chosen, chosenForced, chosen != ""
+}
+
+func (p *planner) kilnLoadOn(day time.Time) *KilnLoad {
+	for i := range p.kilnLoads {
+		if p.kilnLoads[i].Date.Format("2006-01-02") == day.Format("2006-01-02") {
+			return &p.kilnLoads[i]
+		}
+	}
+
+	return nil
+}
+
+func (p *planner) kilnAvailable(day time.Time) bool {
+	turnaround := p.kiln.TurnaroundDays()
+	firingsThisWeek := 0
+
+	for _, load := range p.kilnLoads {
+		if kilnWeek(load.Date) == kilnWeek(day) {
+			firingsThisWeek++
+		}
+
+		if day.Before(load.Date.AddDate(0, 0, turnaround)) && load.Date.Before(day.AddDate(0, 0, turnaround)) {
+			return false
+		}
+	}
+
+	return p.kiln.FiringsPerWeek <= 0 || firingsThisWeek < p.kiln.FiringsPerWeek
+}
+
+func indexOfChainItem(tasks []TaskChainItem, chainKey string, status StepKey) int {
+	for i, task := range tasks {
+		if task.chainKey() == chainKey && task.OrderDetailStatus == status {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBisqueItem(detailID string, pieceType PieceType, quantity int, startDate time.Time) TaskChainItem {
+	return TaskChainItem{
+		TaskType:          TaskTypeBisque,
+		PieceType:         pieceType,
+		StartDate:         startDate,
+		Quantity:          quantity,
+		OrderDetailId:     detailID,
+		OrderDetailStatus: StepKeyBisque,
+	}
+}
+
+func newKilnTestPlanner(monday time.Time, kiln KilnConfig, tasks []TaskChainItem) *planner {
+	p := newPlanner(monday, monday.AddDate(0, 0, 13), map[string]float64{}, kiln, DefaultPriority, DefaultChangeover)
+
+	for _, task := range tasks {
+		p.trackChain([]TaskChainItem{task})
+	}
+
+	return p
+}
+
+func TestKiln_WaitsForFullerLoad(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	tasks := []TaskChainItem{newBisqueItem("detail-1", PieceTypeMugWithHandle, 5, monday)}
+	p := newKilnTestPlanner(monday, DefaultKiln, tasks)
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}}
+	remaining, fired := p.fireKiln(monday, daySchedule, tasks)
+
+	assert.False(t, fired, "5 mugs should not fill a 40 piece kiln while there is slack")
+	assert.Len(t, remaining, 1)
+	assert.Empty(t, daySchedule.Tasks)
+	assert.Empty(t, p.kilnLoads)
+}
+
+func TestKiln_GroupsOrdersIntoSharedLoad(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	tasks := []TaskChainItem{
+		newBisqueItem("detail-1", PieceTypeMugWithHandle, 15, monday),
+		newBisqueItem("detail-2", PieceTypeTumbler, 10, monday),
+	}
+	p := newKilnTestPlanner(monday, DefaultKiln, tasks)
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}}
+	remaining, fired := p.fireKiln(monday, daySchedule, tasks)
+
+	require.True(t, fired, "30 piece-equivalents should reach the 75% load threshold")
+	assert.Empty(t, remaining)
+	require.Len(t, p.kilnLoads, 1)
+
+	load := p.kilnLoads[0]
+	assert.Equal(t, "kiln-2025-10-20", load.ID)
+	assert.Equal(t, TaskTypeBisque, load.FiringType)
+	assert.False(t, load.Forced)
+	assert.InDelta(t, 30.0, load.LoadedPieces, 0.001)
+	require.Len(t, load.Items, 2)
+	assert.Equal(t, "detail-1", load.Items[0].OrderDetailId)
+	assert.Equal(t, "detail-2", load.Items[1].OrderDetailId)
+
+	require.Len(t, daySchedule.Tasks, 2)
+	for _, task := range daySchedule.Tasks {
+		assert.Equal(t, load.ID, task.KilnLoadId, "Firing tasks should reference their kiln load")
+		assert.Equal(t, 0.0, task.EstimatedHours)
+	}
+
+	expectedCompletion := monday.AddDate(0, 0, DefaultKiln.TurnaroundDays())
+	assert.Equal(t, expectedCompletion, p.lastCompletion["detail-1"], "Pieces are ready after firing and cool-down")
+}
+
+func TestKiln_DeadlineForcesPartialLoad(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	waitDays := 5 - DefaultKiln.TurnaroundDays()
+	tasks := []TaskChainItem{newBisqueItem("detail-1", PieceTypeMugWithHandle, 5, monday.AddDate(0, 0, -waitDays))}
+	p := newKilnTestPlanner(monday, DefaultKiln, tasks)
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}}
+	remaining, fired := p.fireKiln(monday, daySchedule, tasks)
+
+	require.True(t, fired, "Waiting any longer would delay the order, so the kiln must fire")
+	assert.Empty(t, remaining)
+	require.Len(t, p.kilnLoads, 1)
+	assert.True(t, p.kilnLoads[0].Forced)
+	assert.InDelta(t, 5.0, p.kilnLoads[0].LoadedPieces, 0.001)
+}
+
+func TestKiln_RespectsCapacity(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	tasks := []TaskChainItem{
+		newBisqueItem("detail-1", PieceTypeMugWithHandle, 30, monday),
+		newBisqueItem("detail-2", PieceTypeMugWithHandle, 20, monday),
+	}
+	p := newKilnTestPlanner(monday, DefaultKiln, tasks)
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}}
+	remaining, fired := p.fireKiln(monday, daySchedule, tasks)
+
+	require.True(t, fired)
+	assert.InDelta(t, 40.0, p.kilnLoads[0].LoadedPieces, 0.001, "Load should not exceed kiln capacity")
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "detail-2", remaining[0].OrderDetailId)
+	assert.Equal(t, 10, remaining[0].Quantity, "Pieces that don't fit wait for the next firing")
+}
+
+func TestKiln_BusyDuringFiringAndWeeklyLimit(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newKilnTestPlanner(monday, DefaultKiln, nil)
+
+	p.kilnLoads = append(p.kilnLoads, KilnLoad{ID: kilnLoadID(monday), Date: monday, FiringType: TaskTypeBisque})
+
+	assert.False(t, p.kilnAvailable(monday.AddDate(0, 0, 1)), "Kiln is still firing or cooling the next day")
+	assert.True(t, p.kilnAvailable(monday.AddDate(0, 0, 2)), "Kiln is free after the turnaround")
+
+	wednesday := monday.AddDate(0, 0, 2)
+	p.kilnLoads = append(p.kilnLoads, KilnLoad{ID: kilnLoadID(wednesday), Date: wednesday, FiringType: TaskTypeFire})
+
+	assert.False(t, p.kilnAvailable(monday.AddDate(0, 0, 5)), "Only two firings are allowed per week")
+	assert.True(t, p.kilnAvailable(monday.AddDate(0, 0, 7)), "Limit resets the following week")
+}
+
+func TestKiln_PrefersForcedFiringType(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newKilnTestPlanner(monday, DefaultKiln, nil)
+
+	candidates := map[TaskType][]kilnCandidate{
+		TaskTypeBisque: {{task: newBisqueItem("full", PieceTypeMugWithHandle, 40, monday), latestFiring: monday.AddDate(0, 0, 3)}},
+		TaskTypeFire:   {{task: TaskChainItem{TaskType: TaskTypeFire, PieceType: PieceTypeMugWithHandle, Quantity: 2, OrderDetailId: "urgent", OrderDetailStatus: StepKeyFire}, latestFiring: monday}},
+	}
+
+	firingType, forced, shouldFire := p.chooseFiring(monday, candidates)
+
+	assert.True(t, shouldFire)
+	assert.True(t, forced)
+	assert.Equal(t, TaskTypeFire, firingType, "A firing forced by a deadline wins over a fuller load")
+}
+
+func TestScheduler_PreviewBatchesKilnLoadsAcrossOrders(t *testing.T) {
+	now := time.Now()
+	dueDate := now.AddDate(0, 0, 14)
+	trimmedAt := now.AddDate(0, 0, -10)
+
+	newTrimmedOrder := func(orderID, detailID string, quantity int) orders.OrderDTO {
+		order := newDeadlineOrder(orderID, detailID, PieceTypeMugWithHandle, quantity, dueDate)
+		order.OrderDetails[0].Status = string(StepKeyTrimFinal)
+		order.OrderDetails[0].StatusChangedAt = &trimmedAt
+		return order
+	}
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newTrimmedOrder("order-1", "detail-1", 15), newTrimmedOrder("order-2", "detail-2", 15)),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+
+	require.NotEmpty(t, preview.KilnLoads)
+	bisqueLoad := preview.KilnLoads[0]
+	assert.Equal(t, TaskTypeBisque, bisqueLoad.FiringType)
+	require.Len(t, bisqueLoad.Items, 2, "Both orders should share the first bisque firing")
+	assert.Equal(t, "detail-1", bisqueLoad.Items[0].OrderDetailId)
+	assert.Equal(t, "detail-2", bisqueLoad.Items[1].OrderDetailId)
+}
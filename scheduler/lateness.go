@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+func (p *planner) latenessReport(remaining []TaskChainItem, reportedAt time.Time) []LatenessEntry {
+	entries := []LatenessEntry{}
+
+	for chainKey, chain := range p.chains {
+		first := chain[0]
+		if !first.HasDeadline {
+			continue
+		}
+
+		completion, starts, fullyScheduled := p.projectCompletion(chainKey, remaining)
+
+		late := completion.After(first.DueDate)
+		breaksPromise := !first.PromisedDate.IsZero() && completion.After(first.PromisedDate)
+
+		if !late && !breaksPromise {
+			continue
+		}
+
+		bottleneck := findBottleneck(chain, starts)
+
+		entry := LatenessEntry{
+			OrderId:             first.OrderId,
+			OrderDetailId:       first.OrderDetailId,
+			Component:           first.Component,
+			DefectReportId:      first.DefectReportId,
+			PieceType:           first.PieceType,
+			DueDate:             first.DueDate,
+			ProjectedCompletion: completion,
+			DaysLate:            daysLate(completion, first.DueDate),
+			BottleneckStep:      bottleneck.OrderDetailStatus,
+			BottleneckTaskType:  bottleneck.TaskType,
+			FullyScheduled:      fullyScheduled,
+			ReportedAt:          reportedAt,
+			CatalogVersion:      p.process.Version(),
+			BulkCodeId:          first.BulkCodeId,
+		}
+
+		if !first.PromisedDate.IsZero() {
+			promisedDate := first.PromisedDate
+			entry.PromisedDate = &promisedDate
+			entry.DaysPastPromise = daysLate(completion, promisedDate)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].DaysLate != entries[j].DaysLate {
+			return entries[i].DaysLate > entries[j].DaysLate
+		}
+		if entries[i].DaysPastPromise != entries[j].DaysPastPromise {
+			return entries[i].DaysPastPromise > entries[j].DaysPastPromise
+		}
+		if entries[i].OrderDetailId != entries[j].OrderDetailId {
+			return entries[i].OrderDetailId < entries[j].OrderDetailId
+		}
+		if entries[i].Component != entries[j].Component {
+			return entries[i].Component < entries[j].Component
+		}
+		return entries[i].DefectReportId < entries[j].DefectReportId
+	})
+
+	return entries
+}
+
+func daysLate(completion time.Time, dueDate time.Time) int {
+	return max(0, int(math.Ceil(completion.Sub(dueDate).Hours()/24)))
+}
+
+func (p *planner) projectCompletion(chainKey string, remaining []TaskChainItem) (time.Time, map[StepKey]time.Time, bool) {
+	starts := make(map[StepKey]time.Time)
+	for stepKey, start := range p.stepStarts[chainKey] {
+		starts[stepKey] = start
+	}
+
+	pending := make(map[StepKey]TaskChainItem)
+	for _, task := range remaining {
+		if task.chainKey() == chainKey {
+			pending[task.OrderDetailStatus] = task
+		}
+	}
+
+	completion := p.lastCompletion[chainKey]
+
+	if len(pending) == 0 {
+		return completion, starts, true
+	}
+
+	finishes := make(map[StepKey]time.Time)
+	for stepKey, finish := range p.stepCompletion[chainKey] {
+		finishes[stepKey] = finish
+	}
+
+	// Work left over after the horizon can't begin until the day after it ends
+	horizonEnd := p.endDate.AddDate(0, 0, 1)
+
+	// Chains are in process order, so a step's predecessors are projected
+	// before it is
+	for _, chained := range p.chains[chainKey] {
+		task, isPending := pending[chained.OrderDetailStatus]
+		if !isPending {
+			continue
+		}
+
+		cursor := horizonEnd
+		for _, stepKey := range append([]StepKey{task.OrderDetailStatus}, p.predecessors[chainKey][task.OrderDetailStatus]...) {
+			if finish := finishes[stepKey]; finish.After(cursor) {
+				cursor = finish
+			}
+		}
+
+		if _, started := starts[task.OrderDetailStatus]; !started {
+			if task.StartDate.After(cursor) {
+				cursor = task.StartDate
+			}
+			starts[task.OrderDetailStatus] = cursor
+		}
+
+		productionStep, _ := p.process.stepByKey(task.OrderDetailStatus, task.processKey())
+		finishes[task.OrderDetailStatus] = p.calendar.stepFinish(cursor, productionStep, task.Quantity)
+
+		if finishes[task.OrderDetailStatus].After(completion) {
+			completion = finishes[task.OrderDetailStatus]
+		}
+	}
+
+	return completion, starts, false
+}
+
+func findBottleneck(chain []TaskChainItem, starts map[StepKey]time.Time) TaskChainItem {
+	bottleneck := chain[len(chain)-1]
+	largestSlip := 0.0
+	previousDelay := 0.0
+
+	for _, task := range chain {
+		start, started := starts[task.OrderDetailStatus]
+		if !started {
+			continue
+		}
+
+		delay := start.Sub(task.StartDate).Hours() / 24
+		if slip := delay - previousDelay; slip > largestSlip {
+			largestSlip = slip
+			bottleneck = task
+		}
+
+		previousDelay = math.Max(delay, previousDelay)
+	}
+
+	return bottleneck
+}
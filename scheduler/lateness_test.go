@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBottleneck_LargestSlip(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	chain := []TaskChainItem{
+		{OrderDetailStatus: StepKeyBuild, TaskType: TaskTypeBuildBase, StartDate: monday},
+		{OrderDetailStatus: StepKeyBisque, TaskType: TaskTypeBisque, StartDate: monday.AddDate(0, 0, 3)},
+		{OrderDetailStatus: StepKeyGlaze, TaskType: TaskTypeGlaze, StartDate: monday.AddDate(0, 0, 6)},
+	}
+
+	starts := map[StepKey]time.Time{
+		StepKeyBuild:  monday.AddDate(0, 0, 1),
+		StepKeyBisque: monday.AddDate(0, 0, 8),
+		StepKeyGlaze:  monday.AddDate(0, 0, 11),
+	}
+
+	bottleneck := findBottleneck(chain, starts)
+
+	assert.Equal(t, StepKeyBisque, bottleneck.OrderDetailStatus, "Bisque added four days of delay on top of the build's one")
+}
+
+func TestFindBottleneck_OnScheduleFallsBackToLastStep(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	chain := []TaskChainItem{
+		{OrderDetailStatus: StepKeyBuild, TaskType: TaskTypeBuildBase, StartDate: monday},
+		{OrderDetailStatus: StepKeyGlaze, TaskType: TaskTypeGlaze, StartDate: monday.AddDate(0, 0, 6)},
+	}
+
+	bottleneck := findBottleneck(chain, map[StepKey]time.Time{StepKeyBuild: monday})
+
+	assert.Equal(t, StepKeyGlaze, bottleneck.OrderDetailStatus)
+}
+
+func TestScheduler_RunReportsLateDeadlineOrders(t *testing.T) {
+	now := time.Now()
+	store := NewInMemoryTaskStore()
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(
+			newDeadlineOrder("rush-order", "rush-detail", PieceTypeMugWithHandle, 30, now.AddDate(0, 0, 3)),
+			newDeadlineOrder("relaxed-order", "relaxed-detail", PieceTypeTrinketDish, 2, now.AddDate(0, 0, 90)),
+		),
+		NewInMemoryAvailabilitySource(nil),
+		store,
+	)
+
+	result, err := scheduler.Run(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+	require.Len(t, result.Lateness, 1, "Only the rush order can't make its due date")
+
+	entry := result.Lateness[0]
+	assert.Equal(t, "rush-order", entry.OrderId)
+	assert.Equal(t, "rush-detail", entry.OrderDetailId)
+	assert.Equal(t, PieceTypeMugWithHandle, entry.PieceType)
+	assert.True(t, entry.ProjectedCompletion.After(entry.DueDate))
+	assert.Greater(t, entry.DaysLate, 0)
+	assert.NotEmpty(t, entry.BottleneckStep)
+	assert.NotEmpty(t, entry.BottleneckTaskType)
+
+	assert.Equal(t, result.Lateness, store.LatenessReports(), "The report is persisted alongside the tasks")
+}
+
+func TestScheduler_RunReplacesThePreviousLatenessReport(t *testing.T) {
+	now := time.Now()
+	store := NewInMemoryTaskStore()
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("rush-order", "rush-detail", PieceTypeMugWithHandle, 30, now.AddDate(0, 0, 3))),
+		NewInMemoryAvailabilitySource(nil),
+		store,
+	)
+
+	_, err := scheduler.Run(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+
+	result, err := scheduler.Run(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, result.Lateness, store.LatenessReports(), "Only the latest run's report is kept")
+}
+
+func TestScheduler_PreviewReportsLatenessWithoutPersisting(t *testing.T) {
+	now := time.Now()
+	store := NewInMemoryTaskStore()
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("rush-order", "rush-detail", PieceTypeMugWithHandle, 30, now.AddDate(0, 0, 3))),
+		NewInMemoryAvailabilitySource(nil),
+		store,
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+
+	assert.Len(t, preview.Lateness, 1)
+	assert.Empty(t, store.LatenessReports())
+}
+
+func TestPlanner_LatenessCountsStepsWaitingPastTheHorizon(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	endDate := monday.AddDate(0, 0, 4)
+
+	chain := []TaskChainItem{
+		{
+			OrderId:           "order-1",
+			OrderDetailId:     "detail-1",
+			OrderDetailStatus: StepKeyGlaze,
+			TaskType:          TaskTypeGlaze,
+			PieceType:         PieceTypeMugWithHandle,
+			Quantity:          4,
+			StartDate:         endDate.AddDate(0, 0, 2),
+			DueDate:           endDate.AddDate(0, 0, 1),
+			HasDeadline:       true,
+		},
+	}
+
+	p := newPlanner(monday, endDate, map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+	p.trackChain(chain)
+
+	remaining, _ := p.planDay(endDate, &DaySchedule{AvailableHours: 8}, chain)
+	require.Len(t, remaining, 1, "A step that can't start before the horizon ends is still outstanding")
+
+	report := p.latenessReport(remaining, monday)
+	require.Len(t, report, 1, "The glaze can't finish before the due date once it's counted")
+	assert.False(t, report[0].FullyScheduled)
+	assert.Equal(t, StepKeyGlaze, report[0].BottleneckStep)
+}
+
+func newPromisedOrder(orderID, detailID string, quantity int, dueDate *time.Time, promisedDate time.Time) orders.OrderDTO {
+	order := newDeadlineOrder(orderID, detailID, PieceTypeMugWithHandle, quantity, promisedDate)
+	bulkCodeID := "bulk-" + orderID
+
+	order.DueDate = dueDate
+	order.PromisedDate = &promisedDate
+	order.BulkCodeID = &bulkCodeID
+
+	return order
+}
+
+func TestScheduler_PlansBulkOrdersAgainstTheirPromisedDate(t *testing.T) {
+	now := time.Now()
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newPromisedOrder("bulk-order", "bulk-detail", 30, nil, now.AddDate(0, 0, 3))),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+	require.Len(t, preview.Lateness, 1, "Without a due date the order is still held to its promise")
+
+	entry := preview.Lateness[0]
+	assert.Equal(t, "bulk-bulk-order", entry.BulkCodeId)
+	require.NotNil(t, entry.PromisedDate)
+	assert.Equal(t, entry.DueDate, *entry.PromisedDate)
+	assert.Greater(t, entry.DaysLate, 0)
+	assert.Equal(t, entry.DaysLate, entry.DaysPastPromise)
+}
+
+func TestScheduler_ReportsBrokenPromiseAfterDueDateMoves(t *testing.T) {
+	now := time.Now()
+	dueDate := now.AddDate(0, 0, 90)
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newPromisedOrder("bulk-order", "bulk-detail", 30, &dueDate, now.AddDate(0, 0, 3))),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+	require.Len(t, preview.Lateness, 1, "The order makes its new due date but not the date the code promised")
+
+	entry := preview.Lateness[0]
+	assert.Equal(t, 0, entry.DaysLate)
+	assert.Greater(t, entry.DaysPastPromise, 0)
+	assert.True(t, entry.ProjectedCompletion.After(*entry.PromisedDate))
+}
@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/availability"
+	"aliciapceramics/legacy/server/orders"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type InMemoryOrderSource struct {
+	Orders []orders.OrderDTO
+}
+
+func NewInMemoryOrderSource(orderList ...orders.OrderDTO) *InMemoryOrderSource {
+	return &InMemoryOrderSource{Orders: orderList}
+}
+
+func (s *InMemoryOrderSource) GetOrdersWithDeadlines() (orders.OrdersDTO, error) {
+	result := orders.OrdersDTO{Orders: []orders.OrderDTO{}}
+
+	for _, order := range s.Orders {
+		if order.DueDate != nil && isOpenOrderStatus(order.Status) {
+			result.Orders = append(result.Orders, order)
+		}
+	}
+
+	sort.SliceStable(result.Orders, func(i, j int) bool {
+		return result.Orders[i].DueDate.Before(*result.Orders[j].DueDate)
+	})
+
+	return result, nil
+}
+
+func (s *InMemoryOrderSource) GetNonDeadlineOrders() (orders.OrdersDTO, error) {
+	result := orders.OrdersDTO{Orders: []orders.OrderDTO{}}
+
+	for _, order := range s.Orders {
+		if order.DueDate == nil && isOpenOrderStatus(order.Status) {
+			result.Orders = append(result.Orders, order)
+		}
+	}
+
+	return result, nil
+}
+
+func isOpenOrderStatus(status string) bool {
+	return status != "delivered" && status != "cancelled" && status != "completed"
+}
+
+type InMemoryAvailabilitySource struct {
+	Hours map[string]float64
+}
+
+func NewInMemoryAvailabilitySource(hours map[string]float64) *InMemoryAvailabilitySource {
+	if hours == nil {
+		hours = map[string]float64{}
+	}
+
+	return &InMemoryAvailabilitySource{Hours: hours}
+}
+
+func (s *InMemoryAvailabilitySource) GetAvailability(startDate, endDate string) ([]availability.AvailabilityDTO, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("[InMemoryAvailabilitySource:GetAvailability] invalid start date: %w", err)
+	}
+
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("[InMemoryAvailabilitySource:GetAvailability] invalid end date: %w", err)
+	}
+
+	result := []availability.AvailabilityDTO{}
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		dateStr := d.Format("2006-01-02")
+
+		if hours, exists := s.Hours[dateStr]; exists {
+			result = append(result, availability.AvailabilityDTO{
+				Date:           dateStr,
+				AvailableHours: hours,
+				IsDefault:      false,
+			})
+		} else {
+			result = append(result, availability.AvailabilityDTO{
+				Date:           dateStr,
+				AvailableHours: availability.DefaultWeeklySchedule[d.Weekday()],
+				IsDefault:      true,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+type InMemoryTaskStore struct {
+	mu        sync.Mutex
+	tasks     []TaskToCreate
+	lateness  []LatenessEntry
+	snapshots []Snapshot
+	completed []CompletedTask
+}
+
+func NewInMemoryTaskStore() *InMemoryTaskStore {
+	return &InMemoryTaskStore{tasks: []TaskToCreate{}, lateness: []LatenessEntry{}}
+}
+
+func (s *InMemoryTaskStore) DeletePendingTasks() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = []TaskToCreate{}
+
+	return nil
+}
+
+func (s *InMemoryTaskStore) InsertTasks(tasks []TaskToCreate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = append(s.tasks, tasks...)
+
+	return nil
+}
+
+func (s *InMemoryTaskStore) Tasks() []TaskToCreate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]TaskToCreate{}, s.tasks...)
+}
+
+func (s *InMemoryTaskStore) DeleteLatenessReports() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lateness = []LatenessEntry{}
+
+	return nil
+}
+
+func (s *InMemoryTaskStore) InsertLatenessReport(entries []LatenessEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lateness = append(s.lateness, entries...)
+
+	return nil
+}
+
+func (s *InMemoryTaskStore) LatenessReports() []LatenessEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]LatenessEntry{}, s.lateness...)
+}
+
+func (s *InMemoryTaskStore) InsertSnapshot(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots = append(s.snapshots, snapshot)
+
+	return nil
+}
+
+func (s *InMemoryTaskStore) Snapshots() []Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Snapshot{}, s.snapshots...)
+}
+
+func (s *InMemoryTaskStore) AddCompletedTasks(tasks ...CompletedTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed = append(s.completed, tasks...)
+}
+
+func (s *InMemoryTaskStore) GetCompletedTasks(since time.Time) ([]CompletedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := []CompletedTask{}
+	for _, task := range s.completed {
+		if !task.CompletedAt.Before(since) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
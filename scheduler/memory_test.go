@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryOrderSource_SplitsByDeadline(t *testing.T) {
+	early := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	source := NewInMemoryOrderSource(
+		orders.OrderDTO{ID: "late", Status: "pending", DueDate: &late},
+		orders.OrderDTO{ID: "no-deadline", Status: "pending"},
+		orders.OrderDTO{ID: "early", Status: "building", DueDate: &early},
+		orders.OrderDTO{ID: "cancelled", Status: "cancelled", DueDate: &early},
+		orders.OrderDTO{ID: "delivered", Status: "delivered"},
+	)
+
+	deadlineOrders, err := source.GetOrdersWithDeadlines()
+	require.NoError(t, err)
+	require.Len(t, deadlineOrders.Orders, 2)
+	assert.Equal(t, "early", deadlineOrders.Orders[0].ID, "Deadline orders should be sorted by due date")
+	assert.Equal(t, "late", deadlineOrders.Orders[1].ID)
+
+	nonDeadlineOrders, err := source.GetNonDeadlineOrders()
+	require.NoError(t, err)
+	require.Len(t, nonDeadlineOrders.Orders, 1)
+	assert.Equal(t, "no-deadline", nonDeadlineOrders.Orders[0].ID)
+}
+
+func TestInMemoryAvailabilitySource_FallsBackToWeeklySchedule(t *testing.T) {
+	source := NewInMemoryAvailabilitySource(map[string]float64{
+		"2025-10-21": 6.0,
+	})
+
+	days, err := source.GetAvailability("2025-10-20", "2025-10-22")
+	require.NoError(t, err)
+	require.Len(t, days, 3)
+
+	assert.InDelta(t, 4.0, days[0].AvailableHours, 0.001, "Monday should use the default schedule")
+	assert.True(t, days[0].IsDefault)
+
+	assert.InDelta(t, 6.0, days[1].AvailableHours, 0.001, "Tuesday should use the override")
+	assert.False(t, days[1].IsDefault)
+
+	assert.InDelta(t, 2.0, days[2].AvailableHours, 0.001)
+}
+
+func TestInMemoryAvailabilitySource_InvalidDate(t *testing.T) {
+	source := NewInMemoryAvailabilitySource(nil)
+
+	_, err := source.GetAvailability("not-a-date", "2025-10-22")
+	assert.Error(t, err)
+}
+
+func TestInMemoryTaskStore_DeleteAndInsert(t *testing.T) {
+	store := NewInMemoryTaskStore()
+
+	require.NoError(t, store.InsertTasks([]TaskToCreate{{OrderDetailId: "old", TaskType: TaskTypeTrim, Quantity: 1}}))
+	require.NoError(t, store.DeletePendingTasks())
+	require.NoError(t, store.InsertTasks([]TaskToCreate{{OrderDetailId: "new", TaskType: TaskTypeBuildBase, Quantity: 2}}))
+
+	tasks := store.Tasks()
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "new", tasks[0].OrderDetailId)
+}
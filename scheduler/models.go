@@ -1,6 +1,9 @@
 package scheduler
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type OrderDB struct {
 	ID              string          `json:"id,omitempty"`
@@ -38,35 +41,255 @@ type TaskDB struct {
 	Status         string     `json:"status"`
 	IsLate         bool       `json:"is_late"`
 	CompletedAt    *time.Time `json:"completed_at"`
+	ActualHours    *float64   `json:"actual_hours"`
 }
 
 type TaskToCreate struct {
 	OrderDetailId  string    `json:"order_detail_id"`
+	Component      string    `json:"component,omitempty"`
+	StepKey        StepKey   `json:"step_key,omitempty"`
 	Date           time.Time `json:"date"`
 	TaskType       TaskType  `json:"task_type"`
 	Quantity       int       `json:"quantity"`
 	EstimatedHours float64   `json:"estimated_hours"`
 	IsLate         bool      `json:"is_late"`
+	KilnLoadId     string    `json:"kiln_load_id,omitempty"`
+	Glaze          string    `json:"glaze,omitempty"`
+	DefectReportId string    `json:"defect_report_id,omitempty"`
+	CatalogVersion string    `json:"catalog_version,omitempty"`
 }
 
 type DaySchedule struct {
-	Weekday        time.Weekday
-	Tasks          []TaskToCreate
-	Mode           StepKey
-	AvailableHours float64
+	Weekday         time.Weekday
+	Tasks           []TaskToCreate
+	Mode            StepKey
+	Modes           []StepKey
+	ChangeoverHours float64
+	Glazes          []string
+	AvailableHours  float64
 }
 
 type WeekSchedule map[time.Time]*DaySchedule
 
+func (w WeekSchedule) Days() []time.Time {
+	days := make([]time.Time, 0, len(w))
+
+	for day := range w {
+		days = append(days, day)
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	return days
+}
+
+func (w WeekSchedule) AllTasks() []TaskToCreate {
+	tasks := []TaskToCreate{}
+
+	for _, day := range w.Days() {
+		tasks = append(tasks, w[day].Tasks...)
+	}
+
+	return tasks
+}
+
+type KilnLoadItem struct {
+	OrderDetailId    string    `json:"order_detail_id"`
+	Component        string    `json:"component,omitempty"`
+	PieceType        PieceType `json:"piece_type"`
+	Quantity         int       `json:"quantity"`
+	PieceEquivalents float64   `json:"piece_equivalents"`
+}
+
+type KilnLoad struct {
+	ID             string         `json:"id"`
+	Date           time.Time      `json:"date"`
+	FiringType     TaskType       `json:"firing_type"`
+	CapacityPieces float64        `json:"capacity_pieces"`
+	LoadedPieces   float64        `json:"loaded_pieces"`
+	Forced         bool           `json:"forced"`
+	Items          []KilnLoadItem `json:"items"`
+}
+
 type TaskChainItem struct {
 	TaskType          TaskType
 	PieceType         PieceType
 	StartDate         time.Time
+	OrderId           string
 	OrderDetailId     string
+	Component         string
+	Process           PieceType
 	OrderDetailStatus StepKey
 	Quantity          int
+	DueDate           time.Time
+	HasDeadline       bool
+	PromisedDate      time.Time
+	BulkCodeId        string
+	WaitingSince      time.Time
+	Glaze             string
+	DefectReportId    string
+
+	// After lists the chain's steps this one waits for. It is nil when the
+	// process is linear and every step waits for the one before it.
+	After []StepKey
+
+	// Eager work starts as soon as its pieces are ready rather than waiting
+	// for StartDate. StartDate still sets its priority.
+	Eager bool
+}
+
+type DayPreview struct {
+	Date            string         `json:"date"`
+	Weekday         string         `json:"weekday"`
+	Mode            StepKey        `json:"mode"`
+	Modes           []StepKey      `json:"modes"`
+	ChangeoverHours float64        `json:"changeover_hours"`
+	Glazes          []string       `json:"glazes,omitempty"`
+	AvailableHours  float64        `json:"available_hours"`
+	Tasks           []TaskToCreate `json:"tasks"`
+}
+
+type SchedulePreview struct {
+	StartDate  string          `json:"start_date"`
+	EndDate    string          `json:"end_date"`
+	TotalTasks int             `json:"total_tasks"`
+	Days       []DayPreview    `json:"days"`
+	KilnLoads  []KilnLoad      `json:"kiln_loads"`
+	Lateness   []LatenessEntry `json:"lateness"`
+	Overtime   *OvertimePlan   `json:"overtime,omitempty"`
+
+	CatalogVersion string `json:"catalog_version"`
+}
+
+type LatenessEntry struct {
+	OrderId             string    `json:"order_id"`
+	OrderDetailId       string    `json:"order_detail_id"`
+	Component           string    `json:"component,omitempty"`
+	DefectReportId      string    `json:"defect_report_id,omitempty"`
+	PieceType           PieceType `json:"piece_type"`
+	DueDate             time.Time `json:"due_date"`
+	ProjectedCompletion time.Time `json:"projected_completion"`
+	DaysLate            int       `json:"days_late"`
+	BottleneckStep      StepKey   `json:"bottleneck_step"`
+	BottleneckTaskType  TaskType  `json:"bottleneck_task_type"`
+	FullyScheduled      bool      `json:"fully_scheduled"`
+	ReportedAt          time.Time `json:"reported_at"`
+	CatalogVersion      string    `json:"catalog_version,omitempty"`
+
+	// PromisedDate is the earliest completion date of the bulk code the order
+	// was placed with, which still stands if the due date is moved
+	BulkCodeId      string     `json:"bulk_code_id,omitempty"`
+	PromisedDate    *time.Time `json:"promised_date,omitempty"`
+	DaysPastPromise int        `json:"days_past_promise,omitempty"`
+}
+
+type ReplayResult struct {
+	Preview    SchedulePreview  `json:"preview"`
+	Comparison []TaskComparison `json:"comparison"`
+}
+
+// TaskComparison is one order detail's task on one day, as planned by the
+// replay and as it was actually completed
+type TaskComparison struct {
+	Date            string   `json:"date"`
+	OrderDetailId   string   `json:"order_detail_id"`
+	TaskType        TaskType `json:"task_type"`
+	PlannedQuantity int      `json:"planned_quantity"`
+	ActualQuantity  int      `json:"actual_quantity"`
+	PlannedHours    float64  `json:"planned_hours"`
+	ActualHours     float64  `json:"actual_hours"`
 }
 
 type SchedulerResult struct {
-	Success bool `json:"success"`
+	Success      bool            `json:"success"`
+	TasksCreated int             `json:"tasks_created"`
+	Lateness     []LatenessEntry `json:"lateness"`
+	Overtime     *OvertimePlan   `json:"overtime,omitempty"`
+
+	CatalogVersion string `json:"catalog_version"`
+}
+
+type CompletionForecast struct {
+	OrderId           string     `json:"order_id"`
+	OrderDetailId     string     `json:"order_detail_id"`
+	Component         string     `json:"component,omitempty"`
+	PieceType         PieceType  `json:"piece_type"`
+	DueDate           *time.Time `json:"due_date,omitempty"`
+	P50               time.Time  `json:"p50"`
+	P80               time.Time  `json:"p80"`
+	P95               time.Time  `json:"p95"`
+	OnTimeProbability *float64   `json:"on_time_probability,omitempty"`
+}
+
+type SimulationResult struct {
+	Runs      int                  `json:"runs"`
+	StartDate string               `json:"start_date"`
+	EndDate   string               `json:"end_date"`
+	Forecasts []CompletionForecast `json:"forecasts"`
+
+	CatalogVersion string `json:"catalog_version"`
+}
+
+type CompletionQuote struct {
+	Date           time.Time `json:"date"`
+	CatalogVersion string    `json:"catalog_version"`
+}
+
+type DetailQuote struct {
+	OrderDetailId  string    `json:"order_detail_id"`
+	PieceType      PieceType `json:"piece_type"`
+	Quantity       int       `json:"quantity"`
+	Date           time.Time `json:"date"`
+	FullyScheduled bool      `json:"fully_scheduled"`
+}
+
+type CapacityQuote struct {
+	Date           time.Time     `json:"date"`
+	Details        []DetailQuote `json:"details"`
+	FullyScheduled bool          `json:"fully_scheduled"`
+	CatalogVersion string        `json:"catalog_version"`
+}
+
+type OvertimeSuggestion struct {
+	Date           string   `json:"date"`
+	ExtraHours     float64  `json:"extra_hours"`
+	AvailableHours float64  `json:"available_hours"`
+	OrdersRescued  []string `json:"orders_rescued"`
+}
+
+type OvertimePlan struct {
+	Suggestions     []OvertimeSuggestion `json:"suggestions"`
+	TotalExtraHours float64              `json:"total_extra_hours"`
+	StillLate       []LatenessEntry      `json:"still_late"`
+
+	// Partial is set when the search ran out of simulations, so more
+	// overtime might recover more of the orders still late
+	Partial bool `json:"partial,omitempty"`
+}
+
+type RateCalibration struct {
+	PieceType      PieceType `json:"piece_type"`
+	TaskType       TaskType  `json:"task_type"`
+	Samples        int       `json:"samples"`
+	Outliers       int       `json:"outliers"`
+	EstimatedHours float64   `json:"estimated_hours"`
+	ActualHours    float64   `json:"actual_hours"`
+	DriftPercent   float64   `json:"drift_percent"`
+	CurrentRate    float64   `json:"current_rate"`
+	ObservedRate   float64   `json:"observed_rate"`
+	ProposedRate   float64   `json:"proposed_rate"`
+	Changed        bool      `json:"changed"`
+	Note           string    `json:"note,omitempty"`
+}
+
+type CalibrationReport struct {
+	GeneratedAt     time.Time         `json:"generated_at"`
+	Since           time.Time         `json:"since"`
+	CatalogVersion  string            `json:"catalog_version"`
+	ProposedVersion string            `json:"proposed_version,omitempty"`
+	Applied         bool              `json:"applied"`
+	Rates           []RateCalibration `json:"rates"`
+	Catalog         *ProcessCatalog   `json:"catalog,omitempty"`
 }
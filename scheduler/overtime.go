@@ -0,0 +1,285 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/availability"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxOvertimeSimulations bounds the planner runs spent searching for overtime.
+// Once it's spent the suggestions found so far are returned as a partial plan.
+const MaxOvertimeSimulations = 400
+
+type OvertimeOptions struct {
+	MaxHoursPerDay float64
+	IncrementHours float64
+	IncludeDaysOff bool
+	MaxSimulations int
+}
+
+var DefaultOvertime = OvertimeOptions{
+	MaxHoursPerDay: 10,
+	IncrementHours: 1,
+	IncludeDaysOff: false,
+	MaxSimulations: MaxOvertimeSimulations,
+}
+
+type overtimeMove struct {
+	date        string
+	hours       float64
+	improvement float64
+	daysLate    int
+	lateness    []LatenessEntry
+}
+
+// overtimeSearch counts every planner run against maxSimulations, and keeps
+// the lateness of the overtime accepted so far so it needn't be planned again
+type overtimeSearch struct {
+	planningInput
+	simulations    int
+	maxSimulations int
+	lateness       []LatenessEntry
+}
+
+func (s *overtimeSearch) simulate(extraHours map[string]float64) ([]LatenessEntry, bool) {
+	if s.simulations >= s.maxSimulations {
+		return nil, false
+	}
+
+	s.simulations++
+	return s.simulateLateness(extraHours), true
+}
+
+func (in planningInput) withExtraHours(extraHours map[string]float64) planningInput {
+	capacityByDate := make(map[string]float64, len(in.capacityByDate))
+	for date, hours := range in.capacityByDate {
+		capacityByDate[date] = hours + extraHours[date]
+	}
+
+	in.capacityByDate = capacityByDate
+	return in
+}
+
+func (in planningInput) simulateLateness(extraHours map[string]float64) []LatenessEntry {
+	planner, remaining := in.withExtraHours(extraHours).run()
+	return planner.latenessReport(remaining, in.now)
+}
+
+func (in planningInput) suggestOvertime(options OvertimeOptions, lateness []LatenessEntry) OvertimePlan {
+	if options.IncrementHours <= 0 {
+		options.IncrementHours = DefaultOvertime.IncrementHours
+	}
+
+	maxSimulations := MaxOvertimeSimulations
+	if options.MaxSimulations > 0 {
+		maxSimulations = min(options.MaxSimulations, MaxOvertimeSimulations)
+	}
+
+	search := &overtimeSearch{planningInput: in, maxSimulations: maxSimulations, lateness: lateness}
+	extraHours := make(map[string]float64)
+	current := totalDaysLate(lateness)
+	exhausted := false
+
+	// Greedily add the overtime that recovers the most days late per extra hour.
+	// Topping a day up to the maximum is also tried, since a single increment
+	// often isn't enough to finish a step a day sooner.
+	for current > 0 && !exhausted {
+		best := overtimeMove{}
+
+	dates:
+		for _, date := range in.overtimeDates(options) {
+			headroom := options.MaxHoursPerDay - in.capacityByDate[date] - extraHours[date]
+			if headroom <= 1e-9 {
+				continue
+			}
+
+			for _, hours := range []float64{math.Min(options.IncrementHours, headroom), headroom} {
+				extraHours[date] += hours
+				simulated, ok := search.simulate(extraHours)
+				extraHours[date] -= hours
+
+				if !ok {
+					exhausted = true
+					break dates
+				}
+
+				daysLate := totalDaysLate(simulated)
+				improvement := float64(current-daysLate) / hours
+				if improvement > best.improvement+1e-9 {
+					best = overtimeMove{date: date, hours: hours, improvement: improvement, daysLate: daysLate, lateness: simulated}
+				}
+			}
+		}
+
+		if best.date == "" {
+			break
+		}
+
+		extraHours[best.date] += best.hours
+		current = best.daysLate
+		search.lateness = best.lateness
+	}
+
+	exhausted = !search.trimOvertime(options, extraHours, current) || exhausted
+
+	suggestions, complete := search.overtimeSuggestions(extraHours)
+
+	return OvertimePlan{
+		Suggestions:     suggestions,
+		TotalExtraHours: sumHours(extraHours),
+		StillLate:       search.lateness,
+		Partial:         exhausted || !complete,
+	}
+}
+
+func (in planningInput) overtimeDates(options OvertimeOptions) []string {
+	dates := []string{}
+
+	for day := in.startDate; !day.After(in.endDate); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+
+		// Overtime can't be worked while the studio is closed
+		if in.calendar != nil && !in.calendar.IsWorkDay(day) {
+			continue
+		}
+
+		if in.capacityByDate[date] <= 0 && !options.IncludeDaysOff {
+			continue
+		}
+
+		dates = append(dates, date)
+	}
+
+	return dates
+}
+
+// trimOvertime reports false when it ran out of simulations before every day
+// was trimmed
+func (s *overtimeSearch) trimOvertime(options OvertimeOptions, extraHours map[string]float64, daysLate int) bool {
+	dates := make([]string, 0, len(extraHours))
+	for date := range extraHours {
+		dates = append(dates, date)
+	}
+
+	// Later days are trimmed first so the suggestions favour recovering early
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+
+	for _, date := range dates {
+		for extraHours[date] > 1e-9 {
+			hours := math.Min(options.IncrementHours, extraHours[date])
+
+			extraHours[date] -= hours
+			simulated, ok := s.simulate(extraHours)
+			if !ok || totalDaysLate(simulated) > daysLate {
+				extraHours[date] += hours
+
+				if !ok {
+					return false
+				}
+				break
+			}
+
+			s.lateness = simulated
+		}
+
+		if extraHours[date] <= 1e-9 {
+			delete(extraHours, date)
+		}
+	}
+
+	return true
+}
+
+// overtimeSuggestions reports false when it ran out of simulations, leaving
+// the orders rescued by the remaining days unknown
+func (s *overtimeSearch) overtimeSuggestions(extraHours map[string]float64) ([]OvertimeSuggestion, bool) {
+	suggestions := []OvertimeSuggestion{}
+	lateWithOvertime := lateOrders(s.lateness)
+	complete := true
+
+	for _, date := range s.overtimeDates(OvertimeOptions{IncludeDaysOff: true}) {
+		hours, exists := extraHours[date]
+		if !exists {
+			continue
+		}
+
+		withoutDate := make(map[string]float64, len(extraHours))
+		for otherDate, otherHours := range extraHours {
+			if otherDate != date {
+				withoutDate[otherDate] = otherHours
+			}
+		}
+
+		rescued := []string{}
+		if complete {
+			simulated, ok := s.simulate(withoutDate)
+			complete = ok
+
+			for orderId := range lateOrders(simulated) {
+				if !lateWithOvertime[orderId] {
+					rescued = append(rescued, orderId)
+				}
+			}
+			sort.Strings(rescued)
+		}
+
+		suggestions = append(suggestions, OvertimeSuggestion{
+			Date:           date,
+			ExtraHours:     hours,
+			AvailableHours: s.capacityByDate[date] + hours,
+			OrdersRescued:  rescued,
+		})
+	}
+
+	return suggestions, complete
+}
+
+func (p OvertimePlan) AvailabilityUpdates() []availability.UpdateAvailabilityItem {
+	updates := []availability.UpdateAvailabilityItem{}
+
+	for _, suggestion := range p.Suggestions {
+		notes := fmt.Sprintf("Overtime: +%.1fh", suggestion.ExtraHours)
+		if len(suggestion.OrdersRescued) > 0 {
+			notes += " for orders " + strings.Join(suggestion.OrdersRescued, ", ")
+		}
+
+		updates = append(updates, availability.UpdateAvailabilityItem{
+			Date:           suggestion.Date,
+			AvailableHours: suggestion.AvailableHours,
+			Notes:          &notes,
+		})
+	}
+
+	return updates
+}
+
+// totalDaysLate counts days past bulk-code promises as well as due dates, so
+// overtime that keeps a promise counts as a gain
+func totalDaysLate(lateness []LatenessEntry) int {
+	total := 0
+	for _, entry := range lateness {
+		total += entry.DaysLate + entry.DaysPastPromise
+	}
+
+	return total
+}
+
+func lateOrders(lateness []LatenessEntry) map[string]bool {
+	orderIds := make(map[string]bool)
+	for _, entry := range lateness {
+		orderIds[entry.OrderId] = true
+	}
+
+	return orderIds
+}
+
+func sumHours(extraHours map[string]float64) float64 {
+	total := 0.0
+	for _, hours := range extraHours {
+		total += hours
+	}
+
+	return total
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func flatAvailability(horizonWeeks int, hours float64) map[string]float64 {
+	startDate, endDate := getPlanningWindow(time.Now(), horizonWeeks)
+
+	availableHours := map[string]float64{}
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		availableHours[day.Format("2006-01-02")] = hours
+	}
+
+	return availableHours
+}
+
+func newOvertimeScheduler(quantity int, dueInDays int) *Scheduler {
+	return NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("rush-order", "rush-detail", PieceTypeMugWithoutHandle, quantity, time.Now().AddDate(0, 0, dueInDays))),
+		NewInMemoryAvailabilitySource(flatAvailability(4, 4)),
+		NewInMemoryTaskStore(),
+	)
+}
+
+func TestScheduler_OvertimeRecoversLateOrder(t *testing.T) {
+	options := DefaultOvertime
+
+	preview, err := newOvertimeScheduler(20, 25).Preview(RunOptions{HorizonWeeks: 4, Overtime: &options})
+	require.NoError(t, err)
+	require.NotEmpty(t, preview.Lateness)
+	require.NotNil(t, preview.Overtime)
+	require.NotEmpty(t, preview.Overtime.Suggestions)
+
+	assert.Less(t, totalDaysLate(preview.Overtime.StillLate), totalDaysLate(preview.Lateness), "Overtime should reduce the total days late")
+
+	total := 0.0
+	for _, suggestion := range preview.Overtime.Suggestions {
+		assert.Greater(t, suggestion.ExtraHours, 0.0)
+		assert.LessOrEqual(t, suggestion.AvailableHours, options.MaxHoursPerDay, "Suggestions must respect the daily maximum")
+		assert.InDelta(t, 4+suggestion.ExtraHours, suggestion.AvailableHours, 0.001)
+		total += suggestion.ExtraHours
+	}
+	assert.InDelta(t, total, preview.Overtime.TotalExtraHours, 0.001)
+
+	if len(preview.Overtime.StillLate) == 0 {
+		rescued := []string{}
+		for _, suggestion := range preview.Overtime.Suggestions {
+			rescued = append(rescued, suggestion.OrdersRescued...)
+		}
+		assert.Contains(t, rescued, "rush-order")
+	}
+}
+
+func TestScheduler_OvertimeRespectsDailyMaximum(t *testing.T) {
+	options := OvertimeOptions{MaxHoursPerDay: 4, IncrementHours: 1}
+
+	preview, err := newOvertimeScheduler(20, 25).Preview(RunOptions{HorizonWeeks: 4, Overtime: &options})
+	require.NoError(t, err)
+	require.NotNil(t, preview.Overtime)
+
+	assert.Empty(t, preview.Overtime.Suggestions, "No day has headroom below the maximum")
+	assert.Equal(t, totalDaysLate(preview.Lateness), totalDaysLate(preview.Overtime.StillLate))
+}
+
+func TestScheduler_OvertimeOnlyWhenRequested(t *testing.T) {
+	preview, err := newOvertimeScheduler(20, 25).Preview(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, preview.Lateness)
+	assert.Nil(t, preview.Overtime)
+}
+
+func TestOvertimePlan_AvailabilityUpdates(t *testing.T) {
+	plan := OvertimePlan{
+		Suggestions: []OvertimeSuggestion{
+			{Date: "2025-10-21", ExtraHours: 2, AvailableHours: 6, OrdersRescued: []string{"order-1", "order-2"}},
+			{Date: "2025-10-23", ExtraHours: 1.5, AvailableHours: 9.5, OrdersRescued: []string{}},
+		},
+	}
+
+	updates := plan.AvailabilityUpdates()
+	require.Len(t, updates, 2)
+
+	assert.Equal(t, "2025-10-21", updates[0].Date)
+	assert.Equal(t, 6.0, updates[0].AvailableHours, "Updates carry the new total, not the extra hours")
+	require.NotNil(t, updates[0].Notes)
+	assert.Equal(t, "Overtime: +2.0h for orders order-1, order-2", *updates[0].Notes)
+
+	assert.Equal(t, 9.5, updates[1].AvailableHours)
+	assert.Equal(t, "Overtime: +1.5h", *updates[1].Notes)
+}
+
+func TestScheduler_OvertimeSearchStopsAtTheSimulationCap(t *testing.T) {
+	options := DefaultOvertime
+	options.MaxSimulations = 10
+
+	preview, err := newOvertimeScheduler(20, 20).Preview(RunOptions{HorizonWeeks: 4, Overtime: &options})
+	require.NoError(t, err)
+	require.NotNil(t, preview.Overtime)
+
+	assert.True(t, preview.Overtime.Partial, "Ten simulations can't cover every day in the horizon")
+	assert.LessOrEqual(t, totalDaysLate(preview.Overtime.StillLate), totalDaysLate(preview.Lateness), "A partial plan never makes things worse")
+}
+
+func TestTotalDaysLate_CountsBrokenPromises(t *testing.T) {
+	lateness := []LatenessEntry{
+		{OrderId: "late-order", DaysLate: 3},
+		{OrderId: "bulk-order", DaysLate: 0, DaysPastPromise: 2},
+		{OrderId: "late-bulk-order", DaysLate: 1, DaysPastPromise: 4},
+	}
+
+	assert.Equal(t, 10, totalDaysLate(lateness), "Overtime that keeps a promise is a gain even when the due date is met")
+}
+
+func TestOvertimeSearch_SuggestionsCountAgainstTheCap(t *testing.T) {
+	scheduler := newOvertimeScheduler(20, 20)
+
+	input, err := scheduler.planningInput(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+
+	search := &overtimeSearch{planningInput: input, maxSimulations: 1, lateness: input.simulateLateness(nil)}
+	dates := input.overtimeDates(DefaultOvertime)
+	require.GreaterOrEqual(t, len(dates), 2)
+
+	suggestions, complete := search.overtimeSuggestions(map[string]float64{dates[0]: 1, dates[1]: 1})
+	assert.False(t, complete, "Working out the orders each day rescues needs a simulation per day")
+	assert.Equal(t, 1, search.simulations, "The search never runs the planner past its cap")
+	assert.Len(t, suggestions, 2, "Days are still suggested once the cap is reached")
+}
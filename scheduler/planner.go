@@ -0,0 +1,252 @@
+package scheduler
+
+import "time"
+
+type planner struct {
+	startDate      time.Time
+	endDate        time.Time
+	capacityByDate map[string]float64
+	schedule       WeekSchedule
+	remaining      map[string]map[StepKey]bool
+	predecessors   map[string]map[StepKey][]StepKey
+	lastCompletion map[string]time.Time
+	stepCompletion map[string]map[StepKey]time.Time
+	kiln           KilnConfig
+	kilnLoads      []KilnLoad
+	priority       PriorityOptions
+	changeover     ChangeoverOptions
+	process        *ProcessCatalog
+	calendar       *Calendar
+	chains         map[string][]TaskChainItem
+	stepStarts     map[string]map[StepKey]time.Time
+}
+
+func newPlanner(startDate, endDate time.Time, capacityByDate map[string]float64, kiln KilnConfig, priority PriorityOptions, changeover ChangeoverOptions) *planner {
+	return &planner{
+		startDate:      startDate,
+		endDate:        endDate,
+		capacityByDate: capacityByDate,
+		schedule:       make(WeekSchedule),
+		remaining:      make(map[string]map[StepKey]bool),
+		predecessors:   make(map[string]map[StepKey][]StepKey),
+		lastCompletion: make(map[string]time.Time),
+		stepCompletion: make(map[string]map[StepKey]time.Time),
+		kiln:           kiln,
+		kilnLoads:      []KilnLoad{},
+		priority:       priority,
+		changeover:     changeover,
+		process:        CurrentProcessCatalog(),
+		calendar:       CurrentCalendar(),
+		chains:         make(map[string][]TaskChainItem),
+		stepStarts:     make(map[string]map[StepKey]time.Time),
+	}
+}
+
+// capacityFor is the day's available hours, or none when the studio is closed
+// whatever availability says
+func (p *planner) capacityFor(day time.Time) float64 {
+	if p.calendar != nil && !p.calendar.IsWorkDay(day) {
+		return 0
+	}
+
+	return p.capacityByDate[day.Format("2006-01-02")]
+}
+
+func (p *planner) trackChain(tasks []TaskChainItem) {
+	if len(tasks) == 0 {
+		return
+	}
+
+	chainKey := tasks[0].chainKey()
+	p.chains[chainKey] = append([]TaskChainItem{}, tasks...)
+	p.remaining[chainKey] = make(map[StepKey]bool)
+	p.predecessors[chainKey] = make(map[StepKey][]StepKey)
+
+	for i, task := range tasks {
+		p.remaining[chainKey][task.OrderDetailStatus] = true
+
+		if task.After != nil {
+			p.predecessors[chainKey][task.OrderDetailStatus] = task.After
+		} else if i > 0 {
+			p.predecessors[chainKey][task.OrderDetailStatus] = []StepKey{tasks[i-1].OrderDetailStatus}
+		}
+	}
+}
+
+func (p *planner) markStarted(task TaskChainItem, day time.Time) {
+	starts, exists := p.stepStarts[task.chainKey()]
+	if !exists {
+		starts = make(map[StepKey]time.Time)
+		p.stepStarts[task.chainKey()] = starts
+	}
+
+	if _, started := starts[task.OrderDetailStatus]; !started {
+		starts[task.OrderDetailStatus] = day
+	}
+}
+
+func (p *planner) earliestStart(task TaskChainItem) (time.Time, bool) {
+	readyAt, ready := p.readyAt(task)
+	if !ready {
+		return time.Time{}, false
+	}
+
+	if task.StartDate.After(readyAt) && !task.Eager {
+		return task.StartDate, true
+	}
+
+	return readyAt, true
+}
+
+// readyAt is when a step's pieces are ready for it once every step it waits
+// for is finished. Work already done on the step has to be finished too.
+func (p *planner) readyAt(task TaskChainItem) (time.Time, bool) {
+	chainKey := task.chainKey()
+	if !p.remaining[chainKey][task.OrderDetailStatus] {
+		return time.Time{}, false
+	}
+
+	predecessors := p.predecessors[chainKey][task.OrderDetailStatus]
+	for _, stepKey := range predecessors {
+		if p.remaining[chainKey][stepKey] {
+			return time.Time{}, false
+		}
+	}
+
+	var readyAt time.Time
+	for _, stepKey := range append([]StepKey{task.OrderDetailStatus}, predecessors...) {
+		if completion, exists := p.stepCompletion[chainKey][stepKey]; exists && completion.After(readyAt) {
+			readyAt = completion
+		}
+	}
+
+	return readyAt, true
+}
+
+func (p *planner) planDay(day time.Time, daySchedule *DaySchedule, tasks []TaskChainItem) ([]TaskChainItem, bool) {
+	anyTaskScheduled := false
+
+	for i := 0; i < len(tasks); i++ {
+		task := tasks[i]
+
+		earliestPossibleStart, isCurrentStep := p.earliestStart(task)
+		if !isCurrentStep {
+			continue
+		}
+
+		if earliestPossibleStart.After(day) {
+			readyAt, _ := p.readyAt(task)
+			if readyAt.After(day) || !p.joinsGlazeBatch(daySchedule, task) {
+				continue
+			}
+		}
+
+		// Bisque and glaze firings are batched into shared kiln loads below
+		if isExternalProcess(task.TaskType) {
+			continue
+		}
+
+		if daySchedule.AvailableHours <= 0 {
+			break
+		}
+
+		// Switching to another step costs setup time, so a new mode is only
+		// opened while the day has room for it and the work it unlocks
+		changeover, canSwitch := p.changeoverCost(daySchedule, task.OrderDetailStatus)
+		if !canSwitch {
+			continue
+		}
+
+		setup := p.daySetup(daySchedule, task)
+
+		hoursAvailable := daySchedule.AvailableHours - changeover - setup
+		if hoursAvailable <= 0 {
+			continue
+		}
+
+		// A process can repeat a task type, as trim and trim_final do, so the
+		// rate comes from the task's own step
+		step, _ := p.process.stepByKey(task.OrderDetailStatus, task.processKey())
+
+		piecesForDay := min(stepQuantity(p.process, hoursAvailable, step), task.Quantity)
+		hoursUsed := stepHours(p.process, step, piecesForDay)
+
+		if piecesForDay == 0 && task.Quantity > 0 {
+			piecesForDay = task.Quantity
+			hoursUsed = stepHours(p.process, step, piecesForDay)
+		}
+
+		if piecesForDay == 0 {
+			continue
+		}
+
+		if hoursUsed > hoursAvailable*1.1 {
+			continue
+		}
+
+		if changeover > 0 && hoursUsed < changeover {
+			continue
+		}
+
+		daySchedule.addMode(task.OrderDetailStatus, changeover)
+		daySchedule.addGlaze(task.Glaze)
+
+		daySchedule.Tasks = append(daySchedule.Tasks, TaskToCreate{
+			OrderDetailId:  task.OrderDetailId,
+			Component:      task.Component,
+			StepKey:        task.OrderDetailStatus,
+			Date:           day,
+			TaskType:       task.TaskType,
+			Quantity:       piecesForDay,
+			EstimatedHours: hoursUsed + setup,
+			IsLate:         task.HasDeadline && task.StartDate.Before(p.startDate),
+			Glaze:          task.Glaze,
+			DefectReportId: task.DefectReportId,
+			CatalogVersion: p.process.Version(),
+		})
+		daySchedule.AvailableHours -= hoursUsed + setup
+		anyTaskScheduled = true
+
+		p.markStarted(task, day)
+
+		completionDate := calculateTaskCompletionWith(p.process, p.calendar, day, task.OrderDetailStatus, task.processKey(), piecesForDay)
+		tasks, i = p.recordProgress(tasks, i, piecesForDay, completionDate)
+	}
+
+	tasks, fired := p.fireKiln(day, daySchedule, tasks)
+
+	return tasks, anyTaskScheduled || fired
+}
+
+func (p *planner) recordProgress(tasks []TaskChainItem, i int, quantity int, completionDate time.Time) ([]TaskChainItem, int) {
+	task := tasks[i]
+	chainKey := task.chainKey()
+
+	if completionDate.After(p.lastCompletion[chainKey]) {
+		p.lastCompletion[chainKey] = completionDate
+	}
+
+	completions, exists := p.stepCompletion[chainKey]
+	if !exists {
+		completions = make(map[StepKey]time.Time)
+		p.stepCompletion[chainKey] = completions
+	}
+
+	if completionDate.After(completions[task.OrderDetailStatus]) {
+		completions[task.OrderDetailStatus] = completionDate
+	}
+
+	if quantity < task.Quantity {
+		tasks[i].Quantity -= quantity
+		return tasks, i
+	}
+
+	tasks = append(tasks[:i], tasks[i+1:]...)
+	delete(p.remaining[chainKey], task.OrderDetailStatus)
+
+	return tasks, i - 1
+}
+
+func isExternalProcess(taskType TaskType) bool {
+	return taskType == TaskTypeBisque || taskType == TaskTypeFire
+}
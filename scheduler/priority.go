@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+)
+
+type PriorityOptions struct {
+	AgingDaysPerWeek float64
+}
+
+var DefaultPriority = PriorityOptions{
+	AgingDaysPerWeek: 2.0,
+}
+
+func (p *planner) prioritise(day time.Time, tasks []TaskChainItem) []TaskChainItem {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return p.slack(day, tasks[i]) < p.slack(day, tasks[j])
+	})
+
+	return tasks
+}
+
+func (p *planner) slack(day time.Time, task TaskChainItem) float64 {
+	return CalculateSlack(task, day, p.priority)
+}
+
+func CalculateSlack(task TaskChainItem, day time.Time, priority PriorityOptions) float64 {
+	// StartDate is the latest start that still meets DueDate once the rest of the
+	// chain is accounted for, so the days until it are the slack
+	slack := task.StartDate.Sub(day).Hours() / 24
+
+	if !task.HasDeadline && !task.WaitingSince.IsZero() && day.After(task.WaitingSince) {
+		weeksWaiting := day.Sub(task.WaitingSince).Hours() / (24 * 7)
+		slack -= weeksWaiting * priority.AgingDaysPerWeek
+	}
+
+	return slack
+}
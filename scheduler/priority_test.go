@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateSlack_DeadlineTask(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	task := TaskChainItem{
+		TaskType:    TaskTypeBuildBase,
+		PieceType:   PieceTypeMugWithHandle,
+		StartDate:   monday.AddDate(0, 0, 3),
+		HasDeadline: true,
+	}
+
+	assert.InDelta(t, 3.0, CalculateSlack(task, monday, DefaultPriority), 0.001, "Slack is the days left before the latest start")
+
+	task.StartDate = monday.AddDate(0, 0, -2)
+	assert.InDelta(t, -2.0, CalculateSlack(task, monday, DefaultPriority), 0.001, "Late tasks have negative slack")
+}
+
+func TestCalculateSlack_NonDeadlineAging(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	task := TaskChainItem{
+		TaskType:     TaskTypeBuildBase,
+		PieceType:    PieceTypeMugWithHandle,
+		StartDate:    monday.AddDate(0, 0, 10),
+		WaitingSince: monday.AddDate(0, 0, -28),
+	}
+
+	assert.InDelta(t, 2.0, CalculateSlack(task, monday, DefaultPriority), 0.001, "Four weeks waiting at 2 days per week removes 8 days of slack")
+	assert.InDelta(t, 10.0, CalculateSlack(task, monday, PriorityOptions{AgingDaysPerWeek: 0}), 0.001, "Aging can be disabled")
+
+	task.HasDeadline = true
+	assert.InDelta(t, 10.0, CalculateSlack(task, monday, DefaultPriority), 0.001, "Deadline orders don't age")
+}
+
+func TestPrioritise_OldNonDeadlineOutranksRelaxedDeadline(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+
+	tasks := []TaskChainItem{
+		{OrderDetailId: "deadline", StartDate: monday.AddDate(0, 0, -1), HasDeadline: true},
+		{OrderDetailId: "old-commission", StartDate: monday, WaitingSince: monday.AddDate(0, 0, -70)},
+		{OrderDetailId: "new-commission", StartDate: monday, WaitingSince: monday.AddDate(0, 0, -3)},
+	}
+
+	tasks = p.prioritise(monday, tasks)
+
+	assert.Equal(t, "old-commission", tasks[0].OrderDetailId, "Ten weeks of aging should outrank one day of lateness")
+	assert.Equal(t, "deadline", tasks[1].OrderDetailId)
+	assert.Equal(t, "new-commission", tasks[2].OrderDetailId)
+}
+
+func TestScheduler_PreviewDoesNotStarveOldCommissions(t *testing.T) {
+	now := time.Now()
+	createdAt := now.AddDate(0, 0, -15*7)
+
+	oldCommission := orders.OrderDTO{
+		ID:        "old-order",
+		Status:    "pending",
+		CreatedAt: &createdAt,
+		OrderDetails: []orders.OrderDetailDTO{
+			{ID: "old-detail", OrderID: "old-order", Type: string(PieceTypeMugWithoutHandle), Quantity: 5, Status: string(StepKeyPending)},
+		},
+	}
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(
+			newDeadlineOrder("deadline-order", "deadline-detail", PieceTypeMugWithoutHandle, 20, now.AddDate(0, 0, 20)),
+			oldCommission,
+		),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 2})
+	require.NoError(t, err)
+	require.NotEmpty(t, preview.Days)
+	require.NotEmpty(t, preview.Days[0].Tasks)
+
+	assert.Equal(t, "old-detail", preview.Days[0].Tasks[0].OrderDetailId, "The long-waiting commission should be built first")
+	assert.False(t, preview.Days[0].Tasks[0].IsLate, "Commissions without a deadline are never flagged late")
+}
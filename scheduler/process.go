@@ -0,0 +1,470 @@
+package scheduler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ProcessCatalog struct {
+	processes     map[PieceType][]ProductionStep
+	sizes         map[string]SizeMultiplier
+	setup         map[TaskType]SetupTime
+	version       string
+	effectiveFrom time.Time
+}
+
+type processFile struct {
+	Version       string                         `json:"version,omitempty" yaml:"version,omitempty"`
+	EffectiveFrom string                         `json:"effective_from,omitempty" yaml:"effective_from,omitempty"`
+	Processes     map[PieceType][]ProductionStep `json:"processes" yaml:"processes"`
+	Sizes         map[string]SizeMultiplier      `json:"sizes,omitempty" yaml:"sizes,omitempty"`
+	Setup         map[TaskType]SetupTime         `json:"setup,omitempty" yaml:"setup,omitempty"`
+}
+
+var stepOrder = []StepKey{
+	StepKeyBuild,
+	StepKeyHandles,
+	StepKeyTrim,
+	StepKeyAttach,
+	StepKeyTrimFinal,
+	StepKeyBisque,
+	StepKeyWax,
+	StepKeyGlaze,
+	StepKeyGlazeCoat,
+	StepKeyFire,
+}
+
+var stepTaskTypes = map[StepKey][]TaskType{
+	StepKeyBuild:     {TaskTypeBuildBase, TaskTypeBuildBowl},
+	StepKeyHandles:   {TaskTypePullHandles},
+	StepKeyTrim:      {TaskTypeTrim},
+	StepKeyAttach:    {TaskTypeAttachHandle, TaskTypeAttachLid},
+	StepKeyTrimFinal: {TaskTypeTrim},
+	StepKeyBisque:    {TaskTypeBisque},
+	StepKeyWax:       {TaskTypeWax},
+	StepKeyGlaze:     {TaskTypeGlaze},
+	StepKeyGlazeCoat: {TaskTypeGlaze},
+	StepKeyFire:      {TaskTypeFire},
+}
+
+var DefaultProcessCatalog = mustProcessCatalog(DefaultProductionProcess())
+
+var activeProcessHistory atomic.Pointer[ProcessHistory]
+
+func init() {
+	activeProcessHistory.Store(DefaultProcessCatalog.history())
+}
+
+// CurrentProcessCatalog is the installed catalog version in force today
+func CurrentProcessCatalog() *ProcessCatalog {
+	return CurrentProcessHistory().current(time.Now())
+}
+
+// CurrentProcessHistory is the history installed by InstallConfiguredProcess,
+// or the compiled defaults until it has run
+func CurrentProcessHistory() *ProcessHistory {
+	return activeProcessHistory.Load()
+}
+
+func SetProcessHistory(history *ProcessHistory) {
+	if history == nil {
+		history = DefaultProcessCatalog.history()
+	}
+
+	activeProcessHistory.Store(history)
+}
+
+func NewProcessCatalog(processes map[PieceType][]ProductionStep) (*ProcessCatalog, error) {
+	return NewProcessCatalogWithSizes(processes, SizeMultipliers)
+}
+
+func NewProcessCatalogWithSizes(processes map[PieceType][]ProductionStep, sizes map[string]SizeMultiplier) (*ProcessCatalog, error) {
+	return NewProcessCatalogWithSetup(processes, sizes, SetupTimes)
+}
+
+func NewProcessCatalogWithSetup(processes map[PieceType][]ProductionStep, sizes map[string]SizeMultiplier, setup map[TaskType]SetupTime) (*ProcessCatalog, error) {
+	if len(processes) == 0 {
+		return nil, fmt.Errorf("[ProcessCatalog:Validate] no production processes defined")
+	}
+
+	errs := []error{}
+	for _, pieceType := range sortedPieceTypes(processes) {
+		if err := validateProcess(pieceType, processes[pieceType]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for size, multiplier := range sizes {
+		if err := validateSize(size, multiplier); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for taskType, setupTime := range setup {
+		if err := validateSetup(taskType, setupTime); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	catalog := newProcessCatalog(processes, sizes, setup)
+	catalog.version = catalog.contentVersion()
+
+	return catalog, nil
+}
+
+func ParseProcessCatalog(data []byte, format string) (*ProcessCatalog, error) {
+	var file processFile
+
+	if err := decodeProcessData(data, format, &file); err != nil {
+		return nil, err
+	}
+
+	return file.catalog()
+}
+
+func decodeProcessData(data []byte, format string, target any) error {
+	switch strings.ToLower(format) {
+	case "json":
+		if err := json.Unmarshal(data, target); err != nil {
+			return fmt.Errorf("[ProcessCatalog:Parse] invalid json: %w", err)
+		}
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, target); err != nil {
+			return fmt.Errorf("[ProcessCatalog:Parse] invalid yaml: %w", err)
+		}
+	default:
+		return fmt.Errorf("[ProcessCatalog:Parse] unsupported format %q", format)
+	}
+
+	return nil
+}
+
+func (f processFile) catalog() (*ProcessCatalog, error) {
+	sizes := f.Sizes
+	if sizes == nil {
+		sizes = SizeMultipliers
+	}
+
+	setup := f.Setup
+	if setup == nil {
+		setup = SetupTimes
+	}
+
+	catalog, err := NewProcessCatalogWithSetup(f.Processes, sizes, setup)
+	if err != nil {
+		return nil, err
+	}
+
+	effectiveFrom := time.Time{}
+	if f.EffectiveFrom != "" {
+		effectiveFrom, err = time.Parse("2006-01-02", f.EffectiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("[ProcessCatalog:Parse] invalid effective_from %q: %w", f.EffectiveFrom, err)
+		}
+	}
+
+	return catalog.withVersion(f.Version, effectiveFrom), nil
+}
+
+func LoadProcessCatalog(path string) (*ProcessCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[ProcessCatalog:Load] failed to read %s: %w", path, err)
+	}
+
+	catalog, err := ParseProcessCatalog(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("[ProcessCatalog:Load] %s: %w", path, err)
+	}
+
+	return catalog, nil
+}
+
+// LoadConfiguredProcessCatalog returns the configured catalog in force today
+func LoadConfiguredProcessCatalog() (*ProcessCatalog, error) {
+	history, err := LoadConfiguredProcessHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	return history.At(time.Now())
+}
+
+func (c *ProcessCatalog) Version() string {
+	return c.version
+}
+
+func (c *ProcessCatalog) EffectiveFrom() time.Time {
+	return c.effectiveFrom
+}
+
+func (c *ProcessCatalog) Steps(pieceType PieceType) []ProductionStep {
+	return append([]ProductionStep{}, c.processes[pieceType]...)
+}
+
+func (c *ProcessCatalog) PieceTypes() []PieceType {
+	return sortedPieceTypes(c.processes)
+}
+
+func (c *ProcessCatalog) HasProcess(pieceType PieceType) bool {
+	return len(c.processes[pieceType]) > 0
+}
+
+func (c *ProcessCatalog) stepForTask(taskType TaskType, pieceType PieceType) (ProductionStep, bool) {
+	for _, step := range c.processes[pieceType] {
+		if step.TaskType == taskType {
+			return step, true
+		}
+	}
+
+	return ProductionStep{}, false
+}
+
+func (c *ProcessCatalog) stepByKey(stepKey StepKey, pieceType PieceType) (ProductionStep, bool) {
+	for _, step := range c.processes[pieceType] {
+		if step.StepKey == stepKey {
+			return step, true
+		}
+	}
+
+	return ProductionStep{}, false
+}
+
+func (c *ProcessCatalog) MarshalJSON() ([]byte, error) {
+	file := processFile{Version: c.version, Processes: c.processes, Sizes: c.sizes, Setup: c.setup}
+	if !c.effectiveFrom.IsZero() {
+		file.EffectiveFrom = c.effectiveFrom.Format("2006-01-02")
+	}
+
+	return json.Marshal(file)
+}
+
+// contentVersion names a catalog by its rates, so an unversioned catalog
+// still gets a new version whenever a rate changes
+func (c *ProcessCatalog) contentVersion() string {
+	data, _ := json.Marshal(processFile{Processes: c.processes, Sizes: c.sizes, Setup: c.setup})
+	sum := sha256.Sum256(data)
+
+	return "sha-" + hex.EncodeToString(sum[:6])
+}
+
+func (c *ProcessCatalog) withVersion(version string, effectiveFrom time.Time) *ProcessCatalog {
+	versioned := *c
+	if version != "" {
+		versioned.version = version
+	}
+	versioned.effectiveFrom = effectiveFrom
+
+	return &versioned
+}
+
+// derive builds a catalog from processes that keeps this catalog's sizes,
+// setup times and version, for processes planned on top of it
+func (c *ProcessCatalog) derive(processes map[PieceType][]ProductionStep) *ProcessCatalog {
+	derived := newProcessCatalog(processes, c.sizes, c.setup)
+	derived.version = c.version
+	derived.effectiveFrom = c.effectiveFrom
+
+	return derived
+}
+
+func (c *ProcessCatalog) withProcesses(processes map[PieceType][]ProductionStep) *ProcessCatalog {
+	if len(processes) == 0 {
+		return c
+	}
+
+	merged := make(map[PieceType][]ProductionStep, len(c.processes)+len(processes))
+	for pieceType, steps := range c.processes {
+		merged[pieceType] = steps
+	}
+	for pieceType, steps := range processes {
+		merged[pieceType] = steps
+	}
+
+	return c.derive(merged)
+}
+
+func newProcessCatalog(processes map[PieceType][]ProductionStep, sizes map[string]SizeMultiplier, setup map[TaskType]SetupTime) *ProcessCatalog {
+	copied := make(map[PieceType][]ProductionStep, len(processes))
+	for pieceType, steps := range processes {
+		copied[pieceType] = append([]ProductionStep{}, steps...)
+	}
+
+	copiedSizes := make(map[string]SizeMultiplier, len(sizes))
+	for size, multiplier := range sizes {
+		copiedSizes[normalizeSize(size)] = multiplier
+	}
+
+	copiedSetup := make(map[TaskType]SetupTime, len(setup))
+	for taskType, setupTime := range setup {
+		copiedSetup[taskType] = setupTime
+	}
+
+	return &ProcessCatalog{processes: copied, sizes: copiedSizes, setup: copiedSetup}
+}
+
+func mustProcessCatalog(processes map[PieceType][]ProductionStep) *ProcessCatalog {
+	catalog, err := NewProcessCatalog(processes)
+	if err != nil {
+		panic(err)
+	}
+
+	return catalog
+}
+
+func validateProcess(pieceType PieceType, steps []ProductionStep) error {
+	if _, valid := IsValidPieceType(string(basePieceType(pieceType))); !valid {
+		return fmt.Errorf("[ProcessCatalog:Validate] unknown piece type %q", pieceType)
+	}
+
+	if len(steps) == 0 {
+		return fmt.Errorf("[ProcessCatalog:Validate] %s has no steps", pieceType)
+	}
+
+	errs := []error{}
+	previousRank := -1
+
+	for i, step := range steps {
+		rank := stepRank(step.StepKey)
+
+		if rank < 0 {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %d has unknown step key %q", pieceType, i, step.StepKey))
+			continue
+		}
+
+		if !stepAllowsTaskType(step.StepKey, step.TaskType) {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s has unknown task type %q", pieceType, step.StepKey, step.TaskType))
+		}
+
+		if rank <= previousRank {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s is out of order", pieceType, step.StepKey))
+		}
+		previousRank = max(previousRank, rank)
+
+		if step.Rate < 0 {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s has negative rate %v", pieceType, step.StepKey, step.Rate))
+		} else if step.Rate == 0 && !isExternalProcess(step.TaskType) {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s needs a rate", pieceType, step.StepKey))
+		}
+
+		if step.DryingDays < 0 {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s has negative drying days %d", pieceType, step.StepKey, step.DryingDays))
+		}
+
+		if step.LossRate < 0 || step.LossRate >= 1 {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s has loss rate %v, which must be at least 0 and below 1", pieceType, step.StepKey, step.LossRate))
+		}
+	}
+
+	errs = append(errs, validateProcessGraph(pieceType, steps)...)
+
+	if steps[0].StepKey != StepKeyBuild {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s must start with %s", pieceType, StepKeyBuild))
+	}
+
+	if steps[len(steps)-1].StepKey != StepKeyFire {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s must end with %s", pieceType, StepKeyFire))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateSize(size string, multiplier SizeMultiplier) error {
+	errs := []error{}
+
+	if normalizeSize(size) == "" {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] size multiplier has no size"))
+	}
+
+	if multiplier.Rate <= 0 {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] size %s needs a positive rate multiplier", size))
+	}
+
+	if multiplier.DryingDays < 0 {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] size %s has negative drying days multiplier %v", size, multiplier.DryingDays))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateSetup(taskType TaskType, setup SetupTime) error {
+	if !isKnownTaskType(taskType) {
+		return fmt.Errorf("[ProcessCatalog:Validate] setup for unknown task type %q", taskType)
+	}
+
+	errs := []error{}
+
+	if isExternalProcess(taskType) && (setup.TaskHours != 0 || setup.DayHours != 0) {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s runs in the kiln and can't have setup time", taskType))
+	}
+
+	if setup.TaskHours < 0 {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s has negative task setup hours %v", taskType, setup.TaskHours))
+	}
+
+	if setup.DayHours < 0 {
+		errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s has negative day setup hours %v", taskType, setup.DayHours))
+	}
+
+	return errors.Join(errs...)
+}
+
+func stepRank(stepKey StepKey) int {
+	for i, key := range stepOrder {
+		if key == stepKey {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func stepAllowsTaskType(stepKey StepKey, taskType TaskType) bool {
+	for _, allowed := range stepTaskTypes[stepKey] {
+		if allowed == taskType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isKnownTaskType(taskType TaskType) bool {
+	for _, taskTypes := range stepTaskTypes {
+		for _, known := range taskTypes {
+			if known == taskType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sortedPieceTypes(processes map[PieceType][]ProductionStep) []PieceType {
+	pieceTypes := make([]PieceType, 0, len(processes))
+	for pieceType := range processes {
+		pieceTypes = append(pieceTypes, pieceType)
+	}
+
+	sort.Slice(pieceTypes, func(i, j int) bool {
+		return pieceTypes[i] < pieceTypes[j]
+	})
+
+	return pieceTypes
+}
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+func isGraphProcess(steps []ProductionStep) bool {
+	for _, step := range steps {
+		if len(step.After) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stepPredecessors returns the indexes of the steps each step waits for
+func stepPredecessors(steps []ProductionStep) [][]int {
+	predecessors := make([][]int, len(steps))
+	graph := isGraphProcess(steps)
+
+	for i, step := range steps {
+		if !graph {
+			if i > 0 {
+				predecessors[i] = []int{i - 1}
+			}
+			continue
+		}
+
+		for _, after := range step.After {
+			if j := slices.IndexFunc(steps[:i], func(previous ProductionStep) bool { return previous.StepKey == after }); j >= 0 {
+				predecessors[i] = append(predecessors[i], j)
+			}
+		}
+	}
+
+	return predecessors
+}
+
+func stepSuccessors(predecessors [][]int) [][]int {
+	successors := make([][]int, len(predecessors))
+	for i, previous := range predecessors {
+		for _, j := range previous {
+			successors[j] = append(successors[j], i)
+		}
+	}
+
+	return successors
+}
+
+// selectSteps drops the optional steps that weren't asked for. Steps that
+// waited on a dropped step wait on its predecessors instead.
+func selectSteps(steps []ProductionStep, selected []StepKey) []ProductionStep {
+	dropped := make(map[StepKey][]StepKey)
+	kept := []ProductionStep{}
+
+	graph := isGraphProcess(steps)
+
+	for _, step := range steps {
+		if step.Optional && !slices.Contains(selected, step.StepKey) {
+			dropped[step.StepKey] = step.After
+			continue
+		}
+
+		if graph {
+			step.After = rewireAfter(step.After, dropped)
+		}
+
+		kept = append(kept, step)
+	}
+
+	return kept
+}
+
+func rewireAfter(after []StepKey, dropped map[StepKey][]StepKey) []StepKey {
+	rewired := []StepKey{}
+
+	for _, previous := range after {
+		replacement, wasDropped := dropped[previous]
+		if !wasDropped {
+			replacement = []StepKey{previous}
+		}
+
+		for _, key := range replacement {
+			if !slices.Contains(rewired, key) {
+				rewired = append(rewired, key)
+			}
+		}
+	}
+
+	return rewired
+}
+
+// processFinish follows the longest path through steps, which is how long
+// the process takes when every branch is worked on in parallel
+func processFinish(calendar *Calendar, start time.Time, steps []ProductionStep, quantity int) time.Time {
+	predecessors := stepPredecessors(steps)
+	quantities := stepQuantities(steps, quantity)
+	finish := make([]time.Time, len(steps))
+	latest := start
+
+	for i, step := range steps {
+		stepStart := start
+		for _, j := range predecessors[i] {
+			if finish[j].After(stepStart) {
+				stepStart = finish[j]
+			}
+		}
+
+		finish[i] = calendar.stepFinish(stepStart, step, quantities[i])
+		if finish[i].After(latest) {
+			latest = finish[i]
+		}
+	}
+
+	return latest
+}
+
+func validateProcessGraph(pieceType PieceType, steps []ProductionStep) []error {
+	errs := []error{}
+	seen := make(map[StepKey]bool)
+
+	for i, step := range steps {
+		for _, after := range step.After {
+			if !seen[after] {
+				errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s runs after %q, which must be an earlier step", pieceType, step.StepKey, after))
+			}
+		}
+
+		if step.Optional && (i == 0 || i == len(steps)-1) {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s can't be optional", pieceType, step.StepKey))
+		}
+
+		seen[step.StepKey] = true
+	}
+
+	if !isGraphProcess(steps) || len(errs) > 0 {
+		return errs
+	}
+
+	// Every branch has to join back up before the last step
+	successors := stepSuccessors(stepPredecessors(steps))
+	for i := 0; i < len(steps)-1; i++ {
+		if len(successors[i]) == 0 {
+			errs = append(errs, fmt.Errorf("[ProcessCatalog:Validate] %s step %s leads to no later step", pieceType, steps[i].StepKey))
+		}
+	}
+
+	return errs
+}
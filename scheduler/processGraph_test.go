@@ -0,0 +1,225 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Handles are pulled while the bodies dry and join them at attach. Wax resist
+// and a second glaze coat are only applied when they're asked for.
+const graphProcessYAML = `
+processes:
+  mug-with-handle:
+    - {step_key: build, task_type: task_build_base, rate: 5, drying_days: 2}
+    - {step_key: handles, task_type: task_pull_handles, rate: 20, drying_days: 1}
+    - {step_key: trim, task_type: task_trim, rate: 15, drying_days: 1, after: [build]}
+    - {step_key: attach, task_type: task_attach_handle, rate: 8, drying_days: 2, after: [trim, handles]}
+    - {step_key: trim_final, task_type: task_trim, rate: 15, drying_days: 3, after: [attach]}
+    - {step_key: bisque, task_type: task_bisque, rate: 0, drying_days: 5, after: [trim_final]}
+    - {step_key: wax, task_type: task_wax, rate: 30, drying_days: 0, after: [bisque], optional: true}
+    - {step_key: glaze, task_type: task_glaze, rate: 17, drying_days: 0, after: [wax]}
+    - {step_key: glaze_coat, task_type: task_glaze, rate: 17, drying_days: 0, after: [glaze], optional: true}
+    - {step_key: fire, task_type: task_fire, rate: 0, drying_days: 5, after: [glaze_coat]}
+`
+
+func graphCatalog(t *testing.T) *ProcessCatalog {
+	t.Helper()
+
+	catalog, err := ParseProcessCatalog([]byte(graphProcessYAML), "yaml")
+	require.NoError(t, err)
+
+	return catalog
+}
+
+func graphDetail(optionalSteps ...string) orders.OrderDetailDTO {
+	return orders.OrderDetailDTO{ID: "graph-detail", Type: string(PieceTypeMugWithHandle), Quantity: 10, Status: string(StepKeyPending), OptionalSteps: optionalSteps}
+}
+
+func TestCalculateTaskChain_Branches(t *testing.T) {
+	dueDate := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tasks, err := calculateTaskChain(graphCatalog(t), everyDayCalendar, graphDetail(), dueDate, dueDate.AddDate(0, 0, -60))
+	require.NoError(t, err)
+	require.Len(t, tasks, 8, "Wax resist wasn't asked for")
+
+	byStep := map[StepKey]TaskChainItem{}
+	for _, task := range tasks {
+		byStep[task.OrderDetailStatus] = task
+	}
+
+	assert.Empty(t, byStep[StepKeyHandles].After)
+	assert.Equal(t, []StepKey{StepKeyTrim, StepKeyHandles}, byStep[StepKeyAttach].After)
+	assert.Equal(t, []StepKey{StepKeyBisque}, byStep[StepKeyGlaze].After, "Glaze waits for what wax waited for")
+
+	// Both branches have to be done when attach starts, but handles only take
+	// two days where building and trimming take six
+	attachStart := byStep[StepKeyAttach].StartDate
+	assert.Equal(t, attachStart.AddDate(0, 0, -2), byStep[StepKeyHandles].StartDate)
+	assert.Equal(t, attachStart.AddDate(0, 0, -2), byStep[StepKeyTrim].StartDate)
+	assert.Equal(t, attachStart.AddDate(0, 0, -6), byStep[StepKeyBuild].StartDate)
+
+	withWax, err := calculateTaskChain(graphCatalog(t), everyDayCalendar, graphDetail(string(StepKeyWax)), dueDate, dueDate.AddDate(0, 0, -60))
+	require.NoError(t, err)
+	require.Len(t, withWax, 9)
+	assert.Equal(t, StepKeyWax, withWax[6].OrderDetailStatus)
+	assert.Equal(t, []StepKey{StepKeyWax}, withWax[7].After)
+}
+
+func TestCalculateTaskChain_BranchesCompletedOutOfOrder(t *testing.T) {
+	dueDate := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	asOf := dueDate.AddDate(0, 0, -30)
+	finished := asOf.AddDate(0, 0, -5)
+
+	chainSteps := func(detail orders.OrderDetailDTO) map[StepKey]TaskChainItem {
+		tasks, err := calculateTaskChain(graphCatalog(t), everyDayCalendar, detail, dueDate, asOf)
+		require.NoError(t, err)
+
+		byStep := map[StepKey]TaskChainItem{}
+		for _, task := range tasks {
+			byStep[task.OrderDetailStatus] = task
+		}
+
+		return byStep
+	}
+
+	// The bodies are trimmed before all the handles are pulled
+	detail := graphDetail()
+	detail.Status = string(StepKeyTrim)
+	detail.StatusChangedAt = &finished
+	detail.StepProgress = map[string]orders.StepProgressDTO{
+		"build":   {CompletedQuantity: 10, CompletedAt: &finished},
+		"trim":    {CompletedQuantity: 10, CompletedAt: &finished},
+		"handles": {CompletedQuantity: 4},
+	}
+
+	byStep := chainSteps(detail)
+	assert.NotContains(t, byStep, StepKeyBuild)
+	assert.NotContains(t, byStep, StepKeyTrim)
+	require.Contains(t, byStep, StepKeyHandles, "Trimming first doesn't finish the handles")
+	assert.Equal(t, 6, byStep[StepKeyHandles].Quantity)
+	assert.Equal(t, []StepKey{StepKeyHandles}, byStep[StepKeyAttach].After)
+	assert.Equal(t, 10, byStep[StepKeyAttach].Quantity)
+
+	// Then the handles are done while the bodies are still being built
+	detail.Status = string(StepKeyHandles)
+	detail.StepProgress = map[string]orders.StepProgressDTO{
+		"build":   {CompletedQuantity: 3},
+		"handles": {CompletedQuantity: 10, CompletedAt: &finished},
+	}
+
+	byStep = chainSteps(detail)
+	assert.NotContains(t, byStep, StepKeyHandles)
+	assert.Equal(t, 7, byStep[StepKeyBuild].Quantity, "Handles pulled don't count as bodies built")
+	assert.Equal(t, 10, byStep[StepKeyTrim].Quantity)
+	assert.Equal(t, []StepKey{StepKeyTrim}, byStep[StepKeyAttach].After)
+
+	// Status alone from before steps were tracked still plans the handles
+	legacy := graphDetail()
+	legacy.Status = string(StepKeyTrim)
+	legacy.StatusChangedAt = &finished
+
+	byStep = chainSteps(legacy)
+	assert.NotContains(t, byStep, StepKeyBuild)
+	assert.NotContains(t, byStep, StepKeyTrim)
+	assert.Contains(t, byStep, StepKeyHandles)
+}
+
+func TestCalculateTaskChain_GlazeCoatIsNotRepeated(t *testing.T) {
+	dueDate := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	asOf := dueDate.AddDate(0, 0, -20)
+	finished := asOf.AddDate(0, 0, -1)
+
+	detail := graphDetail(string(StepKeyGlazeCoat))
+	detail.Status = string(StepKeyGlazeCoat)
+	detail.StatusChangedAt = &finished
+	detail.StepProgress = map[string]orders.StepProgressDTO{
+		"glaze":      {CompletedQuantity: 10, CompletedAt: &finished},
+		"glaze_coat": {CompletedQuantity: 10, CompletedAt: &finished},
+	}
+
+	tasks, err := calculateTaskChain(graphCatalog(t), everyDayCalendar, detail, dueDate, asOf)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, StepKeyFire, tasks[0].OrderDetailStatus)
+}
+
+func TestCalculateTaskChain_LinearProcessHasNoAfter(t *testing.T) {
+	tasks, err := CalculateTaskChain(graphDetail(), time.Now().AddDate(0, 0, 60))
+	require.NoError(t, err)
+
+	for _, task := range tasks {
+		assert.Nil(t, task.After, task.OrderDetailStatus)
+	}
+}
+
+func TestCalculateCompletionDate_LongestBranch(t *testing.T) {
+	fromDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	catalog := graphCatalog(t)
+
+	completion, err := calculateCompletionDate(catalog, everyDayCalendar, graphDetail(), fromDate)
+	require.NoError(t, err)
+	assert.Equal(t, fromDate.AddDate(0, 0, 25), completion, "Pulling handles alongside building and trimming doesn't add days")
+
+	withWax, err := calculateCompletionDate(catalog, everyDayCalendar, graphDetail(string(StepKeyWax)), fromDate)
+	require.NoError(t, err)
+	assert.Equal(t, fromDate.AddDate(0, 0, 26), withWax)
+
+	_, err = calculateCompletionDate(catalog, everyDayCalendar, graphDetail(string(StepKeyGlaze)), fromDate)
+	assert.ErrorContains(t, err, "isn't an optional step")
+}
+
+func TestScheduler_WorksBranchesInParallel(t *testing.T) {
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "graph-detail", PieceTypeMugWithHandle, 5, time.Now().AddDate(0, 0, 14))),
+		NewInMemoryAvailabilitySource(flatAvailability(4, 8)),
+		NewInMemoryTaskStore(),
+	).WithProcessCatalog(graphCatalog(t))
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+
+	scheduled := map[TaskType]time.Time{}
+	for _, day := range preview.Days {
+		for _, task := range day.Tasks {
+			if _, exists := scheduled[task.TaskType]; !exists {
+				scheduled[task.TaskType] = task.Date
+			}
+		}
+	}
+
+	require.Contains(t, scheduled, TaskTypePullHandles)
+	require.Contains(t, scheduled, TaskTypeAttachHandle)
+	assert.True(t, scheduled[TaskTypePullHandles].Before(scheduled[TaskTypeTrim]), "Handles don't wait for the bodies to be trimmed")
+	assert.True(t, scheduled[TaskTypeAttachHandle].After(scheduled[TaskTypePullHandles]))
+	assert.True(t, scheduled[TaskTypeAttachHandle].After(scheduled[TaskTypeTrim]))
+}
+
+func TestValidateProcess_Graph(t *testing.T) {
+	steps := func() []ProductionStep {
+		return []ProductionStep{
+			{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2},
+			{StepKey: StepKeyHandles, TaskType: TaskTypePullHandles, Rate: 20, DryingDays: 1},
+			{StepKey: StepKeyAttach, TaskType: TaskTypeAttachHandle, Rate: 8, DryingDays: 2, After: []StepKey{StepKeyBuild, StepKeyHandles}},
+			{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, DryingDays: 5, After: []StepKey{StepKeyAttach}},
+			{StepKey: StepKeyFire, TaskType: TaskTypeFire, DryingDays: 5, After: []StepKey{StepKeyBisque}},
+		}
+	}
+
+	assert.NoError(t, validateProcess(PieceTypeMugWithHandle, steps()))
+
+	later := steps()
+	later[2].After = []StepKey{StepKeyBuild, StepKeyBisque}
+	assert.ErrorContains(t, validateProcess(PieceTypeMugWithHandle, later), `runs after "bisque", which must be an earlier step`)
+
+	deadEnd := steps()
+	deadEnd[2].After = []StepKey{StepKeyBuild}
+	assert.ErrorContains(t, validateProcess(PieceTypeMugWithHandle, deadEnd), "handles leads to no later step")
+
+	optionalFire := steps()
+	optionalFire[4].Optional = true
+	assert.ErrorContains(t, validateProcess(PieceTypeMugWithHandle, optionalFire), "fire can't be optional")
+}
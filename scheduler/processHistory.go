@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessHistory holds every catalog version so estimates can be recomputed
+// against the rates that were in force when they were made
+type ProcessHistory struct {
+	versions []*ProcessCatalog
+}
+
+type processHistoryFile struct {
+	Versions []processFile `json:"versions" yaml:"versions"`
+}
+
+func NewProcessHistory(catalogs ...*ProcessCatalog) (*ProcessHistory, error) {
+	if len(catalogs) == 0 {
+		return nil, fmt.Errorf("[ProcessHistory:Validate] no catalog versions defined")
+	}
+
+	versions := append([]*ProcessCatalog{}, catalogs...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].effectiveFrom.Before(versions[j].effectiveFrom)
+	})
+
+	errs := []error{}
+	seen := make(map[string]bool)
+
+	for i, catalog := range versions {
+		if seen[catalog.version] {
+			errs = append(errs, fmt.Errorf("[ProcessHistory:Validate] version %s is defined more than once", catalog.version))
+		}
+		seen[catalog.version] = true
+
+		if i > 0 && catalog.effectiveFrom.Equal(versions[i-1].effectiveFrom) {
+			errs = append(errs, fmt.Errorf("[ProcessHistory:Validate] versions %s and %s are both effective from %s", versions[i-1].version, catalog.version, catalog.effectiveFrom.Format("2006-01-02")))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return &ProcessHistory{versions: versions}, nil
+}
+
+// ParseProcessHistory reads a file with a versions list, or a single catalog
+// as a history of one version
+func ParseProcessHistory(data []byte, format string) (*ProcessHistory, error) {
+	var file processHistoryFile
+
+	if err := decodeProcessData(data, format, &file); err != nil {
+		return nil, err
+	}
+
+	if len(file.Versions) == 0 {
+		catalog, err := ParseProcessCatalog(data, format)
+		if err != nil {
+			return nil, err
+		}
+
+		return catalog.history(), nil
+	}
+
+	catalogs := []*ProcessCatalog{}
+	errs := []error{}
+
+	for i, version := range file.Versions {
+		catalog, err := version.catalog()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[ProcessHistory:Parse] version %d: %w", i, err))
+			continue
+		}
+
+		catalogs = append(catalogs, catalog)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return NewProcessHistory(catalogs...)
+}
+
+func LoadProcessHistory(path string) (*ProcessHistory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[ProcessHistory:Load] failed to read %s: %w", path, err)
+	}
+
+	history, err := ParseProcessHistory(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("[ProcessHistory:Load] %s: %w", path, err)
+	}
+
+	return history, nil
+}
+
+// LoadConfiguredProcessHistory reads PRODUCTION_PROCESS_FILE, or the
+// production_steps table when PRODUCTION_PROCESS_SOURCE is "supabase", and
+// falls back to the compiled-in defaults
+func LoadConfiguredProcessHistory() (*ProcessHistory, error) {
+	if path := os.Getenv("PRODUCTION_PROCESS_FILE"); path != "" {
+		return LoadProcessHistory(path)
+	}
+
+	if os.Getenv("PRODUCTION_PROCESS_SOURCE") == "supabase" {
+		return LoadProcessHistoryFromSupabase()
+	}
+
+	return DefaultProcessCatalog.history(), nil
+}
+
+var installMu sync.Mutex
+var installed bool
+
+// InstallConfiguredProcess loads the configured production process and studio
+// calendar the first time it's called and installs them, so the estimators,
+// quotes and validation plan from the same catalog as the scheduler. A failed
+// load is retried on the next call.
+func InstallConfiguredProcess() error {
+	installMu.Lock()
+	defer installMu.Unlock()
+
+	if installed {
+		return nil
+	}
+
+	history, err := LoadConfiguredProcessHistory()
+	if err != nil {
+		return fmt.Errorf("[ProcessHistory:Install] failed to load production process: %w", err)
+	}
+
+	calendar, err := LoadConfiguredCalendar()
+	if err != nil {
+		return fmt.Errorf("[ProcessHistory:Install] failed to load studio calendar: %w", err)
+	}
+
+	SetProcessHistory(history)
+	SetCalendar(calendar)
+	installed = true
+
+	return nil
+}
+
+func processInstalled() bool {
+	installMu.Lock()
+	defer installMu.Unlock()
+
+	return installed
+}
+
+// HasCatalogVersion reports whether the configured process history has a
+// version with this name, so callers can reject an unknown catalog_version
+// before planning with it.
+func HasCatalogVersion(version string) (bool, error) {
+	if err := InstallConfiguredProcess(); err != nil {
+		return false, err
+	}
+
+	_, ok := CurrentProcessHistory().Version(version)
+
+	return ok, nil
+}
+
+func (c *ProcessCatalog) history() *ProcessHistory {
+	return &ProcessHistory{versions: []*ProcessCatalog{c}}
+}
+
+// At returns the latest version effective on or before t
+func (h *ProcessHistory) At(t time.Time) (*ProcessCatalog, error) {
+	for i := len(h.versions) - 1; i >= 0; i-- {
+		if !h.versions[i].effectiveFrom.After(t) {
+			return h.versions[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("[ProcessHistory:At] no catalog version is effective on %s", t.Format("2006-01-02"))
+}
+
+func (h *ProcessHistory) Version(version string) (*ProcessCatalog, bool) {
+	for _, catalog := range h.versions {
+		if catalog.version == version {
+			return catalog, true
+		}
+	}
+
+	return nil, false
+}
+
+// current is the version in force at t, or the earliest when none is yet
+func (h *ProcessHistory) current(t time.Time) *ProcessCatalog {
+	if catalog, err := h.At(t); err == nil {
+		return catalog
+	}
+
+	return h.versions[0]
+}
+
+func (h *ProcessHistory) Versions() []*ProcessCatalog {
+	return append([]*ProcessCatalog{}, h.versions...)
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const historyYAML = `
+versions:
+  - version: rates-2025
+    effective_from: "2025-01-01"
+    processes:
+      mug-without-handle:
+        - {step_key: build, task_type: task_build_base, rate: 5, drying_days: 2}
+        - {step_key: fire, task_type: task_fire, rate: 0, drying_days: 5}
+  - version: rates-2026
+    effective_from: "2026-03-01"
+    processes:
+      mug-without-handle:
+        - {step_key: build, task_type: task_build_base, rate: 10, drying_days: 2}
+        - {step_key: fire, task_type: task_fire, rate: 0, drying_days: 5}
+`
+
+func TestParseProcessHistory_At(t *testing.T) {
+	history, err := ParseProcessHistory([]byte(historyYAML), "yaml")
+	require.NoError(t, err)
+	require.Len(t, history.Versions(), 2)
+
+	before, err := history.At(time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "rates-2025", before.Version())
+
+	after, err := history.At(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "rates-2026", after.Version())
+	assert.Equal(t, 10.0, after.Steps(PieceTypeMugWithoutHandle)[0].Rate)
+
+	_, err = history.At(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorContains(t, err, "no catalog version is effective")
+
+	catalog, exists := history.Version("rates-2025")
+	require.True(t, exists)
+	assert.Equal(t, 5.0, catalog.Steps(PieceTypeMugWithoutHandle)[0].Rate)
+}
+
+func TestParseProcessHistory_SingleCatalog(t *testing.T) {
+	history, err := LoadProcessHistory("testdata/process.yaml")
+	require.NoError(t, err)
+	require.Len(t, history.Versions(), 1)
+
+	catalog, err := history.At(time.Now())
+	require.NoError(t, err)
+	assert.Regexp(t, `^sha-[0-9a-f]{12}$`, catalog.Version(), "Unversioned catalogs are named by their contents")
+	assert.NotEqual(t, DefaultProcessCatalog.Version(), catalog.Version())
+}
+
+func TestNewProcessHistory_Validation(t *testing.T) {
+	effective := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := NewProcessHistory(DefaultProcessCatalog.withVersion("a", effective), DefaultProcessCatalog.withVersion("a", effective.AddDate(0, 1, 0)))
+	assert.ErrorContains(t, err, "defined more than once")
+
+	_, err = NewProcessHistory(DefaultProcessCatalog.withVersion("a", effective), DefaultProcessCatalog.withVersion("b", effective))
+	assert.ErrorContains(t, err, "both effective from 2026-01-01")
+}
+
+func TestScheduler_RecordsCatalogVersion(t *testing.T) {
+	history, err := ParseProcessHistory([]byte(historyYAML), "yaml")
+	require.NoError(t, err)
+
+	asOf := time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC)
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithoutHandle, 10, asOf.AddDate(0, 0, 20))),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	).WithProcessHistory(history).WithClock(NewFixedClock(asOf))
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+	assert.Equal(t, "rates-2025", preview.CatalogVersion)
+	require.NotZero(t, preview.TotalTasks)
+
+	for _, day := range preview.Days {
+		for _, task := range day.Tasks {
+			assert.Equal(t, "rates-2025", task.CatalogVersion)
+		}
+	}
+
+	recomputed, err := scheduler.Preview(RunOptions{HorizonWeeks: 4, CatalogVersion: "rates-2026"})
+	require.NoError(t, err)
+	assert.Equal(t, "rates-2026", recomputed.CatalogVersion)
+
+	_, err = scheduler.Preview(RunOptions{HorizonWeeks: 4, CatalogVersion: "rates-2020"})
+	assert.ErrorContains(t, err, "unknown catalog version")
+
+	_, err = scheduler.Run(RunOptions{HorizonWeeks: 4, CatalogVersion: "rates-2026"})
+	assert.ErrorContains(t, err, "can only be previewed")
+}
+
+func TestQuoteCompletionDate_RecordsCatalogVersion(t *testing.T) {
+	detail := sizedDetail("10")
+
+	quote, err := QuoteCompletionDate(detail, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	date, err := CalculateCompletionDate(detail, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, date, quote.Date)
+	assert.Equal(t, DefaultProcessCatalog.Version(), quote.CatalogVersion)
+}
+
+func TestProcessHistoryFromRows_KeepsSizesAndSetup(t *testing.T) {
+	sizes := map[string]SizeMultiplier{"large": {Rate: 0.5, DryingDays: 1.5}}
+	setup := map[TaskType]SetupTime{TaskTypeBuildBase: {TaskHours: 0.5, DayHours: 0.25}}
+
+	catalog, err := NewProcessCatalogWithSetup(DefaultProductionProcess(), sizes, setup)
+	require.NoError(t, err)
+	catalog = catalog.withVersion("calibrated-2026-06-01", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	data, err := json.Marshal(processCatalogRows(catalog))
+	require.NoError(t, err)
+
+	var rows []productionStepRow
+	require.NoError(t, json.Unmarshal(data, &rows))
+
+	history, err := processHistoryFromRows(rows)
+	require.NoError(t, err)
+
+	loaded, exists := history.Version("calibrated-2026-06-01")
+	require.True(t, exists)
+	assert.Equal(t, sizes, loaded.sizes)
+	assert.Equal(t, setup, loaded.setup)
+
+	// Versions saved before sizes and setup were stored keep the defaults
+	for i := range rows {
+		rows[i].Sizes = nil
+		rows[i].Setup = nil
+	}
+
+	history, err = processHistoryFromRows(rows)
+	require.NoError(t, err)
+
+	loaded, _ = history.Version("calibrated-2026-06-01")
+	assert.Equal(t, SizeMultipliers, loaded.sizes)
+	assert.Equal(t, SetupTimes, loaded.setup)
+}
+
+func TestHasCatalogVersion(t *testing.T) {
+	t.Cleanup(func() {
+		installed = false
+		SetProcessHistory(nil)
+		SetCalendar(nil)
+	})
+
+	t.Setenv("PRODUCTION_PROCESS_FILE", "testdata/process.yaml")
+
+	history, err := LoadProcessHistory("testdata/process.yaml")
+	require.NoError(t, err)
+	configured := history.Versions()[0].Version()
+
+	known, err := HasCatalogVersion(configured)
+	require.NoError(t, err)
+	assert.True(t, known)
+
+	known, err = HasCatalogVersion("rates-1999")
+	require.NoError(t, err)
+	assert.False(t, known)
+}
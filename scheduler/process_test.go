@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultProcessCatalog_MatchesBuiltInProcess(t *testing.T) {
+	for pieceType, steps := range DefaultProductionProcess() {
+		assert.Equal(t, steps, DefaultProcessCatalog.Steps(pieceType))
+	}
+
+	assert.Len(t, DefaultProcessCatalog.PieceTypes(), len(DefaultProductionProcess()))
+}
+
+func TestProcessCatalog_IsImmutable(t *testing.T) {
+	processes := map[PieceType][]ProductionStep{
+		PieceTypeMugWithoutHandle: DefaultProcessCatalog.Steps(PieceTypeMugWithoutHandle),
+	}
+
+	catalog, err := NewProcessCatalog(processes)
+	require.NoError(t, err)
+
+	processes[PieceTypeMugWithoutHandle][0].Rate = 99
+	steps := catalog.Steps(PieceTypeMugWithoutHandle)
+	steps[0].Rate = 99
+
+	assert.Equal(t, 5.0, catalog.Steps(PieceTypeMugWithoutHandle)[0].Rate, "Neither the source map nor returned steps can change the catalog")
+}
+
+func TestLoadProcessCatalog_YAML(t *testing.T) {
+	catalog, err := LoadProcessCatalog("testdata/process.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []PieceType{PieceTypeMugWithoutHandle, PieceTypeTrinketDish}, catalog.PieceTypes())
+	assert.Equal(t, 10.0, catalog.Steps(PieceTypeMugWithoutHandle)[0].Rate)
+	assert.False(t, catalog.HasProcess(PieceTypeTumbler))
+}
+
+func TestParseProcessCatalog_JSON(t *testing.T) {
+	data := []byte(`{"processes": {"trinket-dish": [
+		{"step_key": "build", "task_type": "task_build_base", "rate": 30, "drying_days": 2},
+		{"step_key": "bisque", "task_type": "task_bisque", "rate": 0, "drying_days": 5},
+		{"step_key": "fire", "task_type": "task_fire", "rate": 0, "drying_days": 0}
+	]}}`)
+
+	catalog, err := ParseProcessCatalog(data, "json")
+	require.NoError(t, err)
+	assert.Len(t, catalog.Steps(PieceTypeTrinketDish), 3)
+
+	_, err = ParseProcessCatalog(data, "toml")
+	assert.Error(t, err)
+}
+
+func TestNewProcessCatalog_Validation(t *testing.T) {
+	build := ProductionStep{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: 2}
+	glaze := ProductionStep{StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: 17}
+	bisque := ProductionStep{StepKey: StepKeyBisque, TaskType: TaskTypeBisque, DryingDays: 5}
+	fire := ProductionStep{StepKey: StepKeyFire, TaskType: TaskTypeFire, DryingDays: 5}
+
+	tests := []struct {
+		name      string
+		pieceType PieceType
+		steps     []ProductionStep
+		message   string
+	}{
+		{"Unknown piece type", "vase", []ProductionStep{build, fire}, "unknown piece type"},
+		{"No steps", PieceTypeTumbler, []ProductionStep{}, "has no steps"},
+		{"Unknown step key", PieceTypeTumbler, []ProductionStep{build, {StepKey: "polish", TaskType: TaskTypeTrim, Rate: 1}, fire}, "unknown step key"},
+		{"Unknown task type", PieceTypeTumbler, []ProductionStep{build, {StepKey: StepKeyGlaze, TaskType: "task_paint", Rate: 1}, fire}, "unknown task type"},
+		{"Task type from another step", PieceTypeTumbler, []ProductionStep{build, {StepKey: StepKeyGlaze, TaskType: TaskTypeTrim, Rate: 1}, fire}, "unknown task type"},
+		{"Glaze before bisque", PieceTypeTumbler, []ProductionStep{build, glaze, bisque, fire}, "out of order"},
+		{"Duplicate step", PieceTypeTumbler, []ProductionStep{build, build, fire}, "out of order"},
+		{"Negative rate", PieceTypeTumbler, []ProductionStep{build, {StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze, Rate: -1}, fire}, "negative rate"},
+		{"Hand step without rate", PieceTypeTumbler, []ProductionStep{build, {StepKey: StepKeyGlaze, TaskType: TaskTypeGlaze}, fire}, "needs a rate"},
+		{"Negative drying days", PieceTypeTumbler, []ProductionStep{{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, DryingDays: -1}, fire}, "negative drying days"},
+		{"Fire not last", PieceTypeTumbler, []ProductionStep{build, bisque, glaze}, "must end with fire"},
+		{"Build not first", PieceTypeTumbler, []ProductionStep{bisque, glaze, fire}, "must start with build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProcessCatalog(map[PieceType][]ProductionStep{tt.pieceType: tt.steps})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+
+	_, err := NewProcessCatalog(map[PieceType][]ProductionStep{})
+	assert.Error(t, err, "An empty catalog is rejected")
+}
+
+func TestLoadConfiguredProcessCatalog(t *testing.T) {
+	t.Setenv("PRODUCTION_PROCESS_FILE", "")
+	t.Setenv("PRODUCTION_PROCESS_SOURCE", "")
+
+	catalog, err := LoadConfiguredProcessCatalog()
+	require.NoError(t, err)
+	assert.Same(t, DefaultProcessCatalog, catalog)
+
+	t.Setenv("PRODUCTION_PROCESS_FILE", "testdata/process.yaml")
+
+	catalog, err = LoadConfiguredProcessCatalog()
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, catalog.Steps(PieceTypeMugWithoutHandle)[0].Rate)
+}
+
+func TestSetProcessHistory_DrivesCalculateHours(t *testing.T) {
+	catalog, err := LoadProcessCatalog("testdata/process.yaml")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { SetProcessHistory(nil) })
+	SetProcessHistory(catalog.history())
+
+	assert.InDelta(t, 4.0, CalculateHours(TaskTypeBuildBase, PieceTypeMugWithoutHandle, 10), 0.001, "10 per shift at the configured rate")
+	assert.Equal(t, 0.0, CalculateHours(TaskTypeBuildBase, PieceTypeTumbler, 10), "Piece types missing from the catalog have no steps")
+
+	SetProcessHistory(nil)
+	assert.InDelta(t, 8.0, CalculateHours(TaskTypeBuildBase, PieceTypeMugWithoutHandle, 10), 0.001, "Resetting restores the defaults")
+}
+
+func TestInstallConfiguredProcess_DrivesEstimatesAndValidation(t *testing.T) {
+	t.Cleanup(func() {
+		installed = false
+		SetProcessHistory(nil)
+		SetCalendar(nil)
+	})
+	t.Setenv("PRODUCTION_PROCESS_FILE", "testdata/process.yaml")
+
+	require.NoError(t, InstallConfiguredProcess())
+
+	assert.InDelta(t, 4.0, CalculateHours(TaskTypeBuildBase, PieceTypeMugWithoutHandle, 10), 0.001, "Estimates use the configured rate")
+	assert.Error(t, ValidateOrderDetailProcess(orders.OrderDetailDTO{ID: "detail-1", Type: string(PieceTypeTumbler), Quantity: 1}), "Validation uses the configured piece types")
+
+	_, err := CalculateCompletionDate(orders.OrderDetailDTO{ID: "detail-1", Type: string(PieceTypeTumbler), Quantity: 1, Status: string(StepKeyPending)}, time.Now())
+	assert.Error(t, err, "Completion dates use the configured piece types")
+
+	t.Setenv("PRODUCTION_PROCESS_FILE", "testdata/missing.yaml")
+	assert.NoError(t, InstallConfiguredProcess(), "The configuration is only loaded once")
+}
+
+func TestScheduler_UsesItsProcessCatalog(t *testing.T) {
+	catalog, err := LoadProcessCatalog("testdata/process.yaml")
+	require.NoError(t, err)
+
+	asOf := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	newPreview := func(catalog *ProcessCatalog) SchedulePreview {
+		scheduler := NewScheduler(
+			NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithoutHandle, 10, asOf.AddDate(0, 0, 20))),
+			NewInMemoryAvailabilitySource(nil),
+			NewInMemoryTaskStore(),
+		).WithClock(NewFixedClock(asOf)).WithProcessCatalog(catalog)
+
+		preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 1})
+		require.NoError(t, err)
+		require.NotEmpty(t, preview.Days)
+		require.NotEmpty(t, preview.Days[0].Tasks)
+
+		return preview
+	}
+
+	assert.Equal(t, 5, newPreview(DefaultProcessCatalog).Days[0].Tasks[0].Quantity, "Four hours builds five mugs at the default rate")
+	assert.Equal(t, 10, newPreview(catalog).Days[0].Tasks[0].Quantity, "The configured rate doubles Monday's output")
+}
@@ -0,0 +1,287 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/availability"
+	"aliciapceramics/legacy/server/orders"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const quoteOrderId = "quote"
+
+// QuoteBacklogTTL is how long capacity quotes reuse the backlog and
+// availability they loaded. Anyone can ask for a quote, so the studio's
+// records are read at most once per TTL however often quotes are requested.
+const QuoteBacklogTTL = 5 * time.Minute
+
+var quoteBacklog = &backlogCache{ttl: QuoteBacklogTTL}
+
+// QuoteInputError is an order detail the configured production process can't
+// plan. Item counts from one.
+type QuoteInputError struct {
+	Item int
+	Err  error
+}
+
+func (e *QuoteInputError) Error() string {
+	return fmt.Sprintf("item %d can't be quoted: %v", e.Item, e.Err)
+}
+
+func (e *QuoteInputError) Unwrap() error {
+	return e.Err
+}
+
+// QuoteWithCapacity installs the configured production process, checks the
+// order details against it and quotes them behind the studio's backlog. Details
+// the process can't plan are reported as a *QuoteInputError.
+func QuoteWithCapacity(orderDetails []orders.OrderDetailDTO, options RunOptions) (CapacityQuote, error) {
+	if err := InstallConfiguredProcess(); err != nil {
+		return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteWithCapacity] %w", err)
+	}
+
+	for i, orderDetail := range orderDetails {
+		if _, isValidPieceType := IsValidPieceType(orderDetail.Type); !isValidPieceType {
+			return CapacityQuote{}, &QuoteInputError{Item: i + 1, Err: fmt.Errorf("piece type %s is not available", orderDetail.Type)}
+		}
+
+		if err := ValidateOrderDetailProcess(orderDetail); err != nil {
+			return CapacityQuote{}, &QuoteInputError{Item: i + 1, Err: err}
+		}
+	}
+
+	if options.HorizonWeeks == 0 {
+		options.HorizonWeeks = MaxHorizonWeeks
+	}
+
+	now := SystemClock.Now()
+	asOf := now
+	if options.AsOf != nil {
+		asOf = *options.AsOf
+	}
+
+	startDate, endDate := getPlanningWindow(asOf, options.HorizonWeeks)
+
+	availabilityRepo := availability.NewSupabaseAvailabilityRepository()
+	snapshot, err := quoteBacklog.load(&orders.OrderService{}, availability.NewAvailabilityService(availabilityRepo), startDate, endDate, now)
+	if err != nil {
+		return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteWithCapacity] %w", err)
+	}
+
+	scheduler := NewScheduler(snapshot, snapshot, NewInMemoryTaskStore()).WithClock(NewFixedClock(now))
+
+	return scheduler.QuoteCapacity(orderDetails, options)
+}
+
+// QuoteLeadTime quotes the standard lead time for order details when the
+// backlog can't be planned. It needs the process QuoteWithCapacity installed,
+// so quotes never fall back to the compiled-in catalog. Lead time quotes
+// aren't checked against the backlog, so they are never fully scheduled.
+func QuoteLeadTime(orderDetails []orders.OrderDetailDTO, now time.Time) (CapacityQuote, error) {
+	if !processInstalled() {
+		return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteLeadTime] the configured production process isn't installed")
+	}
+
+	quote := CapacityQuote{Details: []DetailQuote{}}
+
+	for _, orderDetail := range orderDetails {
+		standardQuote, err := QuoteCompletionDate(orderDetail, now)
+		if err != nil {
+			return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteLeadTime] %w", err)
+		}
+
+		quote.Details = append(quote.Details, DetailQuote{
+			OrderDetailId: orderDetail.ID,
+			PieceType:     PieceType(orderDetail.Type),
+			Quantity:      orderDetail.Quantity,
+			Date:          standardQuote.Date,
+		})
+		quote.CatalogVersion = standardQuote.CatalogVersion
+
+		if standardQuote.Date.After(quote.Date) {
+			quote.Date = standardQuote.Date
+		}
+	}
+
+	return quote, nil
+}
+
+// backlogSnapshot is the open orders and the availability over one planning
+// window, as they were when it was loaded
+type backlogSnapshot struct {
+	deadlineOrders    orders.OrdersDTO
+	nonDeadlineOrders orders.OrdersDTO
+	availability      []availability.AvailabilityDTO
+	startDate         string
+	endDate           string
+	loadedAt          time.Time
+}
+
+func (s *backlogSnapshot) GetOrdersWithDeadlines() (orders.OrdersDTO, error) {
+	return s.deadlineOrders, nil
+}
+
+func (s *backlogSnapshot) GetNonDeadlineOrders() (orders.OrdersDTO, error) {
+	return s.nonDeadlineOrders, nil
+}
+
+func (s *backlogSnapshot) GetAvailability(startDate, endDate string) ([]availability.AvailabilityDTO, error) {
+	days := []availability.AvailabilityDTO{}
+
+	for _, day := range s.availability {
+		if day.Date >= startDate && day.Date <= endDate {
+			days = append(days, day)
+		}
+	}
+
+	return days, nil
+}
+
+type backlogCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	snapshot *backlogSnapshot
+}
+
+// load returns the cached snapshot while it's fresh and covers the same
+// window, and reads the backlog again otherwise. Concurrent quotes wait for
+// a single load rather than each reading the backlog.
+func (c *backlogCache) load(orderSource OrderSource, availabilitySource AvailabilitySource, startDate, endDate time.Time, now time.Time) (*backlogSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := startDate.Format("2006-01-02")
+	end := endDate.Format("2006-01-02")
+
+	if c.snapshot != nil && c.snapshot.startDate == start && c.snapshot.endDate == end && now.Sub(c.snapshot.loadedAt) < c.ttl {
+		return c.snapshot, nil
+	}
+
+	deadlineOrders, err := orderSource.GetOrdersWithDeadlines()
+	if err != nil {
+		return nil, fmt.Errorf("[backlogCache:load] failed to fetch orders with deadlines: %w", err)
+	}
+
+	nonDeadlineOrders, err := orderSource.GetNonDeadlineOrders()
+	if err != nil {
+		return nil, fmt.Errorf("[backlogCache:load] failed to fetch orders without deadlines: %w", err)
+	}
+
+	days, err := availabilitySource.GetAvailability(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("[backlogCache:load] failed to get availability for %s through %s: %w", start, end, err)
+	}
+
+	c.snapshot = &backlogSnapshot{
+		deadlineOrders:    deadlineOrders,
+		nonDeadlineOrders: nonDeadlineOrders,
+		availability:      days,
+		startDate:         start,
+		endDate:           end,
+		loadedAt:          now,
+	}
+
+	return c.snapshot, nil
+}
+
+// QuoteCapacity plans the order details as a new order behind the existing
+// backlog, over the studio's actual availability, and quotes the date the
+// planner expects them to be done. Nothing is written to the task store.
+func (s *Scheduler) QuoteCapacity(orderDetails []orders.OrderDetailDTO, options RunOptions) (CapacityQuote, error) {
+	if len(orderDetails) == 0 {
+		return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteCapacity] at least one order detail is required")
+	}
+
+	if options.HorizonWeeks == 0 {
+		options.HorizonWeeks = MaxHorizonWeeks
+	}
+
+	input, err := s.planningInput(options)
+	if err != nil {
+		return CapacityQuote{}, err
+	}
+
+	details := make([]orders.OrderDetailDTO, len(orderDetails))
+	for i, detail := range orderDetails {
+		if detail.ID == "" {
+			detail.ID = fmt.Sprintf("%s-%d", quoteOrderId, i+1)
+		}
+
+		if detail.Status == "" {
+			detail.Status = string(StepKeyPending)
+		}
+
+		details[i] = detail
+	}
+
+	input.chains = append([][]TaskChainItem{}, input.chains...)
+	quoted := make(map[string][]string)
+
+	for _, detail := range details {
+		// The unconstrained estimate stands in for a due date, so the new order
+		// queues like any other order without a deadline. Its work is planned as
+		// soon as there's room though, since the quote is the earliest date the
+		// studio can offer.
+		estimate, err := calculateCompletionDate(input.process, input.calendar, quotedDetail(detail), input.now)
+		if err != nil {
+			return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteCapacity] %w", err)
+		}
+
+		chains, processes, err := calculateWorkChains(input.process, input.calendar, detail, estimate, input.now)
+		if err != nil {
+			return CapacityQuote{}, fmt.Errorf("[Scheduler:QuoteCapacity] %w", err)
+		}
+
+		input.process = input.process.withProcesses(processes)
+
+		for _, chain := range chains {
+			for j := range chain {
+				chain[j].OrderId = quoteOrderId
+				chain[j].DueDate = estimate
+				chain[j].WaitingSince = input.now
+				chain[j].Eager = true
+			}
+
+			quoted[detail.ID] = append(quoted[detail.ID], chain[0].chainKey())
+		}
+
+		input.chains = append(input.chains, chains...)
+	}
+
+	planner, remaining := input.run()
+
+	quote := CapacityQuote{
+		Details:        []DetailQuote{},
+		FullyScheduled: true,
+		CatalogVersion: input.process.Version(),
+	}
+
+	for _, detail := range details {
+		detailQuote := DetailQuote{
+			OrderDetailId:  detail.ID,
+			PieceType:      PieceType(detail.Type),
+			Quantity:       detail.Quantity,
+			Date:           input.now,
+			FullyScheduled: true,
+		}
+
+		// Components are made side by side, so the slowest one sets the date
+		for _, chainKey := range quoted[detail.ID] {
+			completion, _, fullyScheduled := planner.projectCompletion(chainKey, remaining)
+
+			if completion.After(detailQuote.Date) {
+				detailQuote.Date = completion
+			}
+			detailQuote.FullyScheduled = detailQuote.FullyScheduled && fullyScheduled
+		}
+
+		if detailQuote.Date.After(quote.Date) {
+			quote.Date = detailQuote.Date
+		}
+		quote.FullyScheduled = quote.FullyScheduled && detailQuote.FullyScheduled
+
+		quote.Details = append(quote.Details, detailQuote)
+	}
+
+	return quote, nil
+}
@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_QuoteCapacity(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	prospective := []orders.OrderDetailDTO{{Type: string(PieceTypeMugWithoutHandle), Quantity: 10}}
+
+	newQuoteScheduler := func(backlog ...orders.OrderDTO) (*Scheduler, *InMemoryTaskStore) {
+		availableHours := map[string]float64{}
+		for day := monday; day.Before(monday.AddDate(0, 0, 56)); day = day.AddDate(0, 0, 1) {
+			availableHours[day.Format("2006-01-02")] = 4
+		}
+
+		store := NewInMemoryTaskStore()
+		scheduler := NewScheduler(
+			NewInMemoryOrderSource(backlog...),
+			NewInMemoryAvailabilitySource(availableHours),
+			store,
+		).WithClock(NewFixedClock(monday))
+
+		return scheduler, store
+	}
+
+	idle, _ := newQuoteScheduler()
+	idleQuote, err := idle.QuoteCapacity(prospective, RunOptions{})
+	require.NoError(t, err)
+	require.Len(t, idleQuote.Details, 1)
+	assert.Equal(t, "quote-1", idleQuote.Details[0].OrderDetailId)
+	assert.True(t, idleQuote.FullyScheduled)
+	assert.Equal(t, idleQuote.Details[0].Date, idleQuote.Date)
+
+	standard, err := CalculateCompletionDate(orders.OrderDetailDTO{Type: string(PieceTypeMugWithoutHandle), Quantity: 10, Status: string(StepKeyPending)}, monday)
+	require.NoError(t, err)
+	assert.True(t, idleQuote.Date.Before(standard), "An idle studio beats the standard lead time")
+
+	backlog := []orders.OrderDTO{}
+	for i := 1; i <= 3; i++ {
+		backlog = append(backlog, newDeadlineOrder(fmt.Sprintf("order-%d", i), fmt.Sprintf("detail-%d", i), PieceTypeMugWithoutHandle, 30, monday.AddDate(0, 0, 21)))
+	}
+
+	busy, store := newQuoteScheduler(backlog...)
+	busyQuote, err := busy.QuoteCapacity(prospective, RunOptions{})
+	require.NoError(t, err)
+	assert.True(t, busyQuote.Date.After(idleQuote.Date), "Existing orders push the quote back")
+	assert.Empty(t, store.Tasks(), "Quoting doesn't create tasks")
+
+	_, err = busy.QuoteCapacity(nil, RunOptions{})
+	assert.ErrorContains(t, err, "at least one order detail")
+}
+
+func TestScheduler_QuoteCapacityPlansAsEarlyAsPossible(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	availableHours := map[string]float64{}
+	for day := monday; day.Before(monday.AddDate(0, 0, 56)); day = day.AddDate(0, 0, 1) {
+		availableHours[day.Format("2006-01-02")] = 4
+	}
+
+	idle := NewScheduler(
+		NewInMemoryOrderSource(),
+		NewInMemoryAvailabilitySource(availableHours),
+		NewInMemoryTaskStore(),
+	).WithClock(NewFixedClock(monday))
+
+	detail := orders.OrderDetailDTO{Type: string(PieceTypeTrinketDish), Quantity: 10, Status: string(StepKeyPending)}
+
+	quote, err := idle.QuoteCapacity([]orders.OrderDetailDTO{detail}, RunOptions{})
+	require.NoError(t, err)
+	require.True(t, quote.FullyScheduled)
+
+	standard, err := CalculateCompletionDate(detail, monday)
+	require.NoError(t, err)
+	assert.True(t, quote.Date.Before(standard), "An idle studio beats the standard lead time")
+
+	// Trinket dishes take less than the standard lead time to make. Started
+	// today, only the kiln's wait for a fuller load stands between the making
+	// and the quote, rather than the rest of the standard lead time.
+	making := processFinish(CurrentCalendar(), monday, DefaultProcessCatalog.Steps(PieceTypeTrinketDish), detail.Quantity)
+	assert.Less(t, quote.Date.Sub(making), standard.Sub(quote.Date), "The work starts today rather than just in time for the standard lead time")
+}
+
+func TestBacklogCache_ReusesSnapshotUntilStale(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	startDate, endDate := getPlanningWindow(monday, MaxHorizonWeeks)
+
+	orderSource := NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithoutHandle, 10, monday.AddDate(0, 0, 21)))
+	availabilitySource := NewInMemoryAvailabilitySource(map[string]float64{startDate.Format("2006-01-02"): 4})
+	cache := &backlogCache{ttl: 5 * time.Minute}
+
+	snapshot, err := cache.load(orderSource, availabilitySource, startDate, endDate, monday)
+	require.NoError(t, err)
+	require.Len(t, snapshot.deadlineOrders.Orders, 1)
+
+	orderSource.Orders = append(orderSource.Orders, newDeadlineOrder("order-2", "detail-2", PieceTypeMugWithoutHandle, 10, monday.AddDate(0, 0, 21)))
+
+	cached, err := cache.load(orderSource, availabilitySource, startDate, endDate, monday.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, cached.deadlineOrders.Orders, 1, "A fresh snapshot is reused")
+
+	nextStart, nextEnd := getPlanningWindow(monday.AddDate(0, 0, 7), MaxHorizonWeeks)
+	moved, err := cache.load(orderSource, availabilitySource, nextStart, nextEnd, monday.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, moved.deadlineOrders.Orders, 2, "A different window loads the backlog again")
+
+	stale, err := cache.load(orderSource, availabilitySource, nextStart, nextEnd, monday.Add(10*time.Minute))
+	require.NoError(t, err)
+	assert.NotSame(t, moved, stale, "A stale snapshot is loaded again")
+
+	days, err := snapshot.GetAvailability(startDate.Format("2006-01-02"), startDate.Format("2006-01-02"))
+	require.NoError(t, err)
+	assert.Len(t, days, 1)
+}
+
+func TestQuoteWithCapacity_ChecksDetailsAgainstTheConfiguredProcess(t *testing.T) {
+	t.Cleanup(func() {
+		installed = false
+		SetProcessHistory(nil)
+		SetCalendar(nil)
+	})
+
+	tumblers := []orders.OrderDetailDTO{{ID: "quote-1", Type: string(PieceTypeTumbler), Quantity: 4, Status: string(StepKeyPending)}}
+
+	_, err := QuoteLeadTime(tumblers, time.Now())
+	assert.ErrorContains(t, err, "isn't installed", "Lead times aren't quoted from the compiled-in catalog")
+
+	t.Setenv("PRODUCTION_PROCESS_FILE", "testdata/process.yaml")
+
+	_, err = QuoteWithCapacity(tumblers, RunOptions{})
+	var inputErr *QuoteInputError
+	require.ErrorAs(t, err, &inputErr, "The configured process has no tumblers")
+	assert.Equal(t, 1, inputErr.Item)
+
+	mugs := []orders.OrderDetailDTO{{ID: "quote-1", Type: string(PieceTypeMugWithoutHandle), Quantity: 10, Status: string(StepKeyPending)}}
+	quote, err := QuoteLeadTime(mugs, time.Now())
+	require.NoError(t, err)
+	assert.False(t, quote.FullyScheduled)
+	require.Len(t, quote.Details, 1)
+	assert.Equal(t, quote.Details[0].Date, quote.Date)
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"fmt"
+	"time"
+)
+
+const detailStatusCompleted = "completed"
+
+// calculateWorkChains adds a chain for each remake still in production to
+// the order detail's own chains. Remakes keep the order's due date, so the
+// time already spent on the lost pieces makes them urgent.
+func calculateWorkChains(catalog *ProcessCatalog, calendar *Calendar, orderDetail orders.OrderDetailDTO, dueDate time.Time, asOf time.Time) ([][]TaskChainItem, map[PieceType][]ProductionStep, error) {
+	chains := [][]TaskChainItem{}
+	processes := make(map[PieceType][]ProductionStep)
+
+	// A detail can finish before the remakes of its lost pieces do
+	if orderDetail.Status != detailStatusCompleted {
+		detailChains, detailProcesses, err := calculateDetailChains(catalog, calendar, orderDetail, dueDate, asOf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		chains = append(chains, detailChains...)
+		for pieceType, steps := range detailProcesses {
+			processes[pieceType] = steps
+		}
+	}
+
+	for _, remake := range orderDetail.Remakes {
+		if remake.Status == detailStatusCompleted {
+			continue
+		}
+
+		remakeChains, remakeProcesses, err := calculateDetailChains(catalog, calendar, remakeDetail(orderDetail, remake), dueDate, asOf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("remake %s: %w", remake.ID, err)
+		}
+
+		for _, chain := range remakeChains {
+			for i := range chain {
+				chain[i].DefectReportId = remake.ID
+			}
+		}
+
+		chains = append(chains, remakeChains...)
+		for pieceType, steps := range remakeProcesses {
+			processes[pieceType] = steps
+		}
+	}
+
+	return chains, processes, nil
+}
+
+// remakeDetail stands in for the order detail while a remake's pieces go
+// through the process again
+func remakeDetail(orderDetail orders.OrderDetailDTO, remake orders.RemakeDTO) orders.OrderDetailDTO {
+	detail := orderDetail
+	detail.Quantity = remake.Quantity
+	detail.Status = remake.Status
+	detail.CompletedQuantity = remake.CompletedQuantity
+	detail.StatusChangedAt = remake.StatusChangedAt
+	detail.StepProgress = remake.StepProgress
+	detail.Remakes = nil
+
+	return detail
+}
+
+// quotedDetail lets an order detail that has finished, but is still waiting
+// on remakes, be quoted as though it had not started
+func quotedDetail(orderDetail orders.OrderDetailDTO) orders.OrderDetailDTO {
+	if orderDetail.Status != detailStatusCompleted {
+		return orderDetail
+	}
+
+	detail := orderDetail
+	detail.Status = string(StepKeyPending)
+	detail.CompletedQuantity = 0
+	detail.StatusChangedAt = nil
+	detail.StepProgress = nil
+
+	return detail
+}
+
+func lostQuantity(orderDetail orders.OrderDetailDTO) int {
+	lost := 0
+	for _, remake := range orderDetail.Remakes {
+		lost += remake.Quantity
+	}
+
+	return lost
+}
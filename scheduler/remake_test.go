@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateWorkChains_Remakes(t *testing.T) {
+	dueDate := time.Now().AddDate(0, 0, 30)
+	detail := orders.OrderDetailDTO{
+		ID:       "detail",
+		Type:     string(PieceTypeMugWithoutHandle),
+		Quantity: 10,
+		Status:   string(StepKeyBisque),
+		Remakes: []orders.RemakeDTO{
+			{ID: "remake-1", StepKey: string(StepKeyBisque), Quantity: 2, Status: string(StepKeyPending)},
+			{ID: "remake-2", StepKey: string(StepKeyBuild), Quantity: 1, Status: detailStatusCompleted},
+		},
+	}
+
+	chains, _, err := calculateWorkChains(DefaultProcessCatalog, DefaultCalendar, detail, dueDate, time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 2, "Finished remakes aren't planned again")
+
+	main, remake := chains[0], chains[1]
+	assert.Equal(t, StepKeyBisque, main[0].OrderDetailStatus)
+	assert.Equal(t, 7, main[0].Quantity, "Every lost piece comes out of the batch")
+	assert.Empty(t, main[0].DefectReportId)
+
+	assert.Equal(t, StepKeyBuild, remake[0].OrderDetailStatus, "Remakes start again from the build step")
+	assert.Equal(t, 2, remake[0].Quantity)
+	assert.Equal(t, "remake-1", remake[0].DefectReportId)
+	assert.NotEqual(t, main[0].chainKey(), remake[0].chainKey())
+	assert.True(t, remake[len(remake)-1].StartDate.Equal(main[len(main)-1].StartDate), "Remakes are planned against the original due date")
+	assert.True(t, remake[0].StartDate.Before(main[0].StartDate))
+
+	detail.Status = detailStatusCompleted
+	chains, _, err = calculateWorkChains(DefaultProcessCatalog, DefaultCalendar, detail, dueDate, time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 1, "A finished detail still plans its remakes")
+	assert.Equal(t, "remake-1", chains[0][0].DefectReportId)
+}
+
+func TestScheduler_SchedulesRemakeTasks(t *testing.T) {
+	order := newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithoutHandle, 6, time.Now().AddDate(0, 0, 12))
+	order.OrderDetails[0].Status = detailStatusCompleted
+	order.OrderDetails[0].Remakes = []orders.RemakeDTO{{ID: "remake-1", StepKey: string(StepKeyFire), Quantity: 3, Status: string(StepKeyPending)}}
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(order),
+		NewInMemoryAvailabilitySource(flatAvailability(4, 4)),
+		NewInMemoryTaskStore(),
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+	require.NotEmpty(t, preview.Days)
+
+	first := preview.Days[0].Tasks[0]
+	assert.Equal(t, TaskTypeBuildBase, first.TaskType)
+	assert.Equal(t, 3, first.Quantity)
+	assert.Equal(t, "remake-1", first.DefectReportId)
+	assert.True(t, first.IsLate, "The remake has less time than the process needs before the original due date")
+
+	require.NotEmpty(t, preview.Lateness)
+	assert.Equal(t, "remake-1", preview.Lateness[0].DefectReportId)
+}
@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+type Snapshot struct {
+	AsOf         time.Time          `json:"as_of"`
+	Orders       []orders.OrderDTO  `json:"orders"`
+	Availability map[string]float64 `json:"availability"`
+}
+
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("[Snapshot:Load] failed to read %s: %w", path, err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("[Snapshot:Load] failed to parse %s: %w", path, err)
+	}
+
+	if snapshot.AsOf.IsZero() {
+		return Snapshot{}, fmt.Errorf("[Snapshot:Load] %s is missing as_of", path)
+	}
+
+	return snapshot, nil
+}
+
+func (s Snapshot) Scheduler() *Scheduler {
+	return NewScheduler(
+		NewInMemoryOrderSource(s.Orders...),
+		NewInMemoryAvailabilitySource(s.Availability),
+		NewInMemoryTaskStore(),
+	).WithClock(NewFixedClock(s.AsOf))
+}
+
+// Snapshot captures the orders and availability a run would plan from
+func (s *Scheduler) Snapshot(options RunOptions) (Snapshot, error) {
+	now := s.now(options)
+	startDate, endDate := getPlanningWindow(now, options.HorizonWeeks)
+
+	deadlineOrders, err := s.orders.GetOrdersWithDeadlines()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("[Scheduler:Snapshot] error: %w", err)
+	}
+
+	nonDeadlineOrders, err := s.orders.GetNonDeadlineOrders()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("[Scheduler:Snapshot] error: %w", err)
+	}
+
+	capacityByDate, err := loadAvailability(s.availability, startDate, endDate)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("[Scheduler:Snapshot] error: %w", err)
+	}
+
+	return Snapshot{
+		AsOf:         now,
+		Orders:       append(deadlineOrders.Orders, nonDeadlineOrders.Orders...),
+		Availability: capacityByDate,
+	}, nil
+}
+
+func Replay(snapshot Snapshot, options RunOptions, completed CompletedTaskSource) (ReplayResult, error) {
+	return snapshot.Scheduler().Replay(options, completed)
+}
+
+// ReplayStored replays the snapshot stored on asOf with the installed catalog
+// and calendar. It reports false when no run was stored that day.
+func ReplayStored(asOf time.Time, options RunOptions) (ReplayResult, bool, error) {
+	scheduler, found, err := storedSnapshotScheduler(asOf)
+	if err != nil || !found {
+		return ReplayResult{}, found, err
+	}
+
+	result, err := scheduler.Replay(options, &supabaseTaskStore{})
+	return result, true, err
+}
+
+func SimulateStored(asOf time.Time, options RunOptions, simulation SimulationOptions) (SimulationResult, bool, error) {
+	scheduler, found, err := storedSnapshotScheduler(asOf)
+	if err != nil || !found {
+		return SimulationResult{}, found, err
+	}
+
+	result, err := scheduler.Simulate(options, simulation)
+	return result, true, err
+}
+
+func storedSnapshotScheduler(asOf time.Time) (*Scheduler, bool, error) {
+	snapshot, found, err := GetSnapshot(asOf)
+	if err != nil {
+		return nil, false, fmt.Errorf("[Snapshot:Replay] failed to load snapshot for %s: %w", asOf.Format("2006-01-02"), err)
+	}
+
+	if !found {
+		return nil, false, nil
+	}
+
+	if err := InstallConfiguredProcess(); err != nil {
+		return nil, true, fmt.Errorf("[Snapshot:Replay] %w", err)
+	}
+
+	return snapshot.Scheduler(), true, nil
+}
+
+// Replay previews the schedule and sets each day's planned tasks beside the
+// tasks that were actually completed that day
+func (s *Scheduler) Replay(options RunOptions, completed CompletedTaskSource) (ReplayResult, error) {
+	preview, err := s.Preview(options)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+
+	startDate, _ := getPlanningWindow(s.now(options), options.HorizonWeeks)
+
+	actual, err := completed.GetCompletedTasks(startDate)
+	if err != nil {
+		return ReplayResult{}, fmt.Errorf("[Scheduler:Replay] failed to load completed tasks: %w", err)
+	}
+
+	return ReplayResult{
+		Preview:    preview,
+		Comparison: compareTasks(preview, actual),
+	}, nil
+}
+
+type taskComparisonKey struct {
+	date          string
+	orderDetailId string
+	taskType      TaskType
+}
+
+func compareTasks(preview SchedulePreview, actual []CompletedTask) []TaskComparison {
+	comparisons := make(map[taskComparisonKey]*TaskComparison)
+
+	comparisonFor := func(key taskComparisonKey) *TaskComparison {
+		comparison, exists := comparisons[key]
+		if !exists {
+			comparison = &TaskComparison{Date: key.date, OrderDetailId: key.orderDetailId, TaskType: key.taskType}
+			comparisons[key] = comparison
+		}
+
+		return comparison
+	}
+
+	for _, day := range preview.Days {
+		for _, task := range day.Tasks {
+			comparison := comparisonFor(taskComparisonKey{day.Date, task.OrderDetailId, task.TaskType})
+			comparison.PlannedQuantity += task.Quantity
+			comparison.PlannedHours += task.EstimatedHours
+		}
+	}
+
+	for _, task := range actual {
+		date := task.CompletedAt.Format("2006-01-02")
+		if date < preview.StartDate || date > preview.EndDate {
+			continue
+		}
+
+		comparison := comparisonFor(taskComparisonKey{date, task.OrderDetailId, task.TaskType})
+		comparison.ActualQuantity += task.Quantity
+		comparison.ActualHours += task.ActualHours
+	}
+
+	result := make([]TaskComparison, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		result = append(result, *comparison)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date < result[j].Date
+		}
+		if result[i].OrderDetailId != result[j].OrderDetailId {
+			return result[i].OrderDetailId < result[j].OrderDetailId
+		}
+		return result[i].TaskType < result[j].TaskType
+	})
+
+	return result
+}
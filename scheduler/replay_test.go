@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay_SnapshotIsPlannedAsOfItsDate(t *testing.T) {
+	snapshot, err := LoadSnapshot("testdata/snapshot_2025-03-03.json")
+	require.NoError(t, err)
+
+	result, err := Replay(snapshot, RunOptions{HorizonWeeks: 2}, NewInMemoryTaskStore())
+	require.NoError(t, err)
+
+	preview := result.Preview
+
+	assert.Equal(t, "2025-03-03", preview.StartDate)
+	assert.Equal(t, "2025-03-15", preview.EndDate)
+	require.NotEmpty(t, preview.Days)
+	assert.Equal(t, "2025-03-03", preview.Days[0].Date)
+
+	for _, day := range preview.Days {
+		assert.NotEqual(t, "2025-03-04", day.Date, "The snapshot took Tuesday off")
+
+		for _, task := range day.Tasks {
+			assert.Equal(t, day.Date, task.Date.Format("2006-01-02"))
+		}
+	}
+
+	again, err := Replay(snapshot, RunOptions{HorizonWeeks: 2}, NewInMemoryTaskStore())
+	require.NoError(t, err)
+	assert.Equal(t, result, again, "Replays of the same snapshot should be identical")
+}
+
+func TestReplay_ComparesPlannedWithCompletedTasks(t *testing.T) {
+	snapshot, err := LoadSnapshot("testdata/snapshot_2025-03-03.json")
+	require.NoError(t, err)
+
+	completed := NewInMemoryTaskStore()
+	completed.AddCompletedTasks(
+		CompletedTask{OrderDetailId: "detail-wedding-mugs", TaskType: TaskTypeBuildBase, PieceType: PieceTypeMugWithoutHandle, Quantity: 4, ActualHours: 3, CompletedAt: time.Date(2025, 3, 3, 17, 0, 0, 0, time.UTC)},
+		CompletedTask{OrderDetailId: "detail-wedding-mugs", TaskType: TaskTypeBuildBase, PieceType: PieceTypeMugWithoutHandle, Quantity: 2, ActualHours: 1, CompletedAt: time.Date(2025, 2, 28, 17, 0, 0, 0, time.UTC)},
+	)
+
+	result, err := Replay(snapshot, RunOptions{HorizonWeeks: 2}, completed)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Comparison)
+
+	var monday *TaskComparison
+	for i, comparison := range result.Comparison {
+		assert.GreaterOrEqual(t, comparison.Date, "2025-03-03", "Work finished before the snapshot isn't compared")
+
+		if comparison.Date == "2025-03-03" && comparison.OrderDetailId == "detail-wedding-mugs" && comparison.TaskType == TaskTypeBuildBase {
+			monday = &result.Comparison[i]
+		}
+	}
+
+	require.NotNil(t, monday, "Work done on a day the replay didn't plan is still compared")
+	assert.Equal(t, 0, monday.PlannedQuantity, "The replay builds the mugs later in the week")
+	assert.Equal(t, 4, monday.ActualQuantity)
+	assert.InDelta(t, 3.0, monday.ActualHours, 0.001)
+}
+
+func TestScheduler_RunStoresASnapshotOfItsInputs(t *testing.T) {
+	asOf := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	store := NewInMemoryTaskStore()
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithHandle, 5, asOf.AddDate(0, 0, 30))),
+		NewInMemoryAvailabilitySource(map[string]float64{"2025-03-04": 0}),
+		store,
+	).WithClock(NewFixedClock(asOf))
+
+	_, err := scheduler.Run(RunOptions{HorizonWeeks: 1})
+	require.NoError(t, err)
+
+	snapshots := store.Snapshots()
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, asOf, snapshots[0].AsOf)
+	require.Len(t, snapshots[0].Orders, 1)
+	assert.Equal(t, "order-1", snapshots[0].Orders[0].ID)
+	assert.Equal(t, 0.0, snapshots[0].Availability["2025-03-04"])
+
+	replayed, err := Replay(snapshots[0], RunOptions{HorizonWeeks: 1}, NewInMemoryTaskStore())
+	require.NoError(t, err)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 1})
+	require.NoError(t, err)
+	assert.Equal(t, preview, replayed.Preview, "Replaying the stored snapshot plans the same week")
+}
+
+func TestLoadSnapshot_RequiresAsOf(t *testing.T) {
+	path := t.TempDir() + "/snapshot.json"
+	require.NoError(t, os.WriteFile(path, []byte(`{"orders": []}`), 0o600))
+
+	_, err := LoadSnapshot(path)
+	assert.Error(t, err)
+}
+
+func TestScheduler_PreviewUsesInjectedClock(t *testing.T) {
+	asOf := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+	dueDate := asOf.AddDate(0, 0, 30)
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithHandle, 5, dueDate)),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	).WithClock(NewFixedClock(asOf))
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-03-03", preview.StartDate, "Sunday as-of dates start planning on Monday")
+	assert.Equal(t, "2025-03-08", preview.EndDate)
+
+	override := time.Date(2025, 6, 10, 10, 0, 0, 0, time.UTC)
+	preview, err = scheduler.Preview(RunOptions{HorizonWeeks: 1, AsOf: &override})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-06-10", preview.StartDate, "AsOf takes precedence over the clock")
+}
+
+func TestScheduler_RunRejectsAsOf(t *testing.T) {
+	store := NewInMemoryTaskStore()
+	require.NoError(t, store.InsertTasks([]TaskToCreate{{OrderDetailId: "existing", TaskType: TaskTypeTrim, Quantity: 1}}))
+
+	asOf := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	scheduler := NewScheduler(NewInMemoryOrderSource(), NewInMemoryAvailabilitySource(nil), store)
+
+	_, err := scheduler.Run(RunOptions{AsOf: &asOf})
+	assert.Error(t, err)
+	assert.Len(t, store.Tasks(), 1, "Historical runs must not touch stored tasks")
+}
+
+func TestCalculateTaskChainAsOf_DryingMeasuredFromAsOf(t *testing.T) {
+	statusChangedAt := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	dueDate := statusChangedAt.AddDate(0, 0, 30)
+
+	detail := orders.OrderDetailDTO{
+		ID:              "tumbler-attached",
+		Type:            string(PieceTypeTumbler),
+		Quantity:        10,
+		Status:          string(StepKeyAttach),
+		StatusChangedAt: &statusChangedAt,
+	}
+
+	tasks, err := CalculateTaskChainAsOf(detail, dueDate, statusChangedAt.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, tasks, "Pieces still drying on the as-of date have nothing to schedule")
+
+	tasks, err = CalculateTaskChainAsOf(detail, dueDate, statusChangedAt.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	require.NotEmpty(t, tasks)
+	assert.Equal(t, StepKeyTrimFinal, tasks[0].OrderDetailStatus, "Once dry, the chain resumes at the next step")
+}
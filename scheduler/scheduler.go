@@ -8,365 +8,561 @@ import (
 	"time"
 )
 
-func Run() error {
+const DefaultHorizonWeeks = 4
+const MaxHorizonWeeks = 8
+
+type RunOptions struct {
+	HorizonWeeks int
+	Kiln         *KilnConfig
+	Priority     *PriorityOptions
+	Overtime     *OvertimeOptions
+	Changeover   *ChangeoverOptions
+	AsOf         *time.Time
+
+	// CatalogVersion recomputes a preview against a past catalog instead of
+	// the one in force on the planning date
+	CatalogVersion string
+}
+
+type schedulePlan struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Schedule  WeekSchedule
+	KilnLoads []KilnLoad
+	Lateness  []LatenessEntry
+	Overtime  *OvertimePlan
+
+	CatalogVersion string
+}
 
-	orders := orders.OrderService{}
+type scheduleWork struct {
+	chains            [][]TaskChainItem
+	processes         map[PieceType][]ProductionStep
+	deadlineOrders    int
+	nonDeadlineOrders int
+}
+
+type planningInput struct {
+	startDate      time.Time
+	endDate        time.Time
+	capacityByDate map[string]float64
+	kiln           KilnConfig
+	priority       PriorityOptions
+	changeover     ChangeoverOptions
+	chains         [][]TaskChainItem
+	process        *ProcessCatalog
+	calendar       *Calendar
+	now            time.Time
+
+	deadlineOrders    int
+	nonDeadlineOrders int
+}
 
-	if err := DeletePendingTasks(); err != nil {
-		return err
+type Scheduler struct {
+	orders       OrderSource
+	availability AvailabilitySource
+	tasks        TaskStore
+	clock        Clock
+	history      *ProcessHistory
+	calendar     *Calendar
+}
+
+func NewScheduler(orders OrderSource, availability AvailabilitySource, tasks TaskStore) *Scheduler {
+	return &Scheduler{
+		orders:       orders,
+		availability: availability,
+		tasks:        tasks,
+		clock:        SystemClock,
 	}
+}
 
-	startDate, endDate := getNextWeek()
+func (s *Scheduler) WithClock(clock Clock) *Scheduler {
+	s.clock = clock
+	return s
+}
+
+func (s *Scheduler) WithProcessCatalog(catalog *ProcessCatalog) *Scheduler {
+	s.history = catalog.history()
+	return s
+}
+
+func (s *Scheduler) WithProcessHistory(history *ProcessHistory) *Scheduler {
+	s.history = history
+	return s
+}
+
+func (s *Scheduler) WithCalendar(calendar *Calendar) *Scheduler {
+	s.calendar = calendar
+	return s
+}
+
+func (s *Scheduler) processCatalog(options RunOptions, now time.Time) (*ProcessCatalog, error) {
+	history := s.history
+	if history == nil {
+		history = CurrentProcessHistory()
+	}
+
+	if options.CatalogVersion == "" {
+		return history.At(now)
+	}
+
+	catalog, exists := history.Version(options.CatalogVersion)
+	if !exists {
+		return nil, fmt.Errorf("[Scheduler run] unknown catalog version %q", options.CatalogVersion)
+	}
+
+	return catalog, nil
+}
+
+func (s *Scheduler) now(options RunOptions) time.Time {
+	if options.AsOf != nil {
+		return *options.AsOf
+	}
+
+	return s.clock.Now()
+}
+
+func NewSupabaseScheduler() (*Scheduler, error) {
+	if err := InstallConfiguredProcess(); err != nil {
+		return nil, fmt.Errorf("[Scheduler run] %w", err)
+	}
 
 	availabilityRepo := availability.NewSupabaseAvailabilityRepository()
-	availabilityService := availability.NewAvailabilityService(availabilityRepo)
 
-	deadlineOrders, err := orders.GetOrdersWithDeadlines()
+	return NewScheduler(
+		&orders.OrderService{},
+		availability.NewAvailabilityService(availabilityRepo),
+		NewSupabaseTaskStore(),
+	), nil
+}
 
+func Run(options RunOptions) (SchedulerResult, error) {
+	scheduler, err := NewSupabaseScheduler()
 	if err != nil {
-		return fmt.Errorf("[Scheduler run] error: %w", err)
+		return SchedulerResult{}, err
 	}
 
-	tasksWithDeadlines := []TaskChainItem{}
-	orderDetailCurrentStatus := make(map[string]StepKey)
+	return scheduler.Run(options)
+}
 
-	for _, order := range deadlineOrders.Orders {
-		for _, detail := range order.OrderDetails {
-			newTasks, err := CalculateTaskChain(detail, *order.DueDate)
+func Preview(options RunOptions) (SchedulePreview, error) {
+	scheduler, err := NewSupabaseScheduler()
+	if err != nil {
+		return SchedulePreview{}, err
+	}
 
-			if err != nil {
-				return fmt.Errorf("failed to calculate task chain for order detail %s with error %w", detail.ID, err)
-			}
+	return scheduler.Preview(options)
+}
 
-			if len(newTasks) > 0 {
-				orderDetailCurrentStatus[detail.ID] = newTasks[0].OrderDetailStatus
-			}
+func (s *Scheduler) Run(options RunOptions) (SchedulerResult, error) {
 
-			tasksWithDeadlines = append(tasksWithDeadlines, newTasks...)
-		}
+	if options.AsOf != nil {
+		return SchedulerResult{}, fmt.Errorf("[Scheduler run] as-of schedules can only be previewed, got %s", options.AsOf.Format("2006-01-02"))
+	}
+
+	if options.CatalogVersion != "" {
+		return SchedulerResult{}, fmt.Errorf("[Scheduler run] schedules against catalog version %s can only be previewed", options.CatalogVersion)
+	}
+
+	// The inputs are kept so the run can be replayed against what happened
+	snapshot, err := s.Snapshot(options)
+	if err != nil {
+		return SchedulerResult{}, err
 	}
 
-	weekSchedule := make(WeekSchedule)
-	orderDetailLastCompletion := make(map[string]time.Time)
+	// The plan is built before anything is cleared, so a run that can't plan
+	// leaves the last schedule and lateness report in place
+	plan, err := s.buildSchedule(options)
 
-	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
-		if len(tasksWithDeadlines) == 0 {
-			break
+	if err != nil {
+		return SchedulerResult{}, err
+	}
+
+	if err := s.tasks.InsertSnapshot(snapshot); err != nil {
+		return SchedulerResult{}, fmt.Errorf("failed to save schedule snapshot with error %w", err)
+	}
+
+	if err := s.tasks.DeletePendingTasks(); err != nil {
+		return SchedulerResult{}, err
+	}
+
+	// Each run's report replaces the last, so orders that have caught up drop off it
+	if err := s.tasks.DeleteLatenessReports(); err != nil {
+		return SchedulerResult{}, err
+	}
+
+	tasksToInsert := plan.Schedule.AllTasks()
+
+	if len(tasksToInsert) > 0 {
+		if err := s.tasks.InsertTasks(tasksToInsert); err != nil {
+			return SchedulerResult{}, fmt.Errorf("failed to insert tasks for %s through %s with error %w", plan.StartDate.Format("2006-01-02"), plan.EndDate.Format("2006-01-02"), err)
 		}
+	}
 
-		capacity, err := availabilityService.GetAvailabilityForDate(day)
-		if err != nil {
-			return fmt.Errorf("[Scheduler run] failed to get availability for %s: %w", day.Format("2006-01-02"), err)
+	if len(plan.Lateness) > 0 {
+		if err := s.tasks.InsertLatenessReport(plan.Lateness); err != nil {
+			return SchedulerResult{}, fmt.Errorf("failed to save lateness report with error %w", err)
 		}
+	}
 
-		dayTasks := []TaskToCreate{}
-		var dayFocus StepKey
+	return SchedulerResult{
+		Success:        true,
+		TasksCreated:   len(tasksToInsert),
+		Lateness:       plan.Lateness,
+		Overtime:       plan.Overtime,
+		CatalogVersion: plan.CatalogVersion,
+	}, nil
+}
 
-		if capacity <= 0 {
-			continue
-		}
+func (s *Scheduler) Preview(options RunOptions) (SchedulePreview, error) {
 
-		anyTaskScheduled := false
+	plan, err := s.buildSchedule(options)
 
-		for i := 0; i < len(tasksWithDeadlines); i++ {
-			task := tasksWithDeadlines[i]
+	if err != nil {
+		return SchedulePreview{}, err
+	}
 
-			currentStatus, exists := orderDetailCurrentStatus[task.OrderDetailId]
-			if !exists || task.OrderDetailStatus != currentStatus {
-				continue
-			}
+	return plan.Preview(), nil
+}
 
-			earliestPossibleStart := task.StartDate
-			if lastCompletion, exists := orderDetailLastCompletion[task.OrderDetailId]; exists {
-				if lastCompletion.After(earliestPossibleStart) {
-					earliestPossibleStart = lastCompletion
-				}
-			}
+func (p schedulePlan) Preview() SchedulePreview {
+	preview := SchedulePreview{
+		StartDate:      p.StartDate.Format("2006-01-02"),
+		EndDate:        p.EndDate.Format("2006-01-02"),
+		Days:           []DayPreview{},
+		KilnLoads:      []KilnLoad{},
+		Lateness:       []LatenessEntry{},
+		CatalogVersion: p.CatalogVersion,
+	}
 
-			if earliestPossibleStart.After(day) {
-				if day.Equal(endDate) {
-					tasksWithDeadlines = append(tasksWithDeadlines[:i], tasksWithDeadlines[i+1:]...)
-					i -= 1
-				}
-				continue
-			}
+	for _, day := range p.Schedule.Days() {
+		daySchedule := p.Schedule[day]
 
-			isExternalProcess := task.TaskType == TaskTypeBisque || task.TaskType == TaskTypeFire
+		tasks := daySchedule.Tasks
+		if tasks == nil {
+			tasks = []TaskToCreate{}
+		}
 
-			if dayFocus == "" {
-				if !isExternalProcess {
-					dayFocus = task.OrderDetailStatus
-				}
-			} else if task.OrderDetailStatus != dayFocus && !isExternalProcess {
-				continue
-			}
+		modes := daySchedule.Modes
+		if modes == nil {
+			modes = []StepKey{}
+		}
 
-			if capacity <= 0 && !isExternalProcess {
-				break
-			}
+		preview.Days = append(preview.Days, DayPreview{
+			Date:            day.Format("2006-01-02"),
+			Weekday:         daySchedule.Weekday.String(),
+			Mode:            daySchedule.Mode,
+			Modes:           modes,
+			ChangeoverHours: daySchedule.ChangeoverHours,
+			Glazes:          daySchedule.Glazes,
+			AvailableHours:  daySchedule.AvailableHours,
+			Tasks:           tasks,
+		})
+
+		preview.TotalTasks += len(tasks)
+	}
 
-			piecesForDay := min(CalculateQuantity(capacity, task.TaskType, task.PieceType), task.Quantity)
-			hoursUsed := CalculateHours(task.TaskType, task.PieceType, piecesForDay)
+	if p.KilnLoads != nil {
+		preview.KilnLoads = p.KilnLoads
+	}
 
-			if piecesForDay == 0 && task.Quantity > 0 {
-				piecesForDay = task.Quantity
-				hoursUsed = CalculateHours(task.TaskType, task.PieceType, piecesForDay)
-			}
+	if p.Lateness != nil {
+		preview.Lateness = p.Lateness
+	}
 
-			if piecesForDay == 0 {
-				continue
-			}
+	preview.Overtime = p.Overtime
 
-			if hoursUsed > capacity*1.1 {
-				continue
-			}
+	return preview
+}
 
-			dayTasks = append(dayTasks, TaskToCreate{
-				OrderDetailId:  task.OrderDetailId,
-				Date:           day,
-				TaskType:       task.TaskType,
-				Quantity:       piecesForDay,
-				EstimatedHours: hoursUsed,
-				IsLate:         task.StartDate.Before(startDate),
-			})
-			capacity -= hoursUsed
-			anyTaskScheduled = true
-
-			completionDate := calculateTaskCompletion(day, task.TaskType, task.PieceType, piecesForDay)
-			orderDetailLastCompletion[task.OrderDetailId] = completionDate
-
-			if piecesForDay >= task.Quantity {
-				tasksWithDeadlines = append(tasksWithDeadlines[:i], tasksWithDeadlines[i+1:]...)
-				i -= 1
-
-				for j := 0; j < len(tasksWithDeadlines); j++ {
-					if tasksWithDeadlines[j].OrderDetailId == task.OrderDetailId {
-						orderDetailCurrentStatus[task.OrderDetailId] = tasksWithDeadlines[j].OrderDetailStatus
-						break
-					}
-				}
-			} else {
-				tasksWithDeadlines[i].Quantity -= piecesForDay
-			}
+func (s *Scheduler) buildSchedule(options RunOptions) (schedulePlan, error) {
 
-		}
+	input, err := s.planningInput(options)
+	if err != nil {
+		return schedulePlan{}, err
+	}
 
-		weekSchedule[day] = &DaySchedule{
-			Weekday:        day.Weekday(),
-			Tasks:          dayTasks,
-			AvailableHours: capacity,
-			Mode:           dayFocus,
-		}
+	planner, remaining := input.run()
 
-		if !anyTaskScheduled && day.Equal(endDate) {
-			break
-		}
+	lateness := planner.latenessReport(remaining, input.now)
 
+	LogInfo("scheduled_orders", map[string]any{
+		"numberOrDeadlineOrders":    input.deadlineOrders,
+		"numberOfNonDeadlineOrders": input.nonDeadlineOrders,
+		"remainingTasks":            len(remaining),
+		"horizonStart":              input.startDate.Format("2006-01-02"),
+		"horizonEnd":                input.endDate.Format("2006-01-02"),
+		"kilnLoads":                 len(planner.kilnLoads),
+		"lateOrderDetails":          len(lateness),
+		"catalogVersion":            input.process.Version(),
+		"weeklySchedule":            planner.schedule,
+	})
+
+	plan := schedulePlan{
+		StartDate:      input.startDate,
+		EndDate:        input.endDate,
+		Schedule:       planner.schedule,
+		KilnLoads:      planner.kilnLoads,
+		Lateness:       lateness,
+		CatalogVersion: input.process.Version(),
+	}
+
+	if options.Overtime != nil && len(lateness) > 0 {
+		overtime := input.suggestOvertime(*options.Overtime, lateness)
+		plan.Overtime = &overtime
 	}
 
-	nonDeadlineOrdersDTO, err := orders.GetNonDeadlineOrders()
+	return plan, nil
+}
+
+func (s *Scheduler) planningInput(options RunOptions) (planningInput, error) {
+
+	now := s.now(options)
 
+	startDate, endDate := getPlanningWindow(now, options.HorizonWeeks)
+
+	capacityByDate, err := loadAvailability(s.availability, startDate, endDate)
 	if err != nil {
-		return fmt.Errorf("failed to fetch orders without deadlines, error: %w", err)
+		return planningInput{}, fmt.Errorf("[Scheduler run] error: %w", err)
 	}
 
-	tasksWithoutDeadlines := []TaskChainItem{}
+	kiln := DefaultKiln
+	if options.Kiln != nil {
+		kiln = *options.Kiln
+	}
 
-	for _, order := range nonDeadlineOrdersDTO.Orders {
-		for _, detail := range order.OrderDetails {
-			completionDate, err := CalculateCompletionDate(detail, time.Now())
+	priority := DefaultPriority
+	if options.Priority != nil {
+		priority = *options.Priority
+	}
 
-			if err != nil {
-				return fmt.Errorf("failed to calculate completion date for order detail %s with error %w", detail.ID, err)
-			}
+	changeover := DefaultChangeover
+	if options.Changeover != nil {
+		changeover = *options.Changeover
+	}
 
-			newTasks, err := CalculateTaskChain(detail, completionDate)
+	catalog, err := s.processCatalog(options, now)
+	if err != nil {
+		return planningInput{}, err
+	}
 
-			if err != nil {
-				return fmt.Errorf("failed to calculate task chain for order detail %s with error %w", detail.ID, err)
-			}
+	calendar := s.calendar
+	if calendar == nil {
+		calendar = CurrentCalendar()
+	}
 
-			if len(newTasks) > 0 {
-				orderDetailCurrentStatus[detail.ID] = newTasks[0].OrderDetailStatus
-			}
+	work, err := s.loadWork(catalog, calendar, now)
+	if err != nil {
+		return planningInput{}, err
+	}
 
-			tasksWithoutDeadlines = append(tasksWithoutDeadlines, newTasks...)
-		}
+	return planningInput{
+		startDate:         startDate,
+		endDate:           endDate,
+		capacityByDate:    capacityByDate,
+		kiln:              kiln,
+		priority:          priority,
+		changeover:        changeover,
+		chains:            work.chains,
+		process:           catalog.withProcesses(work.processes),
+		calendar:          calendar,
+		now:               now,
+		deadlineOrders:    work.deadlineOrders,
+		nonDeadlineOrders: work.nonDeadlineOrders,
+	}, nil
+}
+
+func (s *Scheduler) loadWork(catalog *ProcessCatalog, calendar *Calendar, now time.Time) (scheduleWork, error) {
+	deadlineOrders, err := s.orders.GetOrdersWithDeadlines()
+
+	if err != nil {
+		return scheduleWork{}, fmt.Errorf("[Scheduler run] error: %w", err)
 	}
 
-	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
-		daySchedule := weekSchedule[day]
+	nonDeadlineOrders, err := s.orders.GetNonDeadlineOrders()
 
-		if daySchedule == nil {
-			if len(tasksWithoutDeadlines) == 0 {
-				break
-			}
+	if err != nil {
+		return scheduleWork{}, fmt.Errorf("failed to fetch orders without deadlines, error: %w", err)
+	}
 
-			var initialMode StepKey
-			for _, task := range tasksWithoutDeadlines {
-				if task.TaskType != TaskTypeBisque && task.TaskType != TaskTypeFire {
-					initialMode = task.OrderDetailStatus
-					break
-				}
-			}
+	work := scheduleWork{
+		chains:            [][]TaskChainItem{},
+		processes:         make(map[PieceType][]ProductionStep),
+		deadlineOrders:    len(deadlineOrders.Orders),
+		nonDeadlineOrders: len(nonDeadlineOrders.Orders),
+	}
 
-			dayCapacity, err := availabilityService.GetAvailabilityForDate(day)
-			if err != nil {
-				return fmt.Errorf("[Scheduler run] failed to get availability for %s: %w", day.Format("2006-01-02"), err)
-			}
+	for _, order := range deadlineOrders.Orders {
+		if err := work.addDeadlineOrder(catalog, calendar, order, *order.DueDate, now); err != nil {
+			return scheduleWork{}, err
+		}
+	}
 
-			daySchedule = &DaySchedule{
-				Weekday:        day.Weekday(),
-				Tasks:          []TaskToCreate{},
-				Mode:           initialMode,
-				AvailableHours: dayCapacity,
+	for _, order := range nonDeadlineOrders.Orders {
+		// An order placed with a bulk code keeps to the code's promised date
+		// until it's given a due date of its own
+		if order.PromisedDate != nil {
+			if err := work.addDeadlineOrder(catalog, calendar, order, *order.PromisedDate, now); err != nil {
+				return scheduleWork{}, err
 			}
+			continue
 		}
 
-		if daySchedule.AvailableHours <= 0 && daySchedule.Mode != "" {
-			continue
+		waitingSince := now
+		if order.CreatedAt != nil {
+			waitingSince = *order.CreatedAt
 		}
 
-		anyTaskScheduled := false
+		for _, detail := range order.OrderDetails {
+			completionDate, err := calculateCompletionDate(catalog, calendar, quotedDetail(detail), waitingSince)
 
-		for i := 0; i < len(tasksWithoutDeadlines); i++ {
+			if err != nil {
+				return scheduleWork{}, fmt.Errorf("failed to calculate completion date for order detail %s with error %w", detail.ID, err)
+			}
 
-			task := tasksWithoutDeadlines[i]
+			chains, processes, err := calculateWorkChains(catalog, calendar, detail, completionDate, now)
 
-			currentStatus, exists := orderDetailCurrentStatus[task.OrderDetailId]
-			if !exists || task.OrderDetailStatus != currentStatus {
-				continue
+			if err != nil {
+				return scheduleWork{}, fmt.Errorf("failed to calculate task chain for order detail %s with error %w", detail.ID, err)
 			}
 
-			earliestPossibleStart := task.StartDate
-			if lastCompletion, exists := orderDetailLastCompletion[task.OrderDetailId]; exists {
-				if lastCompletion.After(earliestPossibleStart) {
-					earliestPossibleStart = lastCompletion
+			for _, newTasks := range chains {
+				for i := range newTasks {
+					newTasks[i].OrderId = order.ID
+					newTasks[i].DueDate = completionDate
+					newTasks[i].WaitingSince = waitingSince
 				}
 			}
 
-			if earliestPossibleStart.After(day) {
-				if day.Equal(endDate) {
-					tasksWithoutDeadlines = append(tasksWithoutDeadlines[:i], tasksWithoutDeadlines[i+1:]...)
-					i -= 1
-				}
-				continue
-			}
+			work.add(chains, processes)
+		}
+	}
+
+	return work, nil
+}
 
-			isExternalProcess := task.TaskType == TaskTypeBisque || task.TaskType == TaskTypeFire
+func (w *scheduleWork) addDeadlineOrder(catalog *ProcessCatalog, calendar *Calendar, order orders.OrderDTO, dueDate time.Time, now time.Time) error {
+	for _, detail := range order.OrderDetails {
+		chains, processes, err := calculateWorkChains(catalog, calendar, detail, dueDate, now)
 
-			if daySchedule.Mode == "" && !isExternalProcess {
-				daySchedule.Mode = task.OrderDetailStatus
-			}
+		if err != nil {
+			return fmt.Errorf("failed to calculate task chain for order detail %s with error %w", detail.ID, err)
+		}
 
-			if task.OrderDetailStatus != daySchedule.Mode && !isExternalProcess {
-				continue
-			}
+		for _, newTasks := range chains {
+			for i := range newTasks {
+				newTasks[i].OrderId = order.ID
+				newTasks[i].DueDate = dueDate
+				newTasks[i].HasDeadline = true
 
-			if daySchedule.AvailableHours <= 0 && !isExternalProcess {
-				break
+				if order.PromisedDate != nil {
+					newTasks[i].PromisedDate = *order.PromisedDate
+				}
+				if order.BulkCodeID != nil {
+					newTasks[i].BulkCodeId = *order.BulkCodeID
+				}
 			}
+		}
 
-			piecesForDay := min(CalculateQuantity(daySchedule.AvailableHours, task.TaskType, task.PieceType), task.Quantity)
-			hoursUsed := CalculateHours(task.TaskType, task.PieceType, piecesForDay)
+		w.add(chains, processes)
+	}
 
-			if piecesForDay == 0 && task.Quantity > 0 {
-				piecesForDay = task.Quantity
-				hoursUsed = CalculateHours(task.TaskType, task.PieceType, piecesForDay)
-			}
+	return nil
+}
 
-			if piecesForDay == 0 {
-				continue
-			}
+func (w *scheduleWork) add(chains [][]TaskChainItem, processes map[PieceType][]ProductionStep) {
+	w.chains = append(w.chains, chains...)
+	for pieceType, steps := range processes {
+		w.processes[pieceType] = steps
+	}
+}
 
-			if hoursUsed > daySchedule.AvailableHours*1.1 {
-				continue
-			}
+func (in planningInput) run() (*planner, []TaskChainItem) {
+	planner := newPlanner(in.startDate, in.endDate, in.capacityByDate, in.kiln, in.priority, in.changeover)
+	if in.process != nil {
+		planner.process = in.process
+	}
+	if in.calendar != nil {
+		planner.calendar = in.calendar
+	}
 
-			daySchedule.Tasks = append(daySchedule.Tasks, TaskToCreate{
-				OrderDetailId:  task.OrderDetailId,
-				Date:           day,
-				TaskType:       task.TaskType,
-				Quantity:       piecesForDay,
-				EstimatedHours: hoursUsed,
-				IsLate:         task.StartDate.Before(startDate),
-			})
-			daySchedule.AvailableHours -= hoursUsed
-			anyTaskScheduled = true
-
-			completionDate := calculateTaskCompletion(day, task.TaskType, task.PieceType, piecesForDay)
-			orderDetailLastCompletion[task.OrderDetailId] = completionDate
-
-			if piecesForDay >= task.Quantity {
-				tasksWithoutDeadlines = append(tasksWithoutDeadlines[:i], tasksWithoutDeadlines[i+1:]...)
-				i -= 1
-
-				for j := 0; j < len(tasksWithoutDeadlines); j++ {
-					if tasksWithoutDeadlines[j].OrderDetailId == task.OrderDetailId {
-						orderDetailCurrentStatus[task.OrderDetailId] = tasksWithoutDeadlines[j].OrderDetailStatus
-						break
-					}
-				}
-			} else {
-				tasksWithoutDeadlines[i].Quantity -= piecesForDay
-			}
+	tasks := []TaskChainItem{}
+	for _, chain := range in.chains {
+		planner.trackChain(chain)
+		tasks = append(tasks, chain...)
+	}
 
+	for day := in.startDate; !day.After(in.endDate); day = day.AddDate(0, 0, 1) {
+		if len(tasks) == 0 {
+			break
 		}
 
-		weekSchedule[day] = daySchedule
+		capacity := planner.capacityFor(day)
 
-		if !anyTaskScheduled && len(tasksWithoutDeadlines) > 0 && day.Equal(endDate) {
-			break
+		if capacity <= 0 {
+			continue
+		}
+
+		daySchedule := &DaySchedule{
+			Weekday:        day.Weekday(),
+			Tasks:          []TaskToCreate{},
+			AvailableHours: capacity,
 		}
 
+		tasks = planner.prioritise(day, tasks)
+		tasks, _ = planner.planDay(day, daySchedule, tasks)
+
+		planner.schedule[day] = daySchedule
 	}
 
-	LogInfo("deadline_orders", map[string]any{
-		"numberOrDeadlineOrders":          len(deadlineOrders.Orders),
-		"remainingTasksWithDeadlines":     len(tasksWithDeadlines),
-		"numberOfNonDeadlineOrders":       len(nonDeadlineOrdersDTO.Orders),
-		"remainingTasksWithoutDeadlines":  len(tasksWithoutDeadlines),
-		"weeklySchedule":                  weekSchedule,
-	})
+	return planner, tasks
+}
 
-	for day, schedule := range weekSchedule {
-		err := InsertTasks(schedule.Tasks)
+func loadAvailability(availabilitySource AvailabilitySource, startDate, endDate time.Time) (map[string]float64, error) {
+	days, err := availabilitySource.GetAvailability(startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
-		if err != nil {
-			return fmt.Errorf("failed to insert tasks for day %s with error %w", day, err)
-		}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability for %s through %s: %w", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), err)
+	}
 
+	capacityByDate := make(map[string]float64, len(days))
+
+	for _, day := range days {
+		capacityByDate[day.Date] = day.AvailableHours
 	}
 
-	return nil
+	return capacityByDate, nil
 }
 
-func calculateTaskCompletion(scheduledDate time.Time, taskType TaskType, pieceType PieceType, quantity int) time.Time {
-	process := ProductionProcess[pieceType]
+func calculateTaskCompletion(scheduledDate time.Time, stepKey StepKey, pieceType PieceType, quantity int) time.Time {
+	return calculateTaskCompletionWith(CurrentProcessCatalog(), CurrentCalendar(), scheduledDate, stepKey, pieceType, quantity)
+}
 
-	var dryingDays int
-	var workDays int
+func calculateTaskCompletionWith(catalog *ProcessCatalog, calendar *Calendar, scheduledDate time.Time, stepKey StepKey, pieceType PieceType, quantity int) time.Time {
+	step, exists := catalog.stepByKey(stepKey, pieceType)
+	if !exists {
+		return scheduledDate
+	}
 
-	for _, step := range process {
-		if step.TaskType == taskType {
-			dryingDays = step.DryingDays
-			if step.Rate > 0 {
-				workDays = int(float64(quantity)/step.Rate + 0.999)
-			}
-			break
-		}
+	return calendar.stepFinish(scheduledDate, step, quantity)
+}
+
+func getPlanningWindow(now time.Time, horizonWeeks int) (startDate, endDate time.Time) {
+	if horizonWeeks <= 0 {
+		horizonWeeks = DefaultHorizonWeeks
 	}
 
-	if taskType == TaskTypeBisque || taskType == TaskTypeFire {
-		return scheduledDate.AddDate(0, 0, dryingDays)
+	if horizonWeeks > MaxHorizonWeeks {
+		horizonWeeks = MaxHorizonWeeks
 	}
 
-	return scheduledDate.AddDate(0, 0, workDays+dryingDays)
-}
+	startDate, endDate = getNextWeek(now)
+	endDate = endDate.AddDate(0, 0, 7*(horizonWeeks-1))
 
-func getNextWeek() (startDate, endDate time.Time) {
-	now := time.Now()
+	return
+}
 
+func getNextWeek(now time.Time) (startDate, endDate time.Time) {
 	if now.Weekday() == time.Sunday {
 		startDate = now.AddDate(0, 0, 1)
 	} else {
@@ -1,10 +1,14 @@
 package scheduler
 
 import (
+	"aliciapceramics/legacy/server/availability"
+	"aliciapceramics/legacy/server/orders"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWeekSchedule_SingleDayBuildCapacity(t *testing.T) {
@@ -23,7 +27,7 @@ func TestWeekSchedule_SingleDayBuildCapacity(t *testing.T) {
 
 	schedule := make(WeekSchedule)
 	day := startDate
-	capacity := WeeklySchedule[day.Weekday()]
+	capacity := availability.DefaultWeeklySchedule[day.Weekday()]
 
 	piecesForDay := min(CalculateQuantity(capacity, tasks[0].TaskType, tasks[0].PieceType), tasks[0].Quantity)
 	hoursUsed := CalculateHours(tasks[0].TaskType, tasks[0].PieceType, piecesForDay)
@@ -193,7 +197,7 @@ func TestWeekSchedule_OverbookingAllowance(t *testing.T) {
 
 func TestWeekSchedule_ZeroCapacityDay(t *testing.T) {
 	sunday := time.Date(2025, 10, 26, 0, 0, 0, 0, time.UTC)
-	capacity := WeeklySchedule[sunday.Weekday()]
+	capacity := availability.DefaultWeeklySchedule[sunday.Weekday()]
 
 	assert.Equal(t, 0.0, capacity, "Sunday should have 0 capacity")
 
@@ -326,7 +330,7 @@ func TestWeekSchedule_FullWeekScheduling(t *testing.T) {
 	totalCapacity := 0.0
 
 	for day := monday; !day.After(endDate); day = day.AddDate(0, 0, 1) {
-		capacity := WeeklySchedule[day.Weekday()]
+		capacity := availability.DefaultWeeklySchedule[day.Weekday()]
 		totalCapacity += capacity
 
 		schedule[day] = &DaySchedule{
@@ -342,7 +346,7 @@ func TestWeekSchedule_FullWeekScheduling(t *testing.T) {
 }
 
 func TestGetNextWeek(t *testing.T) {
-	startDate, endDate := getNextWeek()
+	startDate, endDate := getNextWeek(time.Now())
 
 	assert.True(t, startDate.Before(endDate) || startDate.Equal(endDate), "Start date should be before or equal to end date")
 
@@ -420,7 +424,7 @@ func TestBUG2_LateTasksRespectDryingPeriods(t *testing.T) {
 	}
 
 	scheduledBuildDate := monday
-	buildCompletion := calculateTaskCompletion(scheduledBuildDate, buildTask.TaskType, buildTask.PieceType, buildTask.Quantity)
+	buildCompletion := calculateTaskCompletion(scheduledBuildDate, buildTask.OrderDetailStatus, buildTask.PieceType, buildTask.Quantity)
 
 	expectedBuildCompletion := monday.AddDate(0, 0, 3)
 	assert.Equal(t, expectedBuildCompletion, buildCompletion, "Build should complete on day 3 (1 work day + 2 drying days)")
@@ -438,3 +442,227 @@ func TestBUG2_LateTasksRespectDryingPeriods(t *testing.T) {
 	daysBetweenTasks := scheduledTrimDate.Sub(scheduledBuildDate).Hours() / 24
 	assert.Equal(t, float64(3), daysBetweenTasks, "Should maintain 3-day gap (1 work + 2 drying) between build and trim")
 }
+
+func TestGetPlanningWindow(t *testing.T) {
+	tests := []struct {
+		name         string
+		horizonWeeks int
+		expectedWeek int
+	}{
+		{"Single week matches getNextWeek", 1, 1},
+		{"Four week horizon", 4, 4},
+		{"Eight week horizon", 8, 8},
+		{"Zero falls back to default", 0, DefaultHorizonWeeks},
+		{"Above maximum is capped", 20, MaxHorizonWeeks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weekStart, weekEnd := getNextWeek(time.Now())
+			startDate, endDate := getPlanningWindow(time.Now(), tt.horizonWeeks)
+
+			assert.Equal(t, weekStart.Format("2006-01-02"), startDate.Format("2006-01-02"), "Horizon should start on the same day as the current week")
+			assert.Equal(t, time.Saturday, endDate.Weekday(), "Horizon should always end on a Saturday")
+			assert.Equal(t, weekEnd.AddDate(0, 0, 7*(tt.expectedWeek-1)).Format("2006-01-02"), endDate.Format("2006-01-02"))
+		})
+	}
+}
+
+func TestWeekSchedule_AllTasksOrderedByDay(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	nextMonday := monday.AddDate(0, 0, 7)
+
+	schedule := WeekSchedule{
+		nextMonday: {
+			Weekday: nextMonday.Weekday(),
+			Tasks:   []TaskToCreate{{OrderDetailId: "week-2", Date: nextMonday, TaskType: TaskTypeTrim, Quantity: 5}},
+		},
+		monday: {
+			Weekday: monday.Weekday(),
+			Tasks:   []TaskToCreate{{OrderDetailId: "week-1", Date: monday, TaskType: TaskTypeBuildBase, Quantity: 5}},
+		},
+	}
+
+	tasks := schedule.AllTasks()
+
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "week-1", tasks[0].OrderDetailId, "Earlier days should be inserted first")
+	assert.Equal(t, "week-2", tasks[1].OrderDetailId)
+}
+
+func TestSchedulePlan_Preview(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	plan := schedulePlan{
+		StartDate: monday,
+		EndDate:   monday.AddDate(0, 0, 5),
+		Schedule: WeekSchedule{
+			tuesday: {
+				Weekday:        tuesday.Weekday(),
+				Mode:           StepKeyTrim,
+				AvailableHours: 0.5,
+				Tasks: []TaskToCreate{
+					{OrderDetailId: "detail-1", Date: tuesday, TaskType: TaskTypeTrim, Quantity: 5, EstimatedHours: 1.5},
+				},
+			},
+			monday: {
+				Weekday:        monday.Weekday(),
+				Mode:           StepKeyBuild,
+				AvailableHours: 0,
+				Tasks: []TaskToCreate{
+					{OrderDetailId: "detail-1", Date: monday, TaskType: TaskTypeBuildBase, Quantity: 5, EstimatedHours: 4},
+					{OrderDetailId: "detail-2", Date: monday, TaskType: TaskTypeBisque, Quantity: 10},
+				},
+			},
+		},
+	}
+
+	preview := plan.Preview()
+
+	assert.Equal(t, "2025-10-20", preview.StartDate)
+	assert.Equal(t, "2025-10-25", preview.EndDate)
+	assert.Equal(t, 3, preview.TotalTasks)
+	assert.Len(t, preview.Days, 2)
+
+	assert.Equal(t, "2025-10-20", preview.Days[0].Date, "Days should be ordered chronologically")
+	assert.Equal(t, "Monday", preview.Days[0].Weekday)
+	assert.Equal(t, StepKeyBuild, preview.Days[0].Mode)
+	assert.Len(t, preview.Days[0].Tasks, 2)
+
+	assert.Equal(t, "2025-10-21", preview.Days[1].Date)
+	assert.InDelta(t, 0.5, preview.Days[1].AvailableHours, 0.001, "Remaining hours should be reported per day")
+}
+
+type failingOrderSource struct{}
+
+func (f *failingOrderSource) GetOrdersWithDeadlines() (orders.OrdersDTO, error) {
+	return orders.OrdersDTO{}, fmt.Errorf("database unavailable")
+}
+
+func (f *failingOrderSource) GetNonDeadlineOrders() (orders.OrdersDTO, error) {
+	return orders.OrdersDTO{}, fmt.Errorf("database unavailable")
+}
+
+func newDeadlineOrder(orderID, detailID string, pieceType PieceType, quantity int, dueDate time.Time) orders.OrderDTO {
+	return orders.OrderDTO{
+		ID:      orderID,
+		Status:  "pending",
+		DueDate: &dueDate,
+		OrderDetails: []orders.OrderDetailDTO{
+			{
+				ID:       detailID,
+				OrderID:  orderID,
+				Type:     string(pieceType),
+				Quantity: quantity,
+				Status:   string(StepKeyPending),
+			},
+		},
+	}
+}
+
+func TestScheduler_PreviewDoesNotTouchTaskStore(t *testing.T) {
+	dueDate := time.Now().AddDate(0, 0, 30)
+	store := NewInMemoryTaskStore()
+	require.NoError(t, store.InsertTasks([]TaskToCreate{{OrderDetailId: "existing", TaskType: TaskTypeTrim, Quantity: 1}}))
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithHandle, 5, dueDate)),
+		NewInMemoryAvailabilitySource(nil),
+		store,
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 8})
+	require.NoError(t, err)
+
+	assert.Greater(t, preview.TotalTasks, 0, "Preview should plan tasks for the deadline order")
+	assert.Len(t, store.Tasks(), 1, "Preview must not delete or insert tasks")
+	assert.Equal(t, "existing", store.Tasks()[0].OrderDetailId)
+}
+
+func TestScheduler_RunEndToEnd(t *testing.T) {
+	dueDate := time.Now().AddDate(0, 0, 30)
+	store := NewInMemoryTaskStore()
+	require.NoError(t, store.InsertTasks([]TaskToCreate{{OrderDetailId: "stale", TaskType: TaskTypeTrim, Quantity: 1}}))
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceTypeMugWithHandle, 5, dueDate)),
+		NewInMemoryAvailabilitySource(nil),
+		store,
+	)
+
+	preview, err := scheduler.Preview(RunOptions{HorizonWeeks: 8})
+	require.NoError(t, err)
+
+	result, err := scheduler.Run(RunOptions{HorizonWeeks: 8})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+
+	tasks := store.Tasks()
+	require.Len(t, tasks, preview.TotalTasks, "Run should persist exactly the previewed plan")
+	assert.Equal(t, preview.TotalTasks, result.TasksCreated)
+	assert.Equal(t, TaskTypeBuildBase, tasks[0].TaskType, "Build should be the first task inserted")
+
+	totalBuilt := 0
+	for i, task := range tasks {
+		assert.Equal(t, "detail-1", task.OrderDetailId, "Stale pending tasks should have been deleted")
+		if task.TaskType == TaskTypeBuildBase {
+			totalBuilt += task.Quantity
+		}
+		if i > 0 {
+			assert.False(t, task.Date.Before(tasks[i-1].Date), "Tasks should be inserted in date order")
+		}
+	}
+	assert.Equal(t, 5, totalBuilt, "All pieces should be built within the horizon")
+}
+
+func TestScheduler_RunPropagatesOrderSourceErrors(t *testing.T) {
+	scheduler := NewScheduler(&failingOrderSource{}, NewInMemoryAvailabilitySource(nil), NewInMemoryTaskStore())
+
+	_, err := scheduler.Run(RunOptions{HorizonWeeks: 1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "database unavailable")
+}
+
+func TestPlanDay_TrimFinalUsesItsOwnDrying(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+
+	trimFinal := TaskChainItem{
+		OrderDetailId:     "detail-1",
+		OrderDetailStatus: StepKeyTrimFinal,
+		TaskType:          TaskTypeTrim,
+		PieceType:         PieceTypeMugWithHandle,
+		Quantity:          5,
+		StartDate:         monday,
+		HasDeadline:       true,
+	}
+	p.trackChain([]TaskChainItem{trimFinal})
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: 8}
+	_, scheduled := p.planDay(monday, daySchedule, []TaskChainItem{trimFinal})
+	require.True(t, scheduled)
+
+	// Trim dries for a day, but trim_final shares its task type and dries for three
+	assert.Equal(t, monday.AddDate(0, 0, 4), p.stepCompletion[trimFinal.chainKey()][StepKeyTrimFinal])
+}
+
+func TestScheduler_RunKeepsScheduleWhenPlanningFails(t *testing.T) {
+	dueDate := time.Now().AddDate(0, 0, 30)
+	store := NewInMemoryTaskStore()
+	require.NoError(t, store.InsertTasks([]TaskToCreate{{OrderDetailId: "existing", TaskType: TaskTypeTrim, Quantity: 1}}))
+	require.NoError(t, store.InsertLatenessReport([]LatenessEntry{{OrderDetailId: "existing", DaysLate: 2}}))
+
+	scheduler := NewScheduler(
+		NewInMemoryOrderSource(newDeadlineOrder("order-1", "detail-1", PieceType("teapot"), 5, dueDate)),
+		NewInMemoryAvailabilitySource(nil),
+		store,
+	)
+
+	_, err := scheduler.Run(RunOptions{HorizonWeeks: 8})
+	require.Error(t, err)
+
+	assert.Len(t, store.Tasks(), 1, "A run that can't plan must leave the last schedule in place")
+	assert.Len(t, store.LatenessReports(), 1, "A run that can't plan must leave the last lateness report in place")
+	assert.Empty(t, store.Snapshots())
+}
@@ -0,0 +1,30 @@
+package scheduler
+
+func (c *ProcessCatalog) setupFor(taskType TaskType) SetupTime {
+	return c.setup[taskType]
+}
+
+func (d *DaySchedule) hasTaskType(taskType TaskType) bool {
+	for _, task := range d.Tasks {
+		if task.TaskType == taskType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// daySetup is charged to the first task of its type on a day, and covers
+// every later task of that type. Glazes are mixed one at a time, so glazing is
+// set up again for each glaze used on the day.
+func (p *planner) daySetup(daySchedule *DaySchedule, task TaskChainItem) float64 {
+	if task.TaskType == TaskTypeGlaze && task.Glaze != "" {
+		if daySchedule.hasGlaze(task.Glaze) {
+			return 0
+		}
+	} else if daySchedule.hasTaskType(task.TaskType) {
+		return 0
+	}
+
+	return p.process.setupFor(task.TaskType).DayHours
+}
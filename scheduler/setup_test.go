@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSetupCatalog(t *testing.T) *ProcessCatalog {
+	t.Helper()
+
+	catalog, err := NewProcessCatalogWithSetup(DefaultProductionProcess(), SizeMultipliers, map[TaskType]SetupTime{
+		TaskTypeBuildBase: {TaskHours: 0.5, DayHours: 0.25},
+	})
+	require.NoError(t, err)
+
+	return catalog
+}
+
+func TestCalculateHours_AddsTaskSetup(t *testing.T) {
+	catalog := newSetupCatalog(t)
+
+	assert.InDelta(t, 1.3, calculateHours(catalog, TaskTypeBuildBase, PieceTypeMugWithHandle, 1), 0.001, "A single piece still needs the clay wedged")
+	assert.InDelta(t, 4.5, calculateHours(catalog, TaskTypeBuildBase, PieceTypeMugWithHandle, 5), 0.001)
+	assert.Equal(t, 0.0, calculateHours(catalog, TaskTypeBuildBase, PieceTypeMugWithHandle, 0), "No pieces means no task to set up")
+	assert.InDelta(t, CalculateHours(TaskTypeTrim, PieceTypeMugWithHandle, 15), calculateHours(catalog, TaskTypeTrim, PieceTypeMugWithHandle, 15), 0.001, "Task types without setup are unchanged")
+
+	assert.Equal(t, 0, calculateQuantity(catalog, 0.5, TaskTypeBuildBase, PieceTypeMugWithHandle), "The setup uses up the hours")
+	assert.Equal(t, 1, calculateQuantity(catalog, 1.3, TaskTypeBuildBase, PieceTypeMugWithHandle))
+	assert.Equal(t, 5, calculateQuantity(catalog, 4.5, TaskTypeBuildBase, PieceTypeMugWithHandle))
+
+	assert.NotEqual(t, DefaultProcessCatalog.Version(), catalog.Version(), "Setup times are part of the catalog's version")
+
+	for quantity := 1; quantity <= 30; quantity++ {
+		hours := calculateHours(catalog, TaskTypeBuildBase, PieceTypeMugWithHandle, quantity)
+		assert.Equal(t, quantity, calculateQuantity(catalog, hours, TaskTypeBuildBase, PieceTypeMugWithHandle))
+	}
+}
+
+func TestPlanDay_ChargesDaySetupOncePerTaskType(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+	p.process = newSetupCatalog(t)
+
+	tasks := []TaskChainItem{
+		{OrderDetailId: "first", OrderDetailStatus: StepKeyBuild, TaskType: TaskTypeBuildBase, PieceType: PieceTypeMugWithoutHandle, Quantity: 5, StartDate: monday, HasDeadline: true},
+		{OrderDetailId: "second", OrderDetailStatus: StepKeyBuild, TaskType: TaskTypeBuildBase, PieceType: PieceTypeMugWithoutHandle, Quantity: 5, StartDate: monday, HasDeadline: true},
+	}
+	for i := range tasks {
+		p.trackChain(tasks[i : i+1])
+	}
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: 10}
+	_, scheduled := p.planDay(monday, daySchedule, tasks)
+	require.True(t, scheduled)
+	require.Len(t, daySchedule.Tasks, 2)
+
+	assert.InDelta(t, 4.75, daySchedule.Tasks[0].EstimatedHours, 0.001, "The first build of the day sets up the wheel and wedges its clay")
+	assert.InDelta(t, 4.5, daySchedule.Tasks[1].EstimatedHours, 0.001, "Later builds only wedge their own clay")
+	assert.InDelta(t, 0.75, daySchedule.AvailableHours, 0.001)
+}
+
+func TestPlanDay_SetupLimitsPiecesForDay(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+	p := newPlanner(monday, monday.AddDate(0, 0, 6), map[string]float64{}, DefaultKiln, DefaultPriority, DefaultChangeover)
+	p.process = newSetupCatalog(t)
+
+	tasks := []TaskChainItem{
+		{OrderDetailId: "build", OrderDetailStatus: StepKeyBuild, TaskType: TaskTypeBuildBase, PieceType: PieceTypeMugWithoutHandle, Quantity: 10, StartDate: monday, HasDeadline: true},
+	}
+	p.trackChain(tasks)
+
+	daySchedule := &DaySchedule{Weekday: monday.Weekday(), Tasks: []TaskToCreate{}, AvailableHours: 4}
+	_, scheduled := p.planDay(monday, daySchedule, tasks)
+	require.True(t, scheduled)
+	require.Len(t, daySchedule.Tasks, 1)
+
+	assert.Equal(t, 4, daySchedule.Tasks[0].Quantity, "Three hours and a quarter of making fit four pieces")
+	assert.LessOrEqual(t, daySchedule.Tasks[0].EstimatedHours, 4.0)
+}
+
+func TestNewProcessCatalogWithSetup_Validates(t *testing.T) {
+	_, err := NewProcessCatalogWithSetup(DefaultProductionProcess(), SizeMultipliers, map[TaskType]SetupTime{
+		TaskTypeTrim:      {TaskHours: -0.5},
+		TaskTypeBisque:    {DayHours: 1},
+		TaskType("wedge"): {TaskHours: 0.5},
+	})
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "task_trim has negative task setup hours")
+	assert.Contains(t, err.Error(), "task_bisque runs in the kiln")
+	assert.Contains(t, err.Error(), `unknown task type "wedge"`)
+}
+
+func TestParseProcessCatalog_ReadsSetup(t *testing.T) {
+	catalog, err := ParseProcessCatalog([]byte(`
+processes:
+  mug-without-handle:
+    - {step_key: build, task_type: task_build_base, rate: 10, drying_days: 2}
+    - {step_key: bisque, task_type: task_bisque, rate: 0, drying_days: 5}
+    - {step_key: glaze, task_type: task_glaze, rate: 17, drying_days: 0}
+    - {step_key: fire, task_type: task_fire, rate: 0, drying_days: 5}
+setup:
+  task_build_base: {task_hours: 0.5, day_hours: 0.25}
+`), "yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, SetupTime{TaskHours: 0.5, DayHours: 0.25}, catalog.setupFor(TaskTypeBuildBase))
+	assert.InDelta(t, 4.5, calculateHours(catalog, TaskTypeBuildBase, PieceTypeMugWithoutHandle, 10), 0.001)
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const MaxSimulationRuns = 2000
+
+type Distribution struct {
+	Min  float64
+	Mode float64
+	Max  float64
+}
+
+type SimulationOptions struct {
+	Runs          int
+	Seed          int64
+	DryingDays    Distribution
+	Rate          Distribution
+	KilnDelayDays Distribution
+}
+
+// DryingDays and Rate are multipliers on the catalog rates, KilnDelayDays is
+// added to the kiln's cool down for the whole run
+var DefaultSimulation = SimulationOptions{
+	Runs:          200,
+	Seed:          1,
+	DryingDays:    Distribution{Min: 0.8, Mode: 1.0, Max: 1.6},
+	Rate:          Distribution{Min: 0.7, Mode: 1.0, Max: 1.1},
+	KilnDelayDays: Distribution{Min: 0, Mode: 0, Max: 3},
+}
+
+func Simulate(options RunOptions, simulation SimulationOptions) (SimulationResult, error) {
+	scheduler, err := NewSupabaseScheduler()
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	return scheduler.Simulate(options, simulation)
+}
+
+func (s *Scheduler) Simulate(options RunOptions, simulation SimulationOptions) (SimulationResult, error) {
+
+	input, err := s.planningInput(options)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	runs := min(max(1, simulation.Runs), MaxSimulationRuns)
+	rng := rand.New(rand.NewSource(simulation.Seed))
+
+	completions := make(map[string][]time.Time)
+
+	for run := 0; run < runs; run++ {
+		sampled := input
+		sampled.process = simulation.sampleProcess(rng, input.process)
+		sampled.kiln.CoolDownDays += int(math.Round(simulation.KilnDelayDays.sample(rng)))
+
+		planner, remaining := sampled.run()
+
+		for chainKey := range planner.chains {
+			completion, _, _ := planner.projectCompletion(chainKey, remaining)
+			completions[chainKey] = append(completions[chainKey], completion)
+		}
+	}
+
+	result := SimulationResult{
+		Runs:      runs,
+		StartDate: input.startDate.Format("2006-01-02"),
+		EndDate:   input.endDate.Format("2006-01-02"),
+		Forecasts: []CompletionForecast{},
+
+		CatalogVersion: input.process.Version(),
+	}
+
+	for _, chain := range input.chains {
+		if len(chain) == 0 {
+			continue
+		}
+
+		first := chain[0]
+		dates := completions[first.chainKey()]
+		sort.Slice(dates, func(i, j int) bool {
+			return dates[i].Before(dates[j])
+		})
+
+		forecast := CompletionForecast{
+			OrderId:       first.OrderId,
+			OrderDetailId: first.OrderDetailId,
+			Component:     first.Component,
+			PieceType:     first.PieceType,
+			P50:           percentile(dates, 0.50),
+			P80:           percentile(dates, 0.80),
+			P95:           percentile(dates, 0.95),
+		}
+
+		if first.HasDeadline {
+			dueDate := first.DueDate
+			onTime := 0
+			for _, date := range dates {
+				if !date.After(dueDate) {
+					onTime++
+				}
+			}
+			onTimeProbability := float64(onTime) / float64(len(dates))
+
+			forecast.DueDate = &dueDate
+			forecast.OnTimeProbability = &onTimeProbability
+		}
+
+		result.Forecasts = append(result.Forecasts, forecast)
+	}
+
+	return result, nil
+}
+
+func (o SimulationOptions) sampleProcess(rng *rand.Rand, catalog *ProcessCatalog) *ProcessCatalog {
+	sampled := make(map[PieceType][]ProductionStep)
+
+	// PieceTypes is sorted, which keeps seeded runs reproducible
+	for _, pieceType := range catalog.PieceTypes() {
+		steps := catalog.Steps(pieceType)
+
+		for i, step := range steps {
+			step.DryingDays = int(math.Round(float64(step.DryingDays) * o.DryingDays.multiplier(rng)))
+			step.Rate = step.Rate * o.Rate.multiplier(rng)
+			steps[i] = step
+		}
+
+		sampled[pieceType] = steps
+	}
+
+	return catalog.derive(sampled)
+}
+
+func (d Distribution) multiplier(rng *rand.Rand) float64 {
+	if d == (Distribution{}) {
+		return 1
+	}
+
+	return d.sample(rng)
+}
+
+// sample draws from a triangular distribution, falling back to Mode when the
+// range is degenerate
+func (d Distribution) sample(rng *rand.Rand) float64 {
+	if d.Max <= d.Min {
+		return d.Mode
+	}
+
+	mode := math.Min(math.Max(d.Mode, d.Min), d.Max)
+
+	u := rng.Float64()
+	split := (mode - d.Min) / (d.Max - d.Min)
+
+	if u < split {
+		return d.Min + math.Sqrt(u*(d.Max-d.Min)*(mode-d.Min))
+	}
+
+	return d.Max - math.Sqrt((1-u)*(d.Max-d.Min)*(d.Max-mode))
+}
+
+func percentile(sorted []time.Time, p float64) time.Time {
+	if len(sorted) == 0 {
+		return time.Time{}
+	}
+
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+	return sorted[max(0, min(index, len(sorted)-1))]
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSimulationScheduler() *Scheduler {
+	asOf := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	createdAt := asOf.AddDate(0, 0, -14)
+
+	commission := newDeadlineOrder("commission-order", "commission-detail", PieceTypeTrinketDish, 6, asOf)
+	commission.DueDate = nil
+	commission.CreatedAt = &createdAt
+
+	return NewScheduler(
+		NewInMemoryOrderSource(
+			newDeadlineOrder("wedding-order", "wedding-detail", PieceTypeMugWithoutHandle, 10, asOf.AddDate(0, 0, 25)),
+			commission,
+		),
+		NewInMemoryAvailabilitySource(nil),
+		NewInMemoryTaskStore(),
+	).WithClock(NewFixedClock(asOf))
+}
+
+func TestSimulate_FixedDistributionsMatchThePlan(t *testing.T) {
+	scheduler := newSimulationScheduler()
+
+	fixed := SimulationOptions{
+		Runs:          5,
+		DryingDays:    Distribution{Min: 1, Mode: 1, Max: 1},
+		Rate:          Distribution{Min: 1, Mode: 1, Max: 1},
+		KilnDelayDays: Distribution{},
+	}
+
+	result, err := scheduler.Simulate(RunOptions{HorizonWeeks: 4}, fixed)
+	require.NoError(t, err)
+	require.Len(t, result.Forecasts, 2)
+	assert.Equal(t, 5, result.Runs)
+
+	input, err := scheduler.planningInput(RunOptions{HorizonWeeks: 4})
+	require.NoError(t, err)
+	planner, remaining := input.run()
+
+	for _, forecast := range result.Forecasts {
+		expected, _, _ := planner.projectCompletion(forecast.OrderDetailId, remaining)
+
+		assert.Equal(t, expected, forecast.P50, "Without variance every run should match the deterministic plan")
+		assert.Equal(t, forecast.P50, forecast.P95)
+	}
+}
+
+func TestSimulate_PercentilesAndOnTimeProbability(t *testing.T) {
+	result, err := newSimulationScheduler().Simulate(RunOptions{HorizonWeeks: 4}, DefaultSimulation)
+	require.NoError(t, err)
+	require.Len(t, result.Forecasts, 2)
+
+	for _, forecast := range result.Forecasts {
+		assert.False(t, forecast.P80.Before(forecast.P50))
+		assert.False(t, forecast.P95.Before(forecast.P80))
+	}
+
+	wedding := result.Forecasts[0]
+	assert.Equal(t, "wedding-detail", wedding.OrderDetailId, "Deadline orders are listed first")
+	require.NotNil(t, wedding.DueDate)
+	require.NotNil(t, wedding.OnTimeProbability)
+	assert.GreaterOrEqual(t, *wedding.OnTimeProbability, 0.0)
+	assert.LessOrEqual(t, *wedding.OnTimeProbability, 1.0)
+
+	commission := result.Forecasts[1]
+	assert.Nil(t, commission.DueDate, "Commissions without a deadline have no on-time probability")
+	assert.Nil(t, commission.OnTimeProbability)
+}
+
+func TestSimulate_SeededRunsAreReproducible(t *testing.T) {
+	first, err := newSimulationScheduler().Simulate(RunOptions{HorizonWeeks: 4}, DefaultSimulation)
+	require.NoError(t, err)
+
+	second, err := newSimulationScheduler().Simulate(RunOptions{HorizonWeeks: 4}, DefaultSimulation)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+}
+
+func TestDistribution_SampleStaysInRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	distribution := Distribution{Min: 0.8, Mode: 1.0, Max: 1.6}
+
+	for i := 0; i < 1000; i++ {
+		value := distribution.sample(rng)
+		assert.GreaterOrEqual(t, value, 0.8)
+		assert.LessOrEqual(t, value, 1.6)
+	}
+
+	assert.Equal(t, 1.0, Distribution{}.multiplier(rng), "An unset multiplier leaves the process unchanged")
+	assert.Equal(t, 0.0, Distribution{}.sample(rng), "An unset offset adds nothing")
+	assert.Equal(t, 2.0, Distribution{Min: 2, Mode: 2, Max: 2}.sample(rng))
+}
+
+func TestPercentile(t *testing.T) {
+	monday := time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	dates := []time.Time{}
+	for i := 0; i < 10; i++ {
+		dates = append(dates, monday.AddDate(0, 0, i))
+	}
+
+	assert.Equal(t, monday.AddDate(0, 0, 4), percentile(dates, 0.50))
+	assert.Equal(t, monday.AddDate(0, 0, 7), percentile(dates, 0.80))
+	assert.Equal(t, monday.AddDate(0, 0, 9), percentile(dates, 0.95))
+	assert.True(t, percentile([]time.Time{}, 0.5).IsZero())
+}
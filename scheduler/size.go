@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"math"
+	"strings"
+)
+
+func CalculateSizedHours(taskType TaskType, pieceType PieceType, size string, quantity int) float64 {
+	catalog, sizedPieceType := CurrentProcessCatalog().sized(pieceType, size)
+	return calculateHours(catalog, taskType, sizedPieceType, quantity)
+}
+
+func CalculateSizedQuantity(hours float64, taskType TaskType, pieceType PieceType, size string) int {
+	catalog, sizedPieceType := CurrentProcessCatalog().sized(pieceType, size)
+	return calculateQuantity(catalog, hours, taskType, sizedPieceType)
+}
+
+// normalizeSize accepts "12", "12oz" and "12 oz" for the same size
+func normalizeSize(size string) string {
+	size = strings.ToLower(strings.TrimSpace(size))
+	return strings.TrimSpace(strings.TrimSuffix(size, "oz"))
+}
+
+func sizedPieceType(pieceType PieceType, size string) PieceType {
+	return variantPieceType(pieceType, normalizeSize(size)+"oz")
+}
+
+func (c *ProcessCatalog) sizeMultiplier(size string) (SizeMultiplier, bool) {
+	multiplier, exists := c.sizes[normalizeSize(size)]
+	return multiplier, exists
+}
+
+func (c *ProcessCatalog) sized(pieceType PieceType, size string) (*ProcessCatalog, PieceType) {
+	multiplier, exists := c.sizeMultiplier(size)
+	if !exists || !c.HasProcess(pieceType) {
+		return c, pieceType
+	}
+
+	key := sizedPieceType(pieceType, size)
+
+	return c.withProcesses(map[PieceType][]ProductionStep{key: multiplier.apply(c.Steps(pieceType))}), key
+}
+
+// apply leaves bisque and fire alone since kiln cycles don't depend on size
+func (m SizeMultiplier) apply(steps []ProductionStep) []ProductionStep {
+	sized := append([]ProductionStep{}, steps...)
+
+	for i, step := range sized {
+		if isExternalProcess(step.TaskType) {
+			continue
+		}
+
+		step.Rate = step.Rate * m.Rate
+		step.DryingDays = int(math.Round(float64(step.DryingDays) * m.DryingDays))
+		sized[i] = step
+	}
+
+	return sized
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sizedDetail(size string) orders.OrderDetailDTO {
+	return orders.OrderDetailDTO{ID: "tumbler-detail", Type: string(PieceTypeTumbler), Size: &size, Quantity: 10, Status: string(StepKeyPending)}
+}
+
+func TestCalculateSizedHours(t *testing.T) {
+	assert.InDelta(t, CalculateHours(TaskTypeBuildBase, PieceTypeTumbler, 10), CalculateSizedHours(TaskTypeBuildBase, PieceTypeTumbler, "10", 10), 0.001)
+	assert.InDelta(t, 10.0, CalculateSizedHours(TaskTypeBuildBase, PieceTypeTumbler, "12 oz", 10), 0.001, "12oz builds at 4 a shift instead of 5")
+	assert.Less(t, CalculateSizedHours(TaskTypeBuildBase, PieceTypeTumbler, "8oz", 10), CalculateHours(TaskTypeBuildBase, PieceTypeTumbler, 10))
+	assert.Zero(t, CalculateSizedHours(TaskTypeBisque, PieceTypeTumbler, "12", 10))
+
+	assert.Equal(t, CalculateQuantity(8, TaskTypeGlaze, PieceTypeTumbler), CalculateSizedQuantity(8, TaskTypeGlaze, PieceTypeTumbler, "large"), "Unknown sizes use the base rates")
+	assert.Equal(t, 8, CalculateSizedQuantity(8, TaskTypeBuildBase, PieceTypeTumbler, "12"))
+}
+
+func TestCalculateDetailChains_ScalesToSize(t *testing.T) {
+	chains, derived, err := calculateDetailChains(DefaultProcessCatalog, DefaultCalendar, sizedDetail("12"), time.Now().AddDate(0, 0, 60), time.Now())
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+
+	process := chains[0][0].processKey()
+	assert.Equal(t, PieceType("tumbler/12oz"), process)
+	require.Contains(t, derived, process)
+
+	steps := derived[process]
+	assert.InDelta(t, 4.0, steps[0].Rate, 0.001)
+	assert.Equal(t, 3, steps[0].DryingDays, "Two drying days become two and a half, rounded up")
+
+	bisque, _ := DefaultProcessCatalog.stepByKey(StepKeyBisque, PieceTypeTumbler)
+	sizedBisque := steps[len(steps)-3]
+	assert.Equal(t, StepKeyBisque, sizedBisque.StepKey)
+	assert.Equal(t, bisque, sizedBisque, "Kiln steps don't depend on size")
+}
+
+func TestCalculateCompletionDate_LargerSizesTakeLonger(t *testing.T) {
+	fromDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	small := sizedDetail("8")
+	small.Quantity = 40
+	large := sizedDetail("12")
+	large.Quantity = 40
+
+	smallCompletion, err := CalculateCompletionDate(small, fromDate)
+	require.NoError(t, err)
+	largeCompletion, err := CalculateCompletionDate(large, fromDate)
+	require.NoError(t, err)
+
+	assert.True(t, largeCompletion.After(smallCompletion))
+}
+
+func TestParseProcessCatalog_Sizes(t *testing.T) {
+	data := []byte(`
+processes:
+  trinket-dish:
+    - {step_key: build, task_type: task_build_base, rate: 30, drying_days: 2}
+    - {step_key: fire, task_type: task_fire, rate: 0, drying_days: 0}
+sizes:
+  "6oz": {rate: 1.5, drying_days: 0.5}
+`)
+
+	catalog, err := ParseProcessCatalog(data, "yaml")
+	require.NoError(t, err)
+
+	multiplier, exists := catalog.sizeMultiplier("6")
+	assert.True(t, exists)
+	assert.Equal(t, SizeMultiplier{Rate: 1.5, DryingDays: 0.5}, multiplier)
+
+	_, exists = catalog.sizeMultiplier("12")
+	assert.False(t, exists, "A catalog's sizes replace the defaults")
+
+	_, err = NewProcessCatalogWithSizes(DefaultProductionProcess(), map[string]SizeMultiplier{"12": {Rate: 0, DryingDays: 1}})
+	assert.ErrorContains(t, err, "positive rate multiplier")
+}
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/availability"
+	"aliciapceramics/legacy/server/orders"
+	"time"
+)
+
+type OrderSource interface {
+	GetOrdersWithDeadlines() (orders.OrdersDTO, error)
+	GetNonDeadlineOrders() (orders.OrdersDTO, error)
+}
+
+type AvailabilitySource interface {
+	GetAvailability(startDate, endDate string) ([]availability.AvailabilityDTO, error)
+}
+
+type TaskStore interface {
+	DeletePendingTasks() error
+	InsertTasks(tasks []TaskToCreate) error
+	DeleteLatenessReports() error
+	InsertLatenessReport(entries []LatenessEntry) error
+	InsertSnapshot(snapshot Snapshot) error
+}
+
+type CompletedTaskSource interface {
+	GetCompletedTasks(since time.Time) ([]CompletedTask, error)
+}
+
+type supabaseTaskStore struct{}
+
+func NewSupabaseTaskStore() TaskStore {
+	return &supabaseTaskStore{}
+}
+
+func (s *supabaseTaskStore) DeletePendingTasks() error {
+	return DeletePendingTasks()
+}
+
+func (s *supabaseTaskStore) InsertTasks(tasks []TaskToCreate) error {
+	return InsertTasks(tasks)
+}
+
+func (s *supabaseTaskStore) DeleteLatenessReports() error {
+	return DeleteLatenessReports()
+}
+
+func (s *supabaseTaskStore) InsertLatenessReport(entries []LatenessEntry) error {
+	return InsertLatenessReport(entries)
+}
+
+func (s *supabaseTaskStore) InsertSnapshot(snapshot Snapshot) error {
+	return InsertSnapshot(snapshot)
+}
+
+func (s *supabaseTaskStore) GetCompletedTasks(since time.Time) ([]CompletedTask, error) {
+	return GetCompletedTasks(since)
+}
@@ -3,89 +3,261 @@ package scheduler
 import (
 	"aliciapceramics/legacy/server/orders"
 	"fmt"
-	"math"
 	"time"
 )
 
 func CalculateTaskChain(orderDetail orders.OrderDetailDTO, dueDate time.Time) ([]TaskChainItem, error) {
+	return CalculateTaskChainAsOf(orderDetail, dueDate, time.Now())
+}
+
+func CalculateTaskChainAsOf(orderDetail orders.OrderDetailDTO, dueDate time.Time, asOf time.Time) ([]TaskChainItem, error) {
+	return calculateTaskChain(CurrentProcessCatalog(), CurrentCalendar(), orderDetail, dueDate, asOf)
+}
+
+func calculateTaskChain(catalog *ProcessCatalog, calendar *Calendar, orderDetail orders.OrderDetailDTO, dueDate time.Time, asOf time.Time) ([]TaskChainItem, error) {
+	chains, _, err := calculateDetailChains(catalog, calendar, orderDetail, dueDate, asOf)
+	if err != nil {
+		return []TaskChainItem{}, err
+	}
+
+	tasks := []TaskChainItem{}
+	for _, chain := range chains {
+		tasks = append(tasks, chain...)
+	}
+
+	return tasks, nil
+}
+
+// calculateDetailChains returns one chain per component of the order detail,
+// along with any derived processes the chains refer to
+func calculateDetailChains(catalog *ProcessCatalog, calendar *Calendar, orderDetail orders.OrderDetailDTO, dueDate time.Time, asOf time.Time) ([][]TaskChainItem, map[PieceType][]ProductionStep, error) {
 
 	safePieceType, isValidPieceType := IsValidPieceType(orderDetail.Type)
 	safeStep, isValidStep := IsValidStepKey(orderDetail.Status)
 
-	dueDateWithBuffer := dueDate.AddDate(0, 0, -3)
-
 	if !isValidPieceType {
-		return []TaskChainItem{}, fmt.Errorf("order detail %s is not valid with type %s", orderDetail.ID, orderDetail.Type)
+		return nil, nil, fmt.Errorf("order detail %s is not valid with type %s", orderDetail.ID, orderDetail.Type)
 	}
 
 	if !isValidStep {
-		return []TaskChainItem{}, fmt.Errorf("order detail %s is not valid with status %s", orderDetail.ID, orderDetail.Status)
+		return nil, nil, fmt.Errorf("order detail %s is not valid with status %s", orderDetail.ID, orderDetail.Status)
 	}
 
-	process := ProductionProcess[safePieceType]
+	processes, err := resolveDetailProcesses(catalog, safePieceType, orderDetail)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	var currentStepIndex int
+	chains := [][]TaskChainItem{}
+	derived := make(map[PieceType][]ProductionStep)
 
-	for idx, step := range process {
-		if step.StepKey == safeStep {
-			currentStepIndex = idx
-			break
+	for _, process := range processes {
+		if process.derived {
+			derived[process.process] = process.steps
+		}
+
+		chain := buildTaskChain(calendar, orderDetail, safePieceType, safeStep, process, dueDate, asOf)
+		if len(chain) > 0 {
+			chains = append(chains, chain)
 		}
 	}
 
-	if safeStep != StepKeyPending && orderDetail.StatusChangedAt != nil {
-		currentStep := process[currentStepIndex]
-		dryingComplete := orderDetail.StatusChangedAt.AddDate(0, 0, currentStep.DryingDays)
+	return chains, derived, nil
+}
 
-		if dryingComplete.After(time.Now()) {
-			return []TaskChainItem{}, nil
-		}
+func buildTaskChain(calendar *Calendar, orderDetail orders.OrderDetailDTO, pieceType PieceType, status StepKey, process detailProcess, dueDate time.Time, asOf time.Time) []TaskChainItem {
 
-		currentStepIndex++
+	dueDateWithBuffer := dueDate.AddDate(0, 0, -3)
+
+	steps := process.planned
+
+	predecessors := stepPredecessors(steps)
+	successors := stepSuccessors(predecessors)
+	graph := isGraphProcess(steps)
+
+	states := stepStates(orderDetail, process, status, predecessors)
+
+	// Pieces reported lost are remade in their own chains
+	remainingQuantity := (orderDetail.Quantity - lostQuantity(orderDetail)) * process.perUnit
+	if len(orderDetail.StepProgress) == 0 {
+		remainingQuantity -= orderDetail.CompletedQuantity * process.perUnit
 	}
 
-	if currentStepIndex >= len(process) {
-		return []TaskChainItem{}, nil
+	if remainingQuantity <= 0 {
+		return []TaskChainItem{}
 	}
 
-	var tasks = []TaskChainItem{}
+	// A step can't start until the steps it waits on are done and dry. The
+	// steps after one that can't start yet are planned on a later run.
+	included := make([]bool, len(steps))
+	remainingSteps := []ProductionStep{}
+	remainingIndexes := []int{}
+
+	for i, step := range steps {
+		if states[i].done {
+			continue
+		}
+
+		included[i] = true
+		for _, previous := range predecessors[i] {
+			if !states[previous].done {
+				included[i] = included[i] && included[previous]
+				continue
+			}
 
-	for i := len(process) - 1; i >= currentStepIndex; i-- {
+			finishedAt := states[previous].finishedAt
+			if finishedAt != nil && finishedAt.AddDate(0, 0, steps[previous].DryingDays).After(asOf) {
+				included[i] = false
+			}
+		}
+
+		if included[i] {
+			remainingSteps = append(remainingSteps, step)
+			remainingIndexes = append(remainingIndexes, i)
+		}
+	}
+
+	if len(remainingSteps) == 0 {
+		return []TaskChainItem{}
+	}
+
+	// Extra pieces are started to cover losses, and each step plans for the
+	// pieces expected to reach it that it hasn't already been through
+	quantities := make([]int, len(steps))
+	for j, quantity := range stepQuantities(remainingSteps, remainingQuantity) {
+		i := remainingIndexes[j]
+		quantities[i] = quantity - states[i].completedQuantity
+	}
 
-		step := process[i]
+	startDates := make([]time.Time, len(steps))
 
-		remainingQuantity := orderDetail.Quantity - orderDetail.CompletedQuantity
+	for j := len(remainingIndexes) - 1; j >= 0; j-- {
+		i := remainingIndexes[j]
 
-		var workDays int
-		if step.Rate > 0 {
-			workDays = int(math.Ceil(float64(remainingQuantity) / step.Rate))
-		} else {
-			workDays = 0
+		finish := dueDateWithBuffer
+		for _, next := range successors[i] {
+			if included[next] && startDates[next].Before(finish) {
+				finish = startDates[next]
+			}
 		}
 
-		var daysNeeded int
-		if step.TaskType == TaskTypeBisque || step.TaskType == TaskTypeFire {
-			daysNeeded = step.DryingDays
-		} else {
-			daysNeeded = workDays + step.DryingDays
+		startDates[i] = calendar.stepStart(finish, steps[i], max(quantities[i], 0))
+	}
+
+	var tasks = []TaskChainItem{}
+	planned := make([]bool, len(steps))
+
+	for _, i := range remainingIndexes {
+		step := steps[i]
+
+		// Every piece has been through a step that is waiting to be marked done
+		if quantities[i] <= 0 {
+			continue
 		}
 
 		task := TaskChainItem{
 			TaskType:          step.TaskType,
-			PieceType:         safePieceType,
-			StartDate:         dueDateWithBuffer.AddDate(0, 0, -daysNeeded),
-			Quantity:          remainingQuantity,
+			PieceType:         pieceType,
+			StartDate:         startDates[i],
+			Quantity:          quantities[i],
 			OrderDetailId:     orderDetail.ID,
+			Component:         process.component,
+			Process:           process.process,
 			OrderDetailStatus: step.StepKey,
 		}
 
+		if step.TaskType == TaskTypeGlaze {
+			task.Glaze = normalizeGlaze(orderDetail.Glaze)
+		}
+
+		if graph {
+			task.After = []StepKey{}
+			for _, previous := range predecessors[i] {
+				if planned[previous] {
+					task.After = append(task.After, steps[previous].StepKey)
+				}
+			}
+		}
+
+		planned[i] = true
 		tasks = append(tasks, task)
-		dueDateWithBuffer = task.StartDate
 	}
 
-	for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
-		tasks[i], tasks[j] = tasks[j], tasks[i]
+	return tasks
+}
+
+// stepState is how far an order detail has got with one step of a process
+type stepState struct {
+	done              bool
+	finishedAt        *time.Time
+	completedQuantity int
+}
+
+// stepStates works out which steps are done from the order detail's step
+// progress. Order details from before steps were tracked only have a status,
+// which is the last step finished, or the one in progress while its status
+// hasn't changed. Either way the steps it waits on are done.
+func stepStates(orderDetail orders.OrderDetailDTO, process detailProcess, status StepKey, predecessors [][]int) []stepState {
+	steps := process.planned
+	states := make([]stepState, len(steps))
+
+	markWaitedOn := func(i int) {
+		pending := append([]int{}, predecessors[i]...)
+		for len(pending) > 0 {
+			j := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+
+			if !states[j].done {
+				states[j].done = true
+				pending = append(pending, predecessors[j]...)
+			}
+		}
 	}
 
-	return tasks, nil
+	if len(orderDetail.StepProgress) == 0 {
+		statusIndex, atStatus := statusStepIndex(steps, status)
+
+		if !atStatus {
+			for i := 0; i < statusIndex; i++ {
+				states[i].done = true
+			}
+
+			return states
+		}
+
+		markWaitedOn(statusIndex)
+		if orderDetail.StatusChangedAt != nil {
+			states[statusIndex] = stepState{done: true, finishedAt: orderDetail.StatusChangedAt}
+		}
+
+		return states
+	}
+
+	for i, step := range steps {
+		progress, found := stepProgress(orderDetail, process.component, step.StepKey)
+		if !found {
+			continue
+		}
+
+		markWaitedOn(i)
+		states[i].completedQuantity = progress.CompletedQuantity
+
+		if progress.CompletedAt != nil {
+			states[i].done = true
+			states[i].finishedAt = progress.CompletedAt
+		}
+	}
+
+	return states
+}
+
+// stepProgress looks a component's step up in the order detail's progress.
+// Progress carried over from the status is recorded against the step alone.
+func stepProgress(orderDetail orders.OrderDetailDTO, component string, stepKey StepKey) (orders.StepProgressDTO, bool) {
+	if progress, found := orderDetail.StepProgress[orders.StepProgressKey(component, string(stepKey))]; found {
+		return progress, true
+	}
+
+	progress, found := orderDetail.StepProgress[string(stepKey)]
+	return progress, found
 }
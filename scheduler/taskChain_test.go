@@ -261,7 +261,7 @@ func TestCalculateTaskChain_BUG1_DuplicateTrimStepKeys(t *testing.T) {
 	tasks, err := CalculateTaskChain(orderDetail, dueDate)
 	require.NoError(t, err)
 
-	process := ProductionProcess[PieceTypeMugWithHandle]
+	process := DefaultProductionProcess()[PieceTypeMugWithHandle]
 	stepKeys := make(map[StepKey]int)
 	for _, step := range process {
 		stepKeys[step.StepKey]++
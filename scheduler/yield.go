@@ -0,0 +1,87 @@
+package scheduler
+
+import "math"
+
+// YieldConfidence is the chance the pieces started for an order detail are
+// enough to cover it once each step's losses are taken
+var YieldConfidence = 0.9
+
+// stepQuantities inflates quantity by the losses of the steps after it, and
+// returns how many pieces each step is expected to work on
+func stepQuantities(steps []ProductionStep, quantity int) []int {
+	quantities := make([]int, len(steps))
+	if len(steps) == 0 {
+		return quantities
+	}
+
+	predecessors := stepPredecessors(steps)
+	survival := make([]float64, len(steps))
+	for i := range steps {
+		survival[i] = survivalBefore(steps, predecessors, i)
+	}
+
+	last := len(steps) - 1
+	started := plannedQuantity(quantity, survival[last]*(1-steps[last].LossRate), YieldConfidence)
+
+	for i := range steps {
+		quantities[i] = int(math.Ceil(float64(started)*survival[i] - 1e-9))
+	}
+
+	return quantities
+}
+
+// survivalBefore is the share of pieces that make it through every step
+// step i waits on, directly or not
+func survivalBefore(steps []ProductionStep, predecessors [][]int, i int) float64 {
+	visited := make(map[int]bool)
+	pending := append([]int{}, predecessors[i]...)
+	survival := 1.0
+
+	for len(pending) > 0 {
+		j := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if visited[j] {
+			continue
+		}
+
+		visited[j] = true
+		survival *= 1 - steps[j].LossRate
+		pending = append(pending, predecessors[j]...)
+	}
+
+	return survival
+}
+
+// plannedQuantity is the fewest pieces to start so that, if each survives
+// with the given chance, at least quantity are left with the given confidence
+func plannedQuantity(quantity int, survival, confidence float64) int {
+	if quantity <= 0 || survival >= 1 || survival <= 0 {
+		return quantity
+	}
+
+	confidence = math.Min(confidence, 0.999)
+
+	for n := quantity; ; n++ {
+		if binomialAtLeast(n, quantity, survival) >= confidence {
+			return n
+		}
+	}
+}
+
+// binomialAtLeast is the chance at least k of n pieces survive
+func binomialAtLeast(n, k int, survival float64) float64 {
+	total := 0.0
+
+	for i := k; i <= n; i++ {
+		logChoose := lgamma(n+1) - lgamma(i+1) - lgamma(n-i+1)
+		total += math.Exp(logChoose + float64(i)*math.Log(survival) + float64(n-i)*math.Log(1-survival))
+	}
+
+	return total
+}
+
+func lgamma(x int) float64 {
+	value, _ := math.Lgamma(float64(x))
+	return value
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"aliciapceramics/legacy/server/orders"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lossProcessYAML = `
+processes:
+  mug-without-handle:
+    - {step_key: build, task_type: task_build_base, rate: 5, drying_days: 2, loss_rate: 0.05}
+    - {step_key: trim_final, task_type: task_trim, rate: 15, drying_days: 3}
+    - {step_key: bisque, task_type: task_bisque, rate: 0, drying_days: 5, loss_rate: 0.1}
+    - {step_key: glaze, task_type: task_glaze, rate: 17, drying_days: 0}
+    - {step_key: fire, task_type: task_fire, rate: 0, drying_days: 5, loss_rate: 0.1}
+`
+
+func TestPlannedQuantity(t *testing.T) {
+	assert.Equal(t, 10, plannedQuantity(10, 1, 0.9), "Nothing is lost")
+	assert.Equal(t, 13, plannedQuantity(10, 0.9, 0.9))
+	assert.Greater(t, plannedQuantity(10, 0.9, 0.99), plannedQuantity(10, 0.9, 0.9))
+	assert.GreaterOrEqual(t, binomialAtLeast(13, 10, 0.9), 0.9)
+	assert.Less(t, binomialAtLeast(12, 10, 0.9), 0.9)
+}
+
+func TestCalculateTaskChain_OverProducesForLosses(t *testing.T) {
+	catalog, err := ParseProcessCatalog([]byte(lossProcessYAML), "yaml")
+	require.NoError(t, err)
+
+	detail := orders.OrderDetailDTO{ID: "detail", Type: string(PieceTypeMugWithoutHandle), Quantity: 20, CompletedQuantity: 0, Status: string(StepKeyPending)}
+
+	tasks, err := calculateTaskChain(catalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 90), time.Now())
+	require.NoError(t, err)
+	require.Len(t, tasks, 5)
+
+	started := plannedQuantity(20, 0.95*0.9*0.9, YieldConfidence)
+	assert.Equal(t, started, tasks[0].Quantity)
+	assert.Greater(t, started, 20)
+
+	assert.Equal(t, int(math.Ceil(float64(started)*0.95)), tasks[1].Quantity, "Trimming plans for the pieces that survive drying")
+	assert.Equal(t, tasks[1].Quantity, tasks[2].Quantity)
+	assert.Equal(t, int(math.Ceil(float64(started)*0.95*0.9)), tasks[3].Quantity)
+	assert.Equal(t, tasks[3].Quantity, tasks[4].Quantity)
+	assert.GreaterOrEqual(t, float64(tasks[4].Quantity)*0.9, 20.0)
+
+	detail.Status = string(StepKeyGlaze)
+	tasks, err = calculateTaskChain(catalog, DefaultCalendar, detail, time.Now().AddDate(0, 0, 90), time.Now())
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, plannedQuantity(20, 0.9, YieldConfidence), tasks[0].Quantity, "Losses already taken before glazing aren't planned for again")
+}
+
+func TestStepQuantities_Branches(t *testing.T) {
+	steps := []ProductionStep{
+		{StepKey: StepKeyBuild, TaskType: TaskTypeBuildBase, Rate: 5, LossRate: 0.1},
+		{StepKey: StepKeyHandles, TaskType: TaskTypePullHandles, Rate: 20, LossRate: 0.2},
+		{StepKey: StepKeyAttach, TaskType: TaskTypeAttachHandle, Rate: 8, After: []StepKey{StepKeyBuild, StepKeyHandles}},
+		{StepKey: StepKeyFire, TaskType: TaskTypeFire, After: []StepKey{StepKeyAttach}},
+	}
+
+	quantities := stepQuantities(steps, 10)
+	started := plannedQuantity(10, 0.9*0.8, YieldConfidence)
+
+	assert.Equal(t, []int{started, started, int(math.Ceil(float64(started) * 0.9 * 0.8)), int(math.Ceil(float64(started) * 0.9 * 0.8))}, quantities)
+}
+
+func TestValidateProcess_LossRate(t *testing.T) {
+	steps := DefaultProductionProcess()[PieceTypeMugWithoutHandle]
+	lossy := append([]ProductionStep{}, steps...)
+	lossy[2].LossRate = 1
+
+	assert.ErrorContains(t, validateProcess(PieceTypeMugWithoutHandle, lossy), "loss rate 1")
+}